@@ -3,12 +3,22 @@ package cache
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"sds/internal/session"
+	"sds/internal/simulation/cache"
 )
 
 var sessionManager *session.Manager
 
+// maxCapacity bounds the cache size a session may configure
+const maxCapacity = 10000
+
+// maxCurvePoints bounds the points of a miss ratio curve
+const maxCurvePoints = 1000
+
 // Helper function to extract session ID from request
 func getSessionID(r *http.Request) string {
 	// Try header first
@@ -48,11 +58,7 @@ func GetAllStates(w http.ResponseWriter, r *http.Request) {
 	sessionID := getSessionID(r)
 	userState := sessionManager.GetOrCreate(sessionID)
 
-	response := map[string]interface{}{
-		"lru":  userState.LRUCache.GetState(),
-		"lfu":  userState.LFUCache.GetState(),
-		"fifo": userState.FIFOCache.GetState(),
-	}
+	response := userState.Caches.States()
 
 	responseJSON, err := json.Marshal(response)
 	if err != nil {
@@ -92,13 +98,10 @@ func AccessCache(w http.ResponseWriter, r *http.Request) {
 
 	if operation == "GET" {
 		// Perform GET on all caches
-		lruValue, lruHit := userState.LRUCache.Get(key)
-		lfuValue, lfuHit := userState.LFUCache.Get(key)
-		fifoValue, fifoHit := userState.FIFOCache.Get(key)
-
-		results["lru"] = map[string]interface{}{"hit": lruHit, "value": lruValue}
-		results["lfu"] = map[string]interface{}{"hit": lfuHit, "value": lfuValue}
-		results["fifo"] = map[string]interface{}{"hit": fifoHit, "value": fifoValue}
+		for _, named := range userState.Caches.Policies() {
+			value, hit := named.Policy.Get(key)
+			results[named.Key] = map[string]interface{}{"hit": hit, "value": value}
+		}
 	} else if operation == "PUT" {
 		if value == "" {
 			http.Error(w, "Missing required parameter: value", http.StatusBadRequest)
@@ -106,13 +109,9 @@ func AccessCache(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Perform PUT on all caches
-		lruEvicted := userState.LRUCache.Put(key, value)
-		lfuEvicted := userState.LFUCache.Put(key, value)
-		fifoEvicted := userState.FIFOCache.Put(key, value)
-
-		results["lru"] = map[string]interface{}{"evicted": lruEvicted}
-		results["lfu"] = map[string]interface{}{"evicted": lfuEvicted}
-		results["fifo"] = map[string]interface{}{"evicted": fifoEvicted}
+		for _, named := range userState.Caches.Policies() {
+			results[named.Key] = map[string]interface{}{"evicted": named.Policy.Put(key, value)}
+		}
 	} else {
 		http.Error(w, "Invalid operation. Must be GET or PUT", http.StatusBadRequest)
 		return
@@ -121,11 +120,7 @@ func AccessCache(w http.ResponseWriter, r *http.Request) {
 	// Get updated states
 	response := map[string]interface{}{
 		"results": results,
-		"states": map[string]interface{}{
-			"lru":  userState.LRUCache.GetState(),
-			"lfu":  userState.LFUCache.GetState(),
-			"fifo": userState.FIFOCache.GetState(),
-		},
+		"states":  userState.Caches.States(),
 	}
 
 	responseJSON, err := json.Marshal(response)
@@ -152,15 +147,395 @@ func ResetAll(w http.ResponseWriter, r *http.Request) {
 	sessionID := getSessionID(r)
 	userState := sessionManager.GetOrCreate(sessionID)
 
-	userState.LRUCache.Reset()
-	userState.LFUCache.Reset()
-	userState.FIFOCache.Reset()
+	userState.Caches.Reset()
 
 	// Return new states
+	response := userState.Caches.States()
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// GetPolicies lists every registered eviction policy and the session's active ones
+// GET /api/cache/policies
+func GetPolicies(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	response := map[string]interface{}{
+		"available": cache.RegisteredPolicies(),
+		"active":    activePolicies(userState.Caches),
+		"capacity":  userState.Caches.Capacity(),
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// ConfigurePolicies replaces the session's caches with fresh instances of the chosen policies
+// POST /api/cache/configure
+// Body: {"policies": ["lru", "lfu"], "capacity": 8}
+func ConfigurePolicies(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	// Parse request body
+	var req struct {
+		Policies []string `json:"policies"`
+		Capacity int      `json:"capacity"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Capacity > maxCapacity {
+		http.Error(w, "capacity must be at most "+strconv.Itoa(maxCapacity), http.StatusBadRequest)
+		return
+	}
+
+	if err := userState.Caches.Configure(req.Policies, req.Capacity); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := userState.Caches.States()
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// activePolicies returns the keys of the session's policies in configured order
+func activePolicies(group *cache.Group) []string {
+	keys := []string{}
+	for _, named := range group.Policies() {
+		keys = append(keys, named.Key)
+	}
+	return keys
+}
+
+// CompareToOPT replays a trace through the session's policies and Belady's OPT
+// POST /api/cache/opt
+// Body: {"trace": ["A", "B", "C", "A", "D", "B"], "capacity": 3, "policies": ["lru", "arc"]}
+// capacity and policies default to the session's configuration
+func CompareToOPT(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	// Parse request body
+	var req struct {
+		Trace    []string `json:"trace"`
+		Capacity int      `json:"capacity"`
+		Policies []string `json:"policies"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Capacity == 0 {
+		req.Capacity = userState.Caches.Capacity()
+	}
+	if len(req.Policies) == 0 {
+		req.Policies = activePolicies(userState.Caches)
+	}
+	if req.Capacity > maxCapacity {
+		http.Error(w, "capacity must be at most "+strconv.Itoa(maxCapacity), http.StatusBadRequest)
+		return
+	}
+
+	response, err := cache.CompareToOPT(req.Trace, req.Capacity, req.Policies)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// readTrace parses an uploaded trace or generates one from the workload config
+func readTrace(workload cache.TraceConfig, traceFile string) ([]string, error) {
+	if workload.Pattern == cache.TraceUpload {
+		return cache.ParseTrace(strings.NewReader(traceFile))
+	}
+	return cache.GenerateTrace(workload)
+}
+
+// RunWorkload generates (or parses an uploaded) trace and replays it through
+// the session's policies and OPT, returning hit ratio over time
+// POST /api/cache/workload
+// Body: {"workload": {"pattern": "zipf", "length": 10000, "keys": 1000, "skew": 0.9}, "capacity": 100, "points": 50}
+// Uploaded trace: {"workload": {"pattern": "upload"}, "traceFile": "A\nB\nA\n..."}
+// capacity and policies default to the session's configuration
+func RunWorkload(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	// Parse request body
+	var req struct {
+		Workload  cache.TraceConfig `json:"workload"`
+		TraceFile string            `json:"traceFile"`
+		Capacity  int               `json:"capacity"`
+		Policies  []string          `json:"policies"`
+		Points    int               `json:"points"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Capacity == 0 {
+		req.Capacity = userState.Caches.Capacity()
+	}
+	if len(req.Policies) == 0 {
+		req.Policies = activePolicies(userState.Caches)
+	}
+	if req.Capacity > maxCapacity {
+		http.Error(w, "capacity must be at most "+strconv.Itoa(maxCapacity), http.StatusBadRequest)
+		return
+	}
+	if req.Points > maxCurvePoints {
+		http.Error(w, "points must be at most "+strconv.Itoa(maxCurvePoints), http.StatusBadRequest)
+		return
+	}
+
+	trace, err := readTrace(req.Workload, req.TraceFile)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := cache.RunWorkload(trace, req.Capacity, req.Policies, req.Points)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// ComputeMRC computes miss ratio curves (hit ratio vs. cache size) for a trace
+// POST /api/cache/mrc
+// Body: {"workload": {"pattern": "zipf", "length": 10000, "keys": 1000}, "sizes": [10, 50, 100, 500]}
+// The trace is given as for /api/cache/workload; sizes defaults to 20 sizes up to
+// the number of unique keys and policies to the session's configuration
+func ComputeMRC(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	// Parse request body
+	var req struct {
+		Workload  cache.TraceConfig `json:"workload"`
+		TraceFile string            `json:"traceFile"`
+		Sizes     []int             `json:"sizes"`
+		Policies  []string          `json:"policies"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Policies) == 0 {
+		req.Policies = activePolicies(userState.Caches)
+	}
+	for _, size := range req.Sizes {
+		if size > maxCapacity {
+			http.Error(w, "sizes must be at most "+strconv.Itoa(maxCapacity), http.StatusBadRequest)
+			return
+		}
+	}
+
+	trace, err := readTrace(req.Workload, req.TraceFile)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := cache.ComputeMRC(trace, req.Sizes, req.Policies)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// GetSizedState returns the state of the size-weighted cache
+// GET /api/cache/sized/state
+func GetSizedState(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	responseJSON, err := json.Marshal(userState.SizedCache.GetState())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// AccessSized performs a GET, PUT or demand-filled ACCESS on the size-weighted cache
+// POST /api/cache/sized/access
+// Body: {"operation": "PUT", "key": "logo.png", "value": "...", "size": 300, "cost": 1, "ttlMs": 5000}
+func AccessSized(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	// Parse request body
+	var req struct {
+		Operation string  `json:"operation"`
+		Key       string  `json:"key"`
+		Value     string  `json:"value"`
+		Size      int     `json:"size"`
+		Cost      float64 `json:"cost"`
+		TTLMs     int     `json:"ttlMs"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Key == "" {
+		http.Error(w, "Missing required field: key", http.StatusBadRequest)
+		return
+	}
+	if req.TTLMs < 0 {
+		http.Error(w, "ttlMs must not be negative", http.StatusBadRequest)
+		return
+	}
+	ttl := time.Duration(req.TTLMs) * time.Millisecond
+
+	result := map[string]interface{}{}
+	switch req.Operation {
+	case "GET":
+		value, hit := userState.SizedCache.Get(req.Key)
+		result["hit"] = hit
+		result["value"] = value
+	case "PUT":
+		evicted, err := userState.SizedCache.Put(req.Key, req.Value, req.Size, req.Cost, ttl)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result["evicted"] = evicted
+	case "ACCESS":
+		hit, evicted, err := userState.SizedCache.Access(req.Key, req.Size, req.Cost, ttl)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result["hit"] = hit
+		result["evicted"] = evicted
+	default:
+		http.Error(w, "Invalid operation. Must be GET, PUT or ACCESS", http.StatusBadRequest)
+		return
+	}
+
 	response := map[string]interface{}{
-		"lru":  userState.LRUCache.GetState(),
-		"lfu":  userState.LFUCache.GetState(),
-		"fifo": userState.FIFOCache.GetState(),
+		"result": result,
+		"state":  userState.SizedCache.GetState(),
 	}
 
 	responseJSON, err := json.Marshal(response)
@@ -173,6 +548,705 @@ func ResetAll(w http.ResponseWriter, r *http.Request) {
 	w.Write(responseJSON)
 }
 
+// AdvanceSized moves the size-weighted cache's virtual clock forward, running
+// active expiry cycles on the way
+// POST /api/cache/sized/advance?ms=<number>
+func AdvanceSized(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	ms, err := strconv.Atoi(r.URL.Query().Get("ms"))
+	if err != nil || ms <= 0 || ms > 24*60*60*1000 {
+		http.Error(w, "Invalid ms parameter (1 to 86400000)", http.StatusBadRequest)
+		return
+	}
+
+	userState.SizedCache.Advance(time.Duration(ms) * time.Millisecond)
+
+	responseJSON, err := json.Marshal(userState.SizedCache.GetState())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// ConfigureSized replaces the size-weighted cache's configuration (emptying it)
+// POST /api/cache/sized/configure
+// Body: {"capacityBytes": 4096, "eviction": "gds", "activeExpiry": true, "expiryHz": 10, "sampleSize": 20}
+func ConfigureSized(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	// Parse request body, starting from the current configuration
+	config := userState.SizedCache.Config()
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := userState.SizedCache.Configure(config); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	responseJSON, err := json.Marshal(userState.SizedCache.GetState())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// ResetSized empties the size-weighted cache
+// POST /api/cache/sized/reset
+func ResetSized(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	userState.SizedCache.Reset()
+
+	responseJSON, err := json.Marshal(userState.SizedCache.GetState())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// CompareSized replays a trace of variously sized objects through each
+// eviction strategy of the size-weighted cache
+// POST /api/cache/sized/compare
+// Body: {"workload": {"pattern": "zipf", "length": 10000, "keys": 1000}, "sized": {"capacityBytes": 100000, "minSize": 100, "maxSize": 10000}}
+// The trace is given as for /api/cache/workload; evictions defaults to all of them
+func CompareSized(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Parse request body
+	var req struct {
+		Workload  cache.TraceConfig     `json:"workload"`
+		TraceFile string                `json:"traceFile"`
+		Sized     cache.SizedWorkload   `json:"sized"`
+		Evictions []cache.SizedEviction `json:"evictions"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	trace, err := readTrace(req.Workload, req.TraceFile)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results, err := cache.CompareSized(trace, req.Sized, req.Evictions)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	responseJSON, err := json.Marshal(map[string]interface{}{"results": results})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// writeState is the response body of every write-policy endpoint
+func writeState(simulator *cache.WriteSimulator) map[string]interface{} {
+	return map[string]interface{}{
+		"config": simulator.Config(),
+		"states": simulator.States(),
+	}
+}
+
+// GetWriteState returns every write-policy cache with its backing store
+// GET /api/cache/write/state
+func GetWriteState(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	responseJSON, err := json.Marshal(writeState(userState.WriteCaches))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// AccessWrite performs the same operation under every write policy
+// POST /api/cache/write/access
+// Body: {"operation": "WRITE", "key": "A", "value": "1"}
+// operation is READ, WRITE, FLUSH (write back dirty entries) or CRASH (lose the caches, keep the stores)
+func AccessWrite(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	// Parse request body
+	var req struct {
+		Operation string `json:"operation"`
+		Key       string `json:"key"`
+		Value     string `json:"value"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if (req.Operation == "READ" || req.Operation == "WRITE") && req.Key == "" {
+		http.Error(w, "Missing required field: key", http.StatusBadRequest)
+		return
+	}
+
+	results := make(map[cache.WritePolicy]interface{})
+	for _, c := range userState.WriteCaches.Caches() {
+		policy := c.Policy()
+		switch req.Operation {
+		case "READ":
+			value, hit := c.Read(req.Key)
+			results[policy] = map[string]interface{}{"hit": hit, "value": value}
+		case "WRITE":
+			c.Write(req.Key, req.Value)
+			results[policy] = map[string]interface{}{}
+		case "FLUSH":
+			results[policy] = map[string]interface{}{"flushed": c.Flush()}
+		case "CRASH":
+			results[policy] = map[string]interface{}{"lost": c.Crash()}
+		default:
+			http.Error(w, "Invalid operation. Must be READ, WRITE, FLUSH or CRASH", http.StatusBadRequest)
+			return
+		}
+	}
+
+	response := writeState(userState.WriteCaches)
+	response["results"] = results
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// ConfigureWrite replaces the write-policy caches and stores with empty ones
+// POST /api/cache/write/configure
+// Body: {"policy": "lru", "capacity": 5, "store": {"readLatencyMs": 10, "writeLatencyMs": 20, "cacheLatencyMs": 1}}
+func ConfigureWrite(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	// Parse request body, starting from the current configuration
+	config := userState.WriteCaches.Config()
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if config.Capacity > maxCapacity {
+		http.Error(w, "capacity must be at most "+strconv.Itoa(maxCapacity), http.StatusBadRequest)
+		return
+	}
+
+	if err := userState.WriteCaches.Configure(config); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	responseJSON, err := json.Marshal(writeState(userState.WriteCaches))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// ResetWrite empties every write-policy cache and store
+// POST /api/cache/write/reset
+func ResetWrite(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	userState.WriteCaches.Reset()
+
+	responseJSON, err := json.Marshal(writeState(userState.WriteCaches))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// CompareWrite replays a read/write workload through every write policy
+// POST /api/cache/write/compare
+// Body: {"workload": {"pattern": "zipf", "length": 10000, "keys": 1000}, "writes": {"writeRatio": 0.2, "crashAt": 5000}, "capacity": 100}
+// The trace is given as for /api/cache/workload; policy, capacity and store default to the session's configuration
+func CompareWrite(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	// Parse request body
+	config := userState.WriteCaches.Config()
+	req := struct {
+		Workload  cache.TraceConfig   `json:"workload"`
+		TraceFile string              `json:"traceFile"`
+		Writes    cache.WriteWorkload `json:"writes"`
+		Policy    string              `json:"policy"`
+		Capacity  int                 `json:"capacity"`
+		Store     cache.StoreConfig   `json:"store"`
+	}{Policy: config.Policy, Capacity: config.Capacity, Store: config.Store}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Capacity > maxCapacity {
+		http.Error(w, "capacity must be at most "+strconv.Itoa(maxCapacity), http.StatusBadRequest)
+		return
+	}
+
+	trace, err := readTrace(req.Workload, req.TraceFile)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reports, err := cache.CompareWritePolicies(trace, req.Writes, cache.WriteSimulatorConfig{
+		Policy:   req.Policy,
+		Capacity: req.Capacity,
+		Store:    req.Store,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	responseJSON, err := json.Marshal(map[string]interface{}{"results": reports})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// SimulateStampede compares cache stampede mitigations on the same traffic
+// POST /api/cache/stampede
+// Body: {"servers": 4, "requestRate": 1000, "keys": 1, "ttlMs": 2000, "recomputeMs": 200, "durationMs": 10000, "mitigations": ["none", "singleflight"]}
+// Omitted fields keep their defaults; mitigations defaults to all of them
+func SimulateStampede(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Parse request body over the defaults
+	config := cache.DefaultStampedeConfig()
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	response, err := cache.SimulateStampede(config)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// GetClusterState returns every node of the distributed cache with its load
+// GET /api/cache/cluster/state
+func GetClusterState(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	responseJSON, err := json.Marshal(userState.CacheCluster.GetState())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// AccessCluster routes a GET or PUT to the node owning the key
+// POST /api/cache/cluster/access
+// Body: {"operation": "PUT", "key": "user:42", "value": "Ada"}
+func AccessCluster(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	// Parse request body
+	var req struct {
+		Operation string `json:"operation"`
+		Key       string `json:"key"`
+		Value     string `json:"value"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Key == "" {
+		http.Error(w, "Missing required field: key", http.StatusBadRequest)
+		return
+	}
+
+	var result map[string]interface{}
+	switch req.Operation {
+	case "GET":
+		value, hit, node := userState.CacheCluster.Get(req.Key)
+		result = map[string]interface{}{"node": node, "hit": hit, "value": value}
+	case "PUT":
+		node, evicted := userState.CacheCluster.Put(req.Key, req.Value)
+		result = map[string]interface{}{"node": node, "evicted": evicted}
+	default:
+		http.Error(w, "Invalid operation. Must be GET or PUT", http.StatusBadRequest)
+		return
+	}
+
+	response := map[string]interface{}{
+		"result": result,
+		"state":  userState.CacheCluster.GetState(),
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// ChangeClusterNode adds or removes a node and reports the keys that moved
+// POST /api/cache/cluster/node
+// Body: {"action": "add", "node": "node-5"} (node is optional when adding)
+func ChangeClusterNode(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	// Parse request body
+	var req struct {
+		Action string `json:"action"`
+		Node   string `json:"node"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var rebalance cache.Rebalance
+	var err error
+	switch req.Action {
+	case "add":
+		rebalance, err = userState.CacheCluster.AddNode(req.Node)
+	case "remove":
+		rebalance, err = userState.CacheCluster.RemoveNode(req.Node)
+	default:
+		http.Error(w, "Invalid action. Must be add or remove", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := map[string]interface{}{
+		"rebalance": rebalance,
+		"state":     userState.CacheCluster.GetState(),
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// PopulateCluster writes synthetic keys key-1..key-N across the cluster
+// POST /api/cache/cluster/populate
+// Body: {"count": 200}
+func PopulateCluster(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	// Parse request body
+	var req struct {
+		Count int `json:"count"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Count <= 0 || req.Count > maxCapacity {
+		http.Error(w, "count must be between 1 and "+strconv.Itoa(maxCapacity), http.StatusBadRequest)
+		return
+	}
+
+	userState.CacheCluster.Populate(req.Count)
+
+	responseJSON, err := json.Marshal(userState.CacheCluster.GetState())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// ConfigureCluster replaces the cluster with empty nodes
+// POST /api/cache/cluster/configure
+// Body: {"strategy": "ring", "nodes": 4, "vnodes": 100, "nodeCapacity": 100}
+// strategy is ring, rendezvous, jump or modulo
+func ConfigureCluster(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	// Parse request body, starting from the current configuration
+	config := userState.CacheCluster.Config()
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if config.NodeCapacity > maxCapacity {
+		http.Error(w, "nodeCapacity must be at most "+strconv.Itoa(maxCapacity), http.StatusBadRequest)
+		return
+	}
+
+	if err := userState.CacheCluster.Configure(config); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	responseJSON, err := json.Marshal(userState.CacheCluster.GetState())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// ResetCluster empties every node, keeping the membership
+// POST /api/cache/cluster/reset
+func ResetCluster(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	userState.CacheCluster.Reset()
+
+	responseJSON, err := json.Marshal(userState.CacheCluster.GetState())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// CompareRouting measures balance and key movement of every routing strategy
+// POST /api/cache/cluster/compare
+// Body: {"nodes": 4, "vnodes": 100, "keys": 10000}
+func CompareRouting(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Parse request body
+	req := struct {
+		Nodes  int `json:"nodes"`
+		VNodes int `json:"vnodes"`
+		Keys   int `json:"keys"`
+	}{Nodes: 4, VNodes: 100, Keys: 10000}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	results, err := cache.CompareRouting(req.Nodes, req.VNodes, req.Keys)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	responseJSON, err := json.Marshal(map[string]interface{}{"results": results})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
 // SetupRoutes registers all cache eviction endpoints
 func SetupRoutes(sm *session.Manager) {
 	sessionManager = sm
@@ -180,5 +1254,29 @@ func SetupRoutes(sm *session.Manager) {
 	http.HandleFunc("/api/cache/state", GetAllStates)
 	http.HandleFunc("/api/cache/access", AccessCache)
 	http.HandleFunc("/api/cache/reset", ResetAll)
+	http.HandleFunc("/api/cache/policies", GetPolicies)
+	http.HandleFunc("/api/cache/configure", ConfigurePolicies)
+	http.HandleFunc("/api/cache/opt", CompareToOPT)
+	http.HandleFunc("/api/cache/workload", RunWorkload)
+	http.HandleFunc("/api/cache/mrc", ComputeMRC)
+	http.HandleFunc("/api/cache/sized/state", GetSizedState)
+	http.HandleFunc("/api/cache/sized/access", AccessSized)
+	http.HandleFunc("/api/cache/sized/advance", AdvanceSized)
+	http.HandleFunc("/api/cache/sized/configure", ConfigureSized)
+	http.HandleFunc("/api/cache/sized/reset", ResetSized)
+	http.HandleFunc("/api/cache/sized/compare", CompareSized)
+	http.HandleFunc("/api/cache/write/state", GetWriteState)
+	http.HandleFunc("/api/cache/write/access", AccessWrite)
+	http.HandleFunc("/api/cache/write/configure", ConfigureWrite)
+	http.HandleFunc("/api/cache/write/reset", ResetWrite)
+	http.HandleFunc("/api/cache/write/compare", CompareWrite)
+	http.HandleFunc("/api/cache/stampede", SimulateStampede)
+	http.HandleFunc("/api/cache/cluster/state", GetClusterState)
+	http.HandleFunc("/api/cache/cluster/access", AccessCluster)
+	http.HandleFunc("/api/cache/cluster/node", ChangeClusterNode)
+	http.HandleFunc("/api/cache/cluster/populate", PopulateCluster)
+	http.HandleFunc("/api/cache/cluster/configure", ConfigureCluster)
+	http.HandleFunc("/api/cache/cluster/reset", ResetCluster)
+	http.HandleFunc("/api/cache/cluster/compare", CompareRouting)
 }
 
@@ -2,9 +2,12 @@ package mapreduce
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"sds/internal/session"
+	"sds/internal/simulation/mapreduce"
 )
 
 var sessionManager *session.Manager
@@ -184,6 +187,690 @@ func ResetJob(w http.ResponseWriter, r *http.Request) {
 	w.Write(responseJSON)
 }
 
+// ListJobTypes returns the registered job types and partitioners
+// GET /api/mapreduce/jobs
+func ListJobTypes(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	response := map[string]interface{}{
+		"jobs":         mapreduce.RegisteredJobs(),
+		"partitioners": mapreduce.RegisteredPartitioners(),
+		"config":       userState.MapReduceJob.Config(),
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// SubmitJob replaces the session's job with a new one of the chosen type
+// POST /api/mapreduce/submit
+// Body: {"jobType": "grep", "documents": ["..."], "numMappers": 3, "numReducers": 2, "params": {"pattern": "error"}}
+func SubmitJob(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	config := mapreduce.DefaultJobConfig()
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := userState.MapReduceJob.Configure(config); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	state := userState.MapReduceJob.GetState()
+
+	responseJSON, err := json.Marshal(state)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// GetMasterState returns the master, its workers and the tasks they run
+// GET /api/mapreduce/master/state
+func GetMasterState(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	state := userState.MapReduceMaster.GetState()
+
+	responseJSON, err := json.Marshal(state)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// ConfigureMaster changes the simulated cluster and restarts the job from scratch
+// POST /api/mapreduce/master/configure
+// Body: {"workers": 4, "heartbeatMs": 100, "failureTimeoutMs": 500, "mapTaskMs": 1000, "reduceTaskMs": 1000, "checkpointMs": 2000, "masterRecoveryMs": 1000}
+func ConfigureMaster(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	config := userState.MapReduceMaster.Config()
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := userState.MapReduceMaster.Configure(config); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	state := userState.MapReduceMaster.GetState()
+
+	responseJSON, err := json.Marshal(state)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// StartMaster loads the session's submitted job into the master and starts it from scratch
+// POST /api/mapreduce/master/start
+func StartMaster(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	if err := userState.MapReduceMaster.Load(userState.MapReduceJob.Config()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := userState.MapReduceMaster.Start(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	state := userState.MapReduceMaster.GetState()
+
+	responseJSON, err := json.Marshal(state)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// AdvanceMaster moves the simulation's virtual clock forward
+// POST /api/mapreduce/master/advance?ms=500
+func AdvanceMaster(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	ms, err := strconv.ParseInt(r.URL.Query().Get("ms"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ms parameter", http.StatusBadRequest)
+		return
+	}
+
+	if err := userState.MapReduceMaster.Advance(ms); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	state := userState.MapReduceMaster.GetState()
+
+	responseJSON, err := json.Marshal(state)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// RunMaster advances until the job completes or aborts
+// POST /api/mapreduce/master/run
+func RunMaster(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	userState.MapReduceMaster.Run()
+
+	state := userState.MapReduceMaster.GetState()
+
+	responseJSON, err := json.Marshal(state)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// CrashNode crashes a worker or the master, now or at a future virtual time
+// POST /api/mapreduce/master/crash
+// Body: {"target": "worker", "worker": 1, "atMs": 1500} or {"target": "master"}
+func CrashNode(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	var req struct {
+		Target string `json:"target"`
+		Worker int    `json:"worker"`
+		AtMs   int64  `json:"atMs"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	switch req.Target {
+	case "worker":
+		err = userState.MapReduceMaster.CrashWorker(req.Worker, req.AtMs)
+	case "master":
+		err = userState.MapReduceMaster.CrashMaster(req.AtMs)
+	default:
+		err = fmt.Errorf("target must be worker or master")
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	state := userState.MapReduceMaster.GetState()
+
+	responseJSON, err := json.Marshal(state)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// RecoverWorker restarts a crashed worker with an empty local disk
+// POST /api/mapreduce/master/recover
+// Body: {"worker": 1}
+func RecoverWorker(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	var req struct {
+		Worker int `json:"worker"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := userState.MapReduceMaster.RecoverWorker(req.Worker); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	state := userState.MapReduceMaster.GetState()
+
+	responseJSON, err := json.Marshal(state)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// ResetMaster restarts the master's job from scratch
+// POST /api/mapreduce/master/reset
+func ResetMaster(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	userState.MapReduceMaster.Reset()
+
+	state := userState.MapReduceMaster.GetState()
+
+	responseJSON, err := json.Marshal(state)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// CompareSpeculation runs the submitted job with and without backup tasks
+// POST /api/mapreduce/master/speculation
+// Body: master config overrides, e.g. {"workers": 4, "workerSpeeds": [1, 1, 1, 0.2]}
+func CompareSpeculation(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	config := userState.MapReduceMaster.Config()
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	report, err := mapreduce.CompareSpeculation(config, userState.MapReduceJob.Config())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	responseJSON, err := json.Marshal(report)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// AnalyzeSkew compares reducer loads of the submitted job under every partitioner, with and without a combiner
+// POST /api/mapreduce/skew
+// Body: job config overrides, e.g. {"numReducers": 4, "documents": ["..."]}
+func AnalyzeSkew(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	config := userState.MapReduceJob.Config()
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	results, err := mapreduce.AnalyzeShuffle(config)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	responseJSON, err := json.Marshal(map[string]interface{}{"results": results})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// ExecuteJob runs the submitted job concurrently on goroutine workers with real timings
+// POST /api/mapreduce/execute
+// Body: {"workers": 4, "splitBytes": 65536, "shuffle": "external", "spillBytes": 262144, "mergeFactor": 16, "generateBytes": 8388608, "seed": 1}
+// generateBytes replaces the job's documents with a generated corpus of that size
+func ExecuteJob(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	req := struct {
+		mapreduce.ExecConfig
+		GenerateBytes int   `json:"generateBytes"`
+		Seed          int64 `json:"seed"`
+	}{ExecConfig: mapreduce.DefaultExecConfig(), Seed: 1}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	config := userState.MapReduceJob.Config()
+	if req.GenerateBytes > 0 {
+		documents, err := mapreduce.GenerateCorpus(req.GenerateBytes, req.Seed)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		config.Documents = documents
+	}
+
+	result, err := mapreduce.Execute(config, req.ExecConfig)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	responseJSON, err := json.Marshal(result)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// CompareLocality schedules the submitted job's map tasks on a rack topology with each scheduler
+// POST /api/mapreduce/locality
+// Body: locality config overrides, e.g. {"racks": 4, "nodesPerRack": 4, "splits": 64, "crossRackMBps": 25}
+func CompareLocality(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	config := mapreduce.DefaultLocalityConfig()
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	report, err := mapreduce.CompareLocality(config, userState.MapReduceJob.Config())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	responseJSON, err := json.Marshal(report)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// GetPipelineState returns every stage of the session's pipeline with its intermediate data
+// GET /api/mapreduce/pipeline/state
+func GetPipelineState(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	state := userState.MapReducePipeline.GetState()
+
+	responseJSON, err := json.Marshal(state)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// ListStageOps returns the pipeline stage operations and example pipelines
+// GET /api/mapreduce/pipeline/ops
+func ListStageOps(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	responseJSON, err := json.Marshal(map[string]interface{}{
+		"ops":     mapreduce.StageOps(),
+		"presets": mapreduce.PipelinePresets(),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// ConfigurePipeline replaces the session's pipeline with a preset or a DAG of stages
+// POST /api/mapreduce/pipeline/configure
+// Body: {"preset": "orders-per-user"} or {"name": "...", "stages": [{"id": "input", "op": "source", "documents": ["..."]}, ...]}
+func ConfigurePipeline(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	var req struct {
+		mapreduce.PipelineConfig
+		Preset string `json:"preset"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	config := req.PipelineConfig
+	if req.Preset != "" {
+		found := false
+		for _, preset := range mapreduce.PipelinePresets() {
+			if preset.Name == req.Preset {
+				config, found = preset, true
+			}
+		}
+		if !found {
+			http.Error(w, fmt.Sprintf("unknown preset %q", req.Preset), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := userState.MapReducePipeline.Configure(config); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	state := userState.MapReducePipeline.GetState()
+
+	responseJSON, err := json.Marshal(state)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// StepPipeline runs the next stage of the session's pipeline
+// POST /api/mapreduce/pipeline/step
+func StepPipeline(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	userState.MapReducePipeline.Step()
+
+	state := userState.MapReducePipeline.GetState()
+
+	responseJSON, err := json.Marshal(state)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// RunPipeline runs every remaining stage of the session's pipeline
+// POST /api/mapreduce/pipeline/run
+func RunPipeline(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	userState.MapReducePipeline.Run()
+
+	state := userState.MapReducePipeline.GetState()
+
+	responseJSON, err := json.Marshal(state)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// ResetPipeline clears every stage's output
+// POST /api/mapreduce/pipeline/reset
+func ResetPipeline(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	userState.MapReducePipeline.Reset()
+
+	state := userState.MapReducePipeline.GetState()
+
+	responseJSON, err := json.Marshal(state)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
 // SetupRoutes registers all MapReduce endpoints
 func SetupRoutes(sm *session.Manager) {
 	sessionManager = sm
@@ -194,5 +881,25 @@ func SetupRoutes(sm *session.Manager) {
 	http.HandleFunc("/api/mapreduce/execute-shuffle", ExecuteShufflePhase)
 	http.HandleFunc("/api/mapreduce/execute-reduce", ExecuteReducePhase)
 	http.HandleFunc("/api/mapreduce/reset", ResetJob)
+	http.HandleFunc("/api/mapreduce/jobs", ListJobTypes)
+	http.HandleFunc("/api/mapreduce/submit", SubmitJob)
+	http.HandleFunc("/api/mapreduce/skew", AnalyzeSkew)
+	http.HandleFunc("/api/mapreduce/execute", ExecuteJob)
+	http.HandleFunc("/api/mapreduce/locality", CompareLocality)
+	http.HandleFunc("/api/mapreduce/master/state", GetMasterState)
+	http.HandleFunc("/api/mapreduce/master/configure", ConfigureMaster)
+	http.HandleFunc("/api/mapreduce/master/start", StartMaster)
+	http.HandleFunc("/api/mapreduce/master/advance", AdvanceMaster)
+	http.HandleFunc("/api/mapreduce/master/run", RunMaster)
+	http.HandleFunc("/api/mapreduce/master/crash", CrashNode)
+	http.HandleFunc("/api/mapreduce/master/recover", RecoverWorker)
+	http.HandleFunc("/api/mapreduce/master/reset", ResetMaster)
+	http.HandleFunc("/api/mapreduce/master/speculation", CompareSpeculation)
+	http.HandleFunc("/api/mapreduce/pipeline/state", GetPipelineState)
+	http.HandleFunc("/api/mapreduce/pipeline/ops", ListStageOps)
+	http.HandleFunc("/api/mapreduce/pipeline/configure", ConfigurePipeline)
+	http.HandleFunc("/api/mapreduce/pipeline/step", StepPipeline)
+	http.HandleFunc("/api/mapreduce/pipeline/run", RunPipeline)
+	http.HandleFunc("/api/mapreduce/pipeline/reset", ResetPipeline)
 }
 
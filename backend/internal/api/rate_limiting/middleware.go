@@ -0,0 +1,89 @@
+package rate_limiting
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"sds/internal/simulation/rate_limiting"
+)
+
+// Middleware protects next with the given limiter
+// Every response carries the IETF RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers; rejected requests get 429 with Retry-After
+func Middleware(limiter rate_limiting.RateLimiter, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		allowed := limiter.AllowRequest()
+		quota := limiter.Quota()
+
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(quota.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(quota.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(quota.Reset)))
+
+		if !allowed {
+			// Retry-After must be at least one second to be meaningful to clients
+			w.Header().Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(quota.RetryAfter))))
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ceilSeconds rounds a duration up to whole seconds, as the headers require
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
+
+// demoHandler is the "real" endpoint sitting behind the limiter
+var demoHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	response := map[string]interface{}{
+		"message":   "Request served",
+		"timestamp": time.Now(),
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+})
+
+// ProtectedDemo serves a demo endpoint behind one of the session's limiters,
+// so load tools hit the same limiter instance the visualization shows
+// GET /api/rate-limiting/demo?algorithm=<fixedWindow|slidingLog|slidingWindow|tokenBucket|leakyBucket|gcra|concurrencyAIMD|concurrencyVegas>
+func ProtectedDemo(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	w.Header().Set("Access-Control-Expose-Headers", "RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+
+	algorithm := r.URL.Query().Get("algorithm")
+	if algorithm == "" {
+		algorithm = "tokenBucket"
+	}
+
+	limiter, exists := limitersFor(userState)[algorithm]
+	if !exists {
+		http.Error(w, "Unknown algorithm: "+algorithm, http.StatusBadRequest)
+		return
+	}
+
+	Middleware(limiter, demoHandler).ServeHTTP(w, r)
+}
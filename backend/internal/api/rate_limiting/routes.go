@@ -3,13 +3,23 @@ package rate_limiting
 import (
 	"encoding/json"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 	
 	"sds/internal/session"
+	"sds/internal/simulation/rate_limiting"
 )
 
 var sessionManager *session.Manager
 
+// Bounds on what one call may send to a session's limiters
+const (
+	maxSessionRequests = 10000                           // Requests per burst or replay
+	maxReplaySpanMs    = rate_limiting.MaxWorkloadSpanMs // Virtual time a replay may cover
+)
+
 // Helper function to extract session ID from request
 func getSessionID(r *http.Request) string {
 	// Try header first
@@ -35,6 +45,54 @@ func setCORSHeaders(w http.ResponseWriter) {
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Session-ID")
 }
 
+// limitersFor returns the session's rate limiters keyed by their JSON name
+func limitersFor(userState *session.State) map[string]rate_limiting.RateLimiter {
+	return map[string]rate_limiting.RateLimiter{
+		"fixedWindow":      userState.FixedWindow,
+		"slidingLog":       userState.SlidingLog,
+		"slidingWindow":    userState.SlidingWindow,
+		"tokenBucket":      userState.TokenBucket,
+		"leakyBucket":      userState.LeakyBucket,
+		"gcra":             userState.GCRA,
+		"concurrencyAIMD":  userState.ConcurrencyAIMD,
+		"concurrencyVegas": userState.ConcurrencyVegas,
+	}
+}
+
+// allStates returns the visualization state of every rate limiter
+func allStates(userState *session.State) map[string]interface{} {
+	states := make(map[string]interface{})
+	for name, limiter := range limitersFor(userState) {
+		states[name] = limiter.GetState()
+	}
+	return states
+}
+
+// allowAll sends one request to every rate limiter and returns the decisions
+func allowAll(userState *session.State) map[string]interface{} {
+	results := make(map[string]interface{})
+	for name, limiter := range limitersFor(userState) {
+		results[name] = limiter.AllowRequest()
+	}
+	return results
+}
+
+// setClock moves every rate limiter onto the given clock (resetting them)
+func setClock(userState *session.State, clock rate_limiting.Clock) {
+	for _, limiter := range limitersFor(userState) {
+		limiter.SetClock(clock)
+	}
+	userState.HierarchicalLimiter.SetClock(clock)
+}
+
+// buildTimeline generates the workload, or parses the CSV trace for pattern "replay"
+func buildTimeline(workload rate_limiting.WorkloadConfig, trace string) ([]rate_limiting.ScheduledRequest, error) {
+	if workload.Pattern == rate_limiting.PatternReplay {
+		return rate_limiting.ParseTraceCSV(strings.NewReader(trace))
+	}
+	return rate_limiting.GenerateWorkload(workload)
+}
+
 // GetAllStates returns the current state of all rate limiters
 // GET /api/rate-limiting/state
 func GetAllStates(w http.ResponseWriter, r *http.Request) {
@@ -49,13 +107,7 @@ func GetAllStates(w http.ResponseWriter, r *http.Request) {
 	sessionID := getSessionID(r)
 	userState := sessionManager.GetOrCreate(sessionID)
 	
-	response := map[string]interface{}{
-		"fixedWindow":   userState.FixedWindow.GetState(),
-		"slidingLog":    userState.SlidingLog.GetState(),
-		"slidingWindow": userState.SlidingWindow.GetState(),
-		"tokenBucket":   userState.TokenBucket.GetState(),
-		"leakyBucket":   userState.LeakyBucket.GetState(),
-	}
+	response := allStates(userState)
 	
 	responseJSON, err := json.Marshal(response)
 	if err != nil {
@@ -82,24 +134,14 @@ func SendRequest(w http.ResponseWriter, r *http.Request) {
 	userState := sessionManager.GetOrCreate(sessionID)
 	
 	// Send request to all rate limiters
-	results := map[string]interface{}{
-		"fixedWindow":   userState.FixedWindow.AllowRequest(),
-		"slidingLog":    userState.SlidingLog.AllowRequest(),
-		"slidingWindow": userState.SlidingWindow.AllowRequest(),
-		"tokenBucket":   userState.TokenBucket.AllowRequest(),
-		"leakyBucket":   userState.LeakyBucket.AllowRequest(),
-	}
+	userState.RateLimitMu.Lock()
+	defer userState.RateLimitMu.Unlock()
+	results := allowAll(userState)
 	
 	// Get updated states
 	response := map[string]interface{}{
 		"results": results,
-		"states": map[string]interface{}{
-			"fixedWindow":   userState.FixedWindow.GetState(),
-			"slidingLog":    userState.SlidingLog.GetState(),
-			"slidingWindow": userState.SlidingWindow.GetState(),
-			"tokenBucket":   userState.TokenBucket.GetState(),
-			"leakyBucket":   userState.LeakyBucket.GetState(),
-		},
+		"states":  allStates(userState),
 	}
 	
 	responseJSON, err := json.Marshal(response)
@@ -113,7 +155,8 @@ func SendRequest(w http.ResponseWriter, r *http.Request) {
 }
 
 // SendBurstRequests sends multiple requests at once
-// POST /api/rate-limiting/send-burst?count=<number>
+// POST /api/rate-limiting/send-burst?count=<number>&intervalMs=<ms>
+// intervalMs spaces the requests out on the virtual clock (ignored in real time)
 func SendBurstRequests(w http.ResponseWriter, r *http.Request) {
 	setCORSHeaders(w)
 	
@@ -132,31 +175,34 @@ func SendBurstRequests(w http.ResponseWriter, r *http.Request) {
 	if err != nil || count <= 0 {
 		count = 20 // Default to 20
 	}
+	if count > maxSessionRequests {
+		http.Error(w, "count must be at most "+strconv.Itoa(maxSessionRequests), http.StatusBadRequest)
+		return
+	}
+	
+	// Optional spacing between requests on the virtual clock
+	intervalMs, err := strconv.Atoi(r.URL.Query().Get("intervalMs"))
+	if err != nil || intervalMs < 0 {
+		intervalMs = 0
+	}
 	
 	// Send multiple requests to all rate limiters
+	userState.RateLimitMu.Lock()
+	defer userState.RateLimitMu.Unlock()
 	allResults := make([]map[string]interface{}, count)
 	
 	for i := 0; i < count; i++ {
-		allResults[i] = map[string]interface{}{
-			"fixedWindow":   userState.FixedWindow.AllowRequest(),
-			"slidingLog":    userState.SlidingLog.AllowRequest(),
-			"slidingWindow": userState.SlidingWindow.AllowRequest(),
-			"tokenBucket":   userState.TokenBucket.AllowRequest(),
-			"leakyBucket":   userState.LeakyBucket.AllowRequest(),
+		if i > 0 && userState.RateLimitClock != nil {
+			userState.RateLimitClock.Advance(time.Duration(intervalMs) * time.Millisecond)
 		}
+		allResults[i] = allowAll(userState)
 	}
 	
 	// Get final states
 	response := map[string]interface{}{
 		"count":   count,
 		"results": allResults,
-		"states": map[string]interface{}{
-			"fixedWindow":   userState.FixedWindow.GetState(),
-			"slidingLog":    userState.SlidingLog.GetState(),
-			"slidingWindow": userState.SlidingWindow.GetState(),
-			"tokenBucket":   userState.TokenBucket.GetState(),
-			"leakyBucket":   userState.LeakyBucket.GetState(),
-		},
+		"states":  allStates(userState),
 	}
 	
 	responseJSON, err := json.Marshal(response)
@@ -169,7 +215,7 @@ func SendBurstRequests(w http.ResponseWriter, r *http.Request) {
 	w.Write(responseJSON)
 }
 
-// ResetAll resets all rate limiters
+// ResetAll resets all rate limiters and switches them back to real time
 // POST /api/rate-limiting/reset
 func ResetAll(w http.ResponseWriter, r *http.Request) {
 	setCORSHeaders(w)
@@ -183,19 +229,522 @@ func ResetAll(w http.ResponseWriter, r *http.Request) {
 	sessionID := getSessionID(r)
 	userState := sessionManager.GetOrCreate(sessionID)
 	
-	userState.FixedWindow.Reset()
-	userState.SlidingLog.Reset()
-	userState.SlidingWindow.Reset()
-	userState.TokenBucket.Reset()
-	userState.LeakyBucket.Reset()
+	// Return to the wall clock (this also resets every limiter)
+	userState.RateLimitMu.Lock()
+	defer userState.RateLimitMu.Unlock()
+	userState.RateLimitClock = nil
+	setClock(userState, rate_limiting.RealClock())
 	
 	// Return new states
+	response := allStates(userState)
+	
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// ReplayRequests replays a request timeline on a fresh virtual clock
+// POST /api/rate-limiting/replay
+// Body: {"offsetsMs": [0, 59900, 60000, 60100]}
+func ReplayRequests(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+	
+	// Parse request body
+	var req struct {
+		OffsetsMs []int64 `json:"offsetsMs"`
+	}
+	
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	
+	if len(req.OffsetsMs) == 0 {
+		http.Error(w, "offsetsMs must contain at least one offset", http.StatusBadRequest)
+		return
+	}
+	if len(req.OffsetsMs) > maxSessionRequests {
+		http.Error(w, "offsetsMs must contain at most "+strconv.Itoa(maxSessionRequests)+" offsets", http.StatusBadRequest)
+		return
+	}
+	
+	// Virtual time only moves forward, so replay in timestamp order
+	offsets := append([]int64(nil), req.OffsetsMs...)
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	if offsets[0] < 0 || offsets[len(offsets)-1] > maxReplaySpanMs {
+		http.Error(w, "offsetsMs must be between 0 and "+strconv.Itoa(maxReplaySpanMs), http.StatusBadRequest)
+		return
+	}
+	
+	// Start every limiter on the same virtual clock
+	userState.RateLimitMu.Lock()
+	defer userState.RateLimitMu.Unlock()
+	start := time.Now().Truncate(time.Second)
+	clock := rate_limiting.NewVirtualClock(start)
+	userState.RateLimitClock = clock
+	setClock(userState, clock)
+	
+	allResults := make([]map[string]interface{}, 0, len(offsets))
+	for _, offset := range offsets {
+		clock.Set(start.Add(time.Duration(offset) * time.Millisecond))
+		allResults = append(allResults, map[string]interface{}{
+			"offsetMs":  offset,
+			"timestamp": clock.Now(),
+			"results":   allowAll(userState),
+		})
+	}
+	
+	response := map[string]interface{}{
+		"startTime": start,
+		"count":     len(offsets),
+		"results":   allResults,
+		"states":    allStates(userState),
+	}
+	
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// AdvanceClock moves the virtual clock forward without sending requests
+// POST /api/rate-limiting/clock/advance?ms=<number>
+func AdvanceClock(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+	
+	ms, err := strconv.Atoi(r.URL.Query().Get("ms"))
+	if err != nil || ms <= 0 {
+		http.Error(w, "Invalid ms parameter", http.StatusBadRequest)
+		return
+	}
+	
+	userState.RateLimitMu.Lock()
+	defer userState.RateLimitMu.Unlock()
+	if userState.RateLimitClock == nil {
+		http.Error(w, "Rate limiters are running in real time; start a replay first", http.StatusBadRequest)
+		return
+	}
+	
+	userState.RateLimitClock.Advance(time.Duration(ms) * time.Millisecond)
+	
+	response := map[string]interface{}{
+		"now":    userState.RateLimitClock.Now(),
+		"states": allStates(userState),
+	}
+	
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// CompareAlgorithms drives every algorithm with the same generated workload
+// POST /api/rate-limiting/compare
+// Body: {"limit": 10, "windowSeconds": 1, "workload": {"pattern": "poisson", "durationSeconds": 60, "rate": 15, "clients": 5}}
+// For pattern "replay" pass the timeline as CSV in "trace" (rows of offsetMs[,clientId])
+func CompareAlgorithms(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	
+	// Parse request body
+	var req struct {
+		rate_limiting.LimiterSpec
+		Workload rate_limiting.WorkloadConfig `json:"workload"`
+		Trace    string                       `json:"trace"`
+	}
+	
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	
+	// Build the request timeline
+	timeline, err := buildTimeline(req.Workload, req.Trace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	
+	report, err := rate_limiting.CompareAlgorithms(req.LimiterSpec, timeline)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	
+	responseJSON, err := json.Marshal(report)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// SetBackend changes the simulated backend behind the adaptive concurrency limiters
+// POST /api/rate-limiting/concurrency/backend
+// Body: {"baseLatencyMs": 800, "capacity": 2, "timeoutMs": 2000}
+func SetBackend(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+	
+	// Parse request body
+	var req struct {
+		BaseLatencyMs int `json:"baseLatencyMs"`
+		Capacity      int `json:"capacity"`
+		TimeoutMs     int `json:"timeoutMs"`
+	}
+	
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	
+	if req.BaseLatencyMs <= 0 || req.Capacity <= 0 || req.TimeoutMs < 0 {
+		http.Error(w, "baseLatencyMs and capacity must be positive", http.StatusBadRequest)
+		return
+	}
+	
+	backend := rate_limiting.BackendModel{
+		BaseLatency: time.Duration(req.BaseLatencyMs) * time.Millisecond,
+		Capacity:    req.Capacity,
+		Timeout:     time.Duration(req.TimeoutMs) * time.Millisecond,
+	}
+	userState.ConcurrencyAIMD.SetBackend(backend)
+	userState.ConcurrencyVegas.SetBackend(backend)
+	
+	response := allStates(userState)
+	
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// keyedStates returns the state of every keyed limiter keyed by algorithm
+func keyedStates(userState *session.State) map[string]interface{} {
+	states := make(map[string]interface{})
+	for _, limiter := range userState.KeyedLimiters {
+		states[limiter.Algorithm()] = limiter.GetState()
+	}
+	return states
+}
+
+// GetKeyedStates returns the state of the per-client keyed limiters
+// GET /api/rate-limiting/keyed/state
+func GetKeyedStates(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+	
+	response := keyedStates(userState)
+	
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// SendKeyedRequest sends a request for one client to every keyed limiter
+// POST /api/rate-limiting/keyed/send-request?key=<client>
+func SendKeyedRequest(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+	
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		http.Error(w, "Missing required parameter: key", http.StatusBadRequest)
+		return
+	}
+	
+	results := make(map[string]interface{})
+	for _, limiter := range userState.KeyedLimiters {
+		results[limiter.Algorithm()] = limiter.AllowRequest(key)
+	}
+	
+	response := map[string]interface{}{
+		"key":     key,
+		"results": results,
+		"states":  keyedStates(userState),
+	}
+	
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// SimulateKeyed runs a many-client workload through keyed limiters and
+// reports memory per algorithm
+// POST /api/rate-limiting/keyed/simulate
+// Body: {"limit": 10, "windowSeconds": 60, "maxKeys": 5000, "idleTTLSeconds": 120, "workload": {"pattern": "poisson", "durationSeconds": 300, "rate": 200, "clients": 10000}}
+func SimulateKeyed(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	
+	// Parse request body
+	var req struct {
+		rate_limiting.LimiterSpec
+		MaxKeys        int                          `json:"maxKeys"`
+		IdleTTLSeconds float64                      `json:"idleTTLSeconds"`
+		Workload       rate_limiting.WorkloadConfig `json:"workload"`
+		Trace          string                       `json:"trace"`
+	}
+	
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	
+	timeline, err := buildTimeline(req.Workload, req.Trace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	
+	idleTTL := time.Duration(req.IdleTTLSeconds * float64(time.Second))
+	states, err := rate_limiting.SimulateKeyed(req.LimiterSpec, req.MaxKeys, idleTTL, timeline)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	
+	response := map[string]interface{}{
+		"totalRequests": len(timeline),
+		"algorithms":    states,
+	}
+	
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// ResetKeyed clears every keyed limiter
+// POST /api/rate-limiting/keyed/reset
+func ResetKeyed(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+	
+	for _, limiter := range userState.KeyedLimiters {
+		limiter.Reset()
+	}
+	
+	response := keyedStates(userState)
+	
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// SimulateDistributed compares ways of sharing one limit across gateway nodes
+// POST /api/rate-limiting/distributed/simulate
+// Body: {"limit": 100, "windowSeconds": 1, "nodes": 4, "storeLatencyMs": 2, "syncIntervalMs": 500, "leaseSize": 10, "workload": {"pattern": "poisson", "durationSeconds": 30, "rate": 150}}
+func SimulateDistributed(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	
+	// Parse request body
+	var req struct {
+		rate_limiting.LimiterSpec
+		rate_limiting.DistributedConfig
+		Workload rate_limiting.WorkloadConfig `json:"workload"`
+		Trace    string                       `json:"trace"`
+	}
+	
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	
+	timeline, err := buildTimeline(req.Workload, req.Trace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	
+	report, err := rate_limiting.SimulateDistributed(req.LimiterSpec, req.DistributedConfig, timeline)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	
+	responseJSON, err := json.Marshal(report)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// GetHierarchicalState returns per-tier usage and recent decisions
+// GET /api/rate-limiting/hierarchical/state
+func GetHierarchicalState(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+	
+	response := userState.HierarchicalLimiter.GetState()
+	
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// SendHierarchicalRequest sends count requests from one tenant/user to one endpoint
+// POST /api/rate-limiting/hierarchical/send-request
+// Body: {"tenant": "acme", "user": "alice", "endpoint": "GET /search", "count": 5}
+func SendHierarchicalRequest(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+	
+	// Parse request body
+	var req struct {
+		rate_limiting.HierarchicalRequest
+		Count int `json:"count"`
+	}
+	
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	
+	if req.Tenant == "" || req.User == "" || req.Endpoint == "" {
+		http.Error(w, "tenant, user and endpoint are required", http.StatusBadRequest)
+		return
+	}
+	if req.Count <= 0 {
+		req.Count = 1
+	}
+	if req.Count > 100 {
+		req.Count = 100 // Limit to prevent abuse
+	}
+	
+	decisions := make([]rate_limiting.TierDecision, 0, req.Count)
+	for i := 0; i < req.Count; i++ {
+		decisions = append(decisions, userState.HierarchicalLimiter.AllowRequest(req.HierarchicalRequest))
+	}
+	
 	response := map[string]interface{}{
-		"fixedWindow":   userState.FixedWindow.GetState(),
-		"slidingLog":    userState.SlidingLog.GetState(),
-		"slidingWindow": userState.SlidingWindow.GetState(),
-		"tokenBucket":   userState.TokenBucket.GetState(),
-		"leakyBucket":   userState.LeakyBucket.GetState(),
+		"decisions": decisions,
+		"state":     userState.HierarchicalLimiter.GetState(),
 	}
 	
 	responseJSON, err := json.Marshal(response)
@@ -208,6 +757,73 @@ func ResetAll(w http.ResponseWriter, r *http.Request) {
 	w.Write(responseJSON)
 }
 
+// ConfigureHierarchy replaces the tier rules and plans (clearing usage)
+// POST /api/rate-limiting/hierarchical/configure
+// Body: {"rules": [{"level": "global", "algorithm": "tokenBucket", "limit": 50, "windowSeconds": 1}, ...], "plans": {"pro": [...]}, "tenants": {"acme": "pro"}}
+func ConfigureHierarchy(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+	
+	// Parse request body
+	var config rate_limiting.HierarchyConfig
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	
+	if err := userState.HierarchicalLimiter.Configure(config); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	
+	response := userState.HierarchicalLimiter.GetState()
+	
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// ResetHierarchy clears every tier's usage, keeping the configuration
+// POST /api/rate-limiting/hierarchical/reset
+func ResetHierarchy(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	
+	// Get user's session
+	sessionID := getSessionID(r)
+	userState := sessionManager.GetOrCreate(sessionID)
+	
+	userState.HierarchicalLimiter.Reset()
+	
+	response := userState.HierarchicalLimiter.GetState()
+	
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
 // SetupRoutes registers all rate limiting endpoints
 func SetupRoutes(sm *session.Manager) {
 	sessionManager = sm
@@ -216,5 +832,19 @@ func SetupRoutes(sm *session.Manager) {
 	http.HandleFunc("/api/rate-limiting/send-request", SendRequest)
 	http.HandleFunc("/api/rate-limiting/send-burst", SendBurstRequests)
 	http.HandleFunc("/api/rate-limiting/reset", ResetAll)
+	http.HandleFunc("/api/rate-limiting/replay", ReplayRequests)
+	http.HandleFunc("/api/rate-limiting/clock/advance", AdvanceClock)
+	http.HandleFunc("/api/rate-limiting/compare", CompareAlgorithms)
+	http.HandleFunc("/api/rate-limiting/concurrency/backend", SetBackend)
+	http.HandleFunc("/api/rate-limiting/keyed/state", GetKeyedStates)
+	http.HandleFunc("/api/rate-limiting/keyed/send-request", SendKeyedRequest)
+	http.HandleFunc("/api/rate-limiting/keyed/simulate", SimulateKeyed)
+	http.HandleFunc("/api/rate-limiting/keyed/reset", ResetKeyed)
+	http.HandleFunc("/api/rate-limiting/distributed/simulate", SimulateDistributed)
+	http.HandleFunc("/api/rate-limiting/hierarchical/state", GetHierarchicalState)
+	http.HandleFunc("/api/rate-limiting/hierarchical/send-request", SendHierarchicalRequest)
+	http.HandleFunc("/api/rate-limiting/hierarchical/configure", ConfigureHierarchy)
+	http.HandleFunc("/api/rate-limiting/hierarchical/reset", ResetHierarchy)
+	http.HandleFunc("/api/rate-limiting/demo", ProtectedDemo)
 }
 
@@ -35,21 +35,48 @@ type State struct {
 	ThreePCParticipants []*three_phase_commit.Participant
 	ThreePCTransaction  *three_phase_commit.Transaction
 
-	// Rate Limiting simulations (all 5 algorithms)
-	FixedWindow    *rate_limiting.FixedWindowCounter
-	SlidingLog     *rate_limiting.SlidingLog
-	SlidingWindow  *rate_limiting.SlidingWindowCounter
-	TokenBucket    *rate_limiting.TokenBucket
-	LeakyBucket    *rate_limiting.LeakyBucket
-
-	// Cache Eviction simulations (3 algorithms)
-	LRUCache  *cache.LRUCache
-	LFUCache  *cache.LFUCache
-	FIFOCache *cache.FIFOCache
+	// Rate Limiting simulations
+	FixedWindow      *rate_limiting.FixedWindowCounter
+	SlidingLog       *rate_limiting.SlidingLog
+	SlidingWindow    *rate_limiting.SlidingWindowCounter
+	TokenBucket      *rate_limiting.TokenBucket
+	LeakyBucket      *rate_limiting.LeakyBucket
+	GCRA             *rate_limiting.GCRA
+	ConcurrencyAIMD  *rate_limiting.AdaptiveConcurrencyLimiter
+	ConcurrencyVegas *rate_limiting.AdaptiveConcurrencyLimiter
+
+	// Virtual clock driving the rate limiters during replays (nil = wall clock)
+	RateLimitClock *rate_limiting.VirtualClock
+	// Guards RateLimitClock and keeps bursts and replays from interleaving
+	RateLimitMu sync.Mutex
+
+	// Per-client keyed rate limiters (one per algorithm)
+	KeyedLimiters []*rate_limiting.KeyedLimiter
+
+	// Global / tenant / user / endpoint limits with daily and monthly quotas
+	HierarchicalLimiter *rate_limiting.HierarchicalLimiter
+
+	// Cache Eviction simulations (any registered policies, compared side by side)
+	Caches *cache.Group
+
+	// Byte-capacity cache with TTLs and cost-aware eviction
+	SizedCache *cache.SizedCache
+
+	// Cache-aside, read-through, write-through, write-back and write-around side by side
+	WriteCaches *cache.WriteSimulator
+
+	// Multi-node cache routed by consistent hashing
+	CacheCluster *cache.Cluster
 
 	// MapReduce simulation
 	MapReduceJob *mapreduce.Job
 
+	// MapReduce master scheduling the job on workers that can fail
+	MapReduceMaster *mapreduce.Master
+
+	// Multi-stage dataflow of chained jobs and DAG operators
+	MapReducePipeline *mapreduce.Pipeline
+
 	// CDC (Change Data Capture) simulation
 	CDCSystem *cdc.CDCSystem
 
@@ -104,6 +131,35 @@ func (m *Manager) GetOrCreate(sessionID string) *State {
 
 	// Create new isolated state for this user
 	now := time.Now()
+
+	defaultBackend := rate_limiting.BackendModel{
+		BaseLatency: 200 * time.Millisecond,
+		Capacity:    5,
+		Timeout:     2 * time.Second,
+	}
+
+	// Keyed limiters share the default limit, tracking up to 1000 clients
+	// and forgetting clients idle for 5 minutes
+	keyedLimiters, _ := rate_limiting.NewKeyedLimiterSet(
+		rate_limiting.LimiterSpec{Limit: 10, WindowSeconds: 60},
+		1000,
+		5*time.Minute,
+	)
+
+	// Start with the three classic eviction policies (capacity of 5 items each)
+	caches, _ := cache.NewGroup(cache.DefaultPolicies, 5)
+	sizedCache, _ := cache.NewSizedCache(cache.DefaultSizedConfig())
+	writeCaches, _ := cache.NewWriteSimulator(5, cache.DefaultStoreConfig())
+	cacheCluster, _ := cache.NewCluster(cache.DefaultClusterConfig())
+	mapReduceJob, _ := mapreduce.NewJob(mapreduce.DefaultJobConfig())
+	mapReduceMaster, _ := mapreduce.NewMaster(mapreduce.DefaultMasterConfig(), mapreduce.DefaultJobConfig())
+	mapReducePipeline, _ := mapreduce.NewPipeline(mapreduce.DefaultPipelineConfig())
+
+	// 1 request per 6 seconds, bursts of 10
+	gcra, _ := rate_limiting.NewGCRA(10, 60*time.Second, 10)
+
+	hierarchicalLimiter, _ := rate_limiting.NewHierarchicalLimiter(rate_limiting.DefaultHierarchyConfig())
+
 	m.sessions[sessionID] = &State{
 		// Initialize Raft cluster with 5 nodes
 		RaftCluster: raft.NewCluster(5),
@@ -122,24 +178,26 @@ func (m *Manager) GetOrCreate(sessionID string) *State {
 		SlidingWindow: rate_limiting.NewSlidingWindowCounter(10, 60*time.Second),
 		TokenBucket:   rate_limiting.NewTokenBucket(10, 10.0/60.0), // 10 tokens, refill at 1 token per 6 seconds
 		LeakyBucket:   rate_limiting.NewLeakyBucket(10, 10.0/60.0), // 10 capacity, process at 1 request per 6 seconds
+		GCRA:          gcra,
+		KeyedLimiters: keyedLimiters,
+
+		// Hierarchical limits modelled on the free and pro SaaS plans
+		HierarchicalLimiter: hierarchicalLimiter,
+
+		// Adaptive concurrency limiters in front of a backend serving 5 requests at once in 200ms
+		ConcurrencyAIMD:  rate_limiting.NewAdaptiveConcurrencyLimiter(rate_limiting.StrategyAIMD, 5, defaultBackend),
+		ConcurrencyVegas: rate_limiting.NewAdaptiveConcurrencyLimiter(rate_limiting.StrategyVegas, 5, defaultBackend),
 
-		// Initialize cache eviction algorithms (capacity of 5 items each)
-		LRUCache:  cache.NewLRUCache(5),
-		LFUCache:  cache.NewLFUCache(5),
-		FIFOCache: cache.NewFIFOCache(5),
+		// Initialize cache eviction policies
+		Caches:       caches,
+		SizedCache:   sizedCache,
+		WriteCaches:  writeCaches,
+		CacheCluster: cacheCluster,
 
 		// Initialize MapReduce job with sample word count data
-		MapReduceJob: mapreduce.NewJob(
-			"word-count-1",
-			[]string{
-				"hello world hello",
-				"world of mapreduce",
-				"hello mapreduce world",
-				"distributed computing rocks",
-			},
-			2, // 2 mappers
-			2, // 2 reducers
-		),
+		MapReduceJob:      mapReduceJob,
+		MapReduceMaster:   mapReduceMaster,
+		MapReducePipeline: mapReducePipeline,
 
 		// Initialize CDC system with sample database
 		CDCSystem: cdc.NewCDCSystem(),
@@ -0,0 +1,179 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// ARCCache implements the Adaptive Replacement Cache (Megiddo & Modha)
+// Resident keys live in T1 (seen once recently) or T2 (seen at least twice).
+// Ghost lists B1 and B2 remember keys recently evicted from T1 and T2; a miss
+// that hits a ghost list shifts the target size p of T1 towards whichever
+// list would have kept the key, so ARC balances recency and frequency itself
+type ARCCache struct {
+	mu       sync.RWMutex
+	capacity int
+	p        int      // Target size of T1
+	t1       *keyList // Resident, seen once
+	t2       *keyList // Resident, seen at least twice
+	b1       *keyList // Ghosts evicted from T1
+	b2       *keyList // Ghosts evicted from T2
+	eventLog
+}
+
+func init() {
+	Register("arc", "ARC (Adaptive Replacement Cache)", func(capacity int) Policy { return NewARCCache(capacity) })
+}
+
+// NewARCCache creates a new ARC cache with given capacity
+func NewARCCache(capacity int) *ARCCache {
+	return &ARCCache{
+		capacity: capacity,
+		t1:       newKeyList(),
+		t2:       newKeyList(),
+		b1:       newKeyList(),
+		b2:       newKeyList(),
+	}
+}
+
+// Get retrieves a value; a hit promotes the key to T2
+func (c *ARCCache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item := c.hit(key)
+	if item == nil {
+		c.eventLog.record("GET", key, "", false, "", c.getCurrentKeys)
+		return "", false
+	}
+
+	c.eventLog.record("GET", key, item.value, true, "", c.getCurrentKeys)
+	return item.value, true
+}
+
+// hit moves a resident key to the front of T2 (must be called with lock held)
+func (c *ARCCache) hit(key string) *entry {
+	item := c.t1.remove(key)
+	if item == nil {
+		item = c.t2.remove(key)
+	}
+	if item == nil {
+		return nil
+	}
+	item.accessTime = time.Now()
+	c.t2.pushFront(item)
+	return item
+}
+
+// Put adds or updates a key-value pair, adapting p on ghost hits
+func (c *ARCCache) Put(key, value string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Resident: update and promote
+	if item := c.hit(key); item != nil {
+		item.value = value
+		c.eventLog.record("PUT", key, value, true, "", c.getCurrentKeys)
+		return ""
+	}
+
+	evictedKey := ""
+	switch {
+	case c.b1.remove(key) != nil:
+		// Recently evicted from T1: recency deserves more room
+		c.p = min(c.capacity, c.p+max(1, c.b2.size()/max(1, c.b1.size()+1)))
+		evictedKey = c.replace(false)
+		c.t2.pushFront(newEntry(key, value))
+
+	case c.b2.remove(key) != nil:
+		// Recently evicted from T2: frequency deserves more room
+		c.p = max(0, c.p-max(1, c.b1.size()/max(1, c.b2.size()+1)))
+		evictedKey = c.replace(true)
+		c.t2.pushFront(newEntry(key, value))
+
+	default:
+		// Brand new key
+		if c.t1.size()+c.b1.size() >= c.capacity {
+			if c.t1.size() < c.capacity {
+				c.b1.popBack()
+				evictedKey = c.replace(false)
+			} else {
+				evictedKey = c.t1.popBack().key
+			}
+		} else if total := c.t1.size() + c.t2.size() + c.b1.size() + c.b2.size(); total >= c.capacity {
+			if total >= 2*c.capacity {
+				c.b2.popBack()
+			}
+			evictedKey = c.replace(false)
+		}
+		c.t1.pushFront(newEntry(key, value))
+	}
+
+	c.eventLog.record("PUT", key, value, false, evictedKey, c.getCurrentKeys)
+	return evictedKey
+}
+
+// replace evicts from T1 or T2 into its ghost list when the cache is full
+// (must be called with lock held)
+func (c *ARCCache) replace(inB2 bool) string {
+	if c.t1.size()+c.t2.size() < c.capacity {
+		return ""
+	}
+	if c.t1.size() > 0 && (c.t1.size() > c.p || (inB2 && c.t1.size() == c.p)) {
+		victim := c.t1.popBack()
+		c.b1.pushFront(&entry{key: victim.key})
+		return victim.key
+	}
+	victim := c.t2.popBack()
+	if victim == nil {
+		victim = c.t1.popBack()
+		c.b1.pushFront(&entry{key: victim.key})
+		return victim.key
+	}
+	c.b2.pushFront(&entry{key: victim.key})
+	return victim.key
+}
+
+// GetState returns current cache state, including ghost lists and p
+func (c *ARCCache) GetState() CacheState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	items := c.t1.appendItems([]CacheItem{}, "T1")
+	items = c.t2.appendItems(items, "T2")
+
+	return CacheState{
+		Algorithm: "ARC (Adaptive Replacement Cache)",
+		Capacity:  c.capacity,
+		Size:      c.t1.size() + c.t2.size(),
+		Items:     items,
+		History:   c.eventLog.recent(20),
+		Segments: []CacheSegment{
+			c.b1.segment("B1", 0, true),
+			c.t1.segment("T1", c.p, false),
+			c.t2.segment("T2", c.capacity-c.p, false),
+			c.b2.segment("B2", 0, true),
+		},
+		Details: map[string]interface{}{
+			"p": c.p,
+		},
+	}
+}
+
+// Reset clears the cache
+func (c *ARCCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.p = 0
+	c.t1 = newKeyList()
+	c.t2 = newKeyList()
+	c.b1 = newKeyList()
+	c.b2 = newKeyList()
+	c.eventLog.clear()
+}
+
+// getCurrentKeys returns resident keys, T1 then T2 (must be called with lock held)
+func (c *ARCCache) getCurrentKeys() []string {
+	return append(c.t1.keys(), c.t2.keys()...)
+}
@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestARCAdaptsToGhostHits(t *testing.T) {
+	c := NewARCCache(2)
+	p := func() int { return c.GetState().Details["p"].(int) }
+
+	c.Put("a", "a")
+	c.Get("a") // a moves to T2
+	c.Put("b", "b")
+	if evicted := c.Put("c", "c"); evicted != "b" {
+		t.Fatalf("Put(c) evicted %q, want b from T1", evicted)
+	}
+
+	// b was evicted from T1 too early: recency gets more room
+	if evicted := c.Put("b", "b"); evicted != "a" {
+		t.Errorf("Put(b) evicted %q, want a from T2", evicted)
+	}
+	if p() != 1 {
+		t.Errorf("p after a B1 hit = %d, want 1", p())
+	}
+
+	// a was evicted from T2 too early: frequency gets the room back
+	if evicted := c.Put("a", "a"); evicted != "c" {
+		t.Errorf("Put(a) evicted %q, want c from T1", evicted)
+	}
+	if p() != 0 {
+		t.Errorf("p after a B2 hit = %d, want 0", p())
+	}
+
+	// Keys returning from a ghost list are resident in T2
+	for _, segment := range c.GetState().Segments {
+		if segment.Name == "T2" && !(slices.Contains(segment.Keys, "a") && slices.Contains(segment.Keys, "b")) {
+			t.Errorf("T2 = %v, want a and b", segment.Keys)
+		}
+		if segment.Name == "B1" && !slices.Contains(segment.Keys, "c") {
+			t.Errorf("B1 = %v, want c", segment.Keys)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// ClockCache implements the CLOCK (second chance) policy
+// Keys sit in a circular buffer with a reference bit that every hit sets.
+// To evict, the hand sweeps the buffer clearing set bits and evicts the
+// first key whose bit is already clear: LRU-like behavior without moving
+// anything on a hit
+type ClockCache struct {
+	mu       sync.RWMutex
+	capacity int
+	slots    []*clockSlot   // Circular buffer, nil = empty slot
+	index    map[string]int // key -> slot
+	hand     int
+	eventLog
+}
+
+// clockSlot is one position in the clock
+type clockSlot struct {
+	entry
+	referenced bool
+}
+
+func init() {
+	Register("clock", "CLOCK (Second Chance)", func(capacity int) Policy { return NewClockCache(capacity) })
+}
+
+// NewClockCache creates a new CLOCK cache with given capacity
+func NewClockCache(capacity int) *ClockCache {
+	return &ClockCache{
+		capacity: capacity,
+		slots:    make([]*clockSlot, capacity),
+		index:    make(map[string]int),
+	}
+}
+
+// Get retrieves a value and sets its reference bit
+func (c *ClockCache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i, exists := c.index[key]
+	if !exists {
+		c.eventLog.record("GET", key, "", false, "", c.getCurrentKeys)
+		return "", false
+	}
+
+	slot := c.slots[i]
+	slot.referenced = true
+	slot.accessTime = time.Now()
+
+	c.eventLog.record("GET", key, slot.value, true, "", c.getCurrentKeys)
+	return slot.value, true
+}
+
+// Put adds or updates a key-value pair, sweeping the hand if the cache is full
+func (c *ClockCache) Put(key, value string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if i, exists := c.index[key]; exists {
+		slot := c.slots[i]
+		slot.value = value
+		slot.referenced = true
+		slot.accessTime = time.Now()
+		c.eventLog.record("PUT", key, value, true, "", c.getCurrentKeys)
+		return ""
+	}
+
+	// Advance until an empty slot or one without a second chance
+	evictedKey := ""
+	for c.slots[c.hand] != nil && c.slots[c.hand].referenced {
+		c.slots[c.hand].referenced = false
+		c.hand = (c.hand + 1) % c.capacity
+	}
+	if victim := c.slots[c.hand]; victim != nil {
+		evictedKey = victim.key
+		delete(c.index, victim.key)
+	}
+
+	c.slots[c.hand] = &clockSlot{entry: *newEntry(key, value)}
+	c.index[key] = c.hand
+	c.hand = (c.hand + 1) % c.capacity
+
+	c.eventLog.record("PUT", key, value, false, evictedKey, c.getCurrentKeys)
+	return evictedKey
+}
+
+// GetState returns current cache state in slot order with the hand position
+func (c *ClockCache) GetState() CacheState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	items := make([]CacheItem, 0, len(c.index))
+	for position, slot := range c.slots {
+		if slot == nil {
+			continue
+		}
+		items = append(items, CacheItem{
+			Key:        slot.key,
+			Value:      slot.value,
+			InsertTime: slot.insertTime,
+			AccessTime: slot.accessTime,
+			Position:   position,
+			Referenced: slot.referenced,
+		})
+	}
+
+	hand := c.hand
+	return CacheState{
+		Algorithm: "CLOCK (Second Chance)",
+		Capacity:  c.capacity,
+		Size:      len(c.index),
+		Items:     items,
+		History:   c.eventLog.recent(20),
+		ClockHand: &hand,
+	}
+}
+
+// Reset clears the cache
+func (c *ClockCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.slots = make([]*clockSlot, c.capacity)
+	c.index = make(map[string]int)
+	c.hand = 0
+	c.eventLog.clear()
+}
+
+// getCurrentKeys returns current keys in slot order (must be called with lock held)
+func (c *ClockCache) getCurrentKeys() []string {
+	keys := make([]string, 0, len(c.index))
+	for _, slot := range c.slots {
+		if slot != nil {
+			keys = append(keys, slot.key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,39 @@
+package cache
+
+import "testing"
+
+func TestClockSecondChance(t *testing.T) {
+	c := NewClockCache(3)
+	for _, key := range []string{"a", "b", "c"} {
+		c.Put(key, key)
+	}
+	c.Get("a")
+	c.Put("c", "c2") // An update also sets the reference bit
+
+	// The hand clears a's bit, skips it, and evicts b; the next sweep
+	// clears c's bit and evicts a, whose second chance is used up. New
+	// keys start unreferenced, so d goes before c
+	for _, step := range []struct{ put, evicted string }{
+		{"d", "b"},
+		{"e", "a"},
+		{"f", "d"},
+		{"g", "c"},
+	} {
+		if evicted := c.Put(step.put, step.put); evicted != step.evicted {
+			t.Errorf("Put(%s) evicted %q, want %q", step.put, evicted, step.evicted)
+		}
+	}
+
+	state := c.GetState()
+	if state.ClockHand == nil {
+		t.Fatal("no clock hand in the state")
+	}
+	if *state.ClockHand != 0 {
+		t.Errorf("hand = %d, want slot 0", *state.ClockHand)
+	}
+	for _, item := range state.Items {
+		if item.Referenced {
+			t.Errorf("%s is referenced, want no bits set", item.Key)
+		}
+	}
+}
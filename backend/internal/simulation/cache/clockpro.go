@@ -0,0 +1,322 @@
+package cache
+
+import (
+	"container/ring"
+	"sync"
+	"time"
+)
+
+// clockProPage is the status of a page on the CLOCK-Pro clock
+type clockProPage string
+
+const (
+	pageHot  clockProPage = "hot"  // Resident, short reuse distance
+	pageCold clockProPage = "cold" // Resident, on trial
+	pageTest clockProPage = "test" // Non-resident: evicted cold page still in its test period
+)
+
+// clockProEntry is one page on the CLOCK-Pro clock
+type clockProEntry struct {
+	entry
+	page       clockProPage
+	referenced bool
+}
+
+// ClockProCache implements CLOCK-Pro (Jiang, Chen & Zhang)
+// All pages sit on one clock swept by three hands. HAND_cold evicts
+// unreferenced cold pages but keeps their metadata as test pages; a test page
+// requested again proves a short reuse distance and comes back hot, growing
+// the cold target. HAND_hot demotes unreferenced hot pages, and HAND_test
+// retires test pages, shrinking the cold target when they were never reused
+type ClockProCache struct {
+	mu         sync.RWMutex
+	capacity   int
+	coldTarget int // Resident cold pages allowed (adapts between 1 and capacity)
+	index      map[string]*ring.Ring
+	handHot    *ring.Ring
+	handCold   *ring.Ring
+	handTest   *ring.Ring
+	hotCount   int
+	coldCount  int
+	testCount  int
+	evicted    []string // Pages that lost residency during the current Put
+	eventLog
+}
+
+func init() {
+	Register("clockpro", "CLOCK-Pro", func(capacity int) Policy { return NewClockProCache(capacity) })
+}
+
+// NewClockProCache creates a new CLOCK-Pro cache with given capacity
+func NewClockProCache(capacity int) *ClockProCache {
+	return &ClockProCache{
+		capacity:   capacity,
+		coldTarget: capacity,
+		index:      make(map[string]*ring.Ring),
+	}
+}
+
+// Get retrieves a resident value and sets its reference bit
+func (c *ClockProCache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r, exists := c.index[key]
+	if !exists || r.Value.(*clockProEntry).page == pageTest {
+		c.eventLog.record("GET", key, "", false, "", c.getCurrentKeys)
+		return "", false
+	}
+
+	page := r.Value.(*clockProEntry)
+	page.referenced = true
+	page.accessTime = time.Now()
+
+	c.eventLog.record("GET", key, page.value, true, "", c.getCurrentKeys)
+	return page.value, true
+}
+
+// Put adds or updates a key-value pair
+func (c *ClockProCache) Put(key, value string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.evicted = nil
+	r, exists := c.index[key]
+
+	if exists && r.Value.(*clockProEntry).page != pageTest {
+		page := r.Value.(*clockProEntry)
+		page.value = value
+		page.referenced = true
+		page.accessTime = time.Now()
+		c.eventLog.record("PUT", key, value, true, "", c.getCurrentKeys)
+		return ""
+	}
+
+	page := &clockProEntry{entry: *newEntry(key, value), page: pageCold}
+	if exists {
+		// Reused during its test period: the cold target was too small
+		if c.coldTarget < c.capacity {
+			c.coldTarget++
+		}
+		c.remove(r)
+		c.testCount--
+		page.page = pageHot
+	}
+
+	c.add(page)
+	if page.page == pageHot {
+		c.hotCount++
+	} else {
+		c.coldCount++
+	}
+
+	evictedKey := ""
+	if len(c.evicted) > 0 {
+		evictedKey = c.evicted[0]
+	}
+	c.eventLog.record("PUT", key, value, false, evictedKey, c.getCurrentKeys)
+	return evictedKey
+}
+
+// add makes room and inserts a page just behind HAND_hot (must be called with lock held)
+func (c *ClockProCache) add(page *clockProEntry) {
+	for c.hotCount+c.coldCount >= c.capacity {
+		c.runHandCold()
+	}
+
+	r := ring.New(1)
+	r.Value = page
+	c.index[page.key] = r
+
+	if c.handHot == nil {
+		c.handHot, c.handCold, c.handTest = r, r, r
+		return
+	}
+	r.Link(c.handHot)
+	if c.handCold == c.handHot {
+		c.handCold = c.handCold.Prev()
+	}
+}
+
+// remove takes a page off the clock, moving any hand that points at it
+// (must be called with lock held)
+func (c *ClockProCache) remove(r *ring.Ring) {
+	delete(c.index, r.Value.(*clockProEntry).key)
+	if r.Next() == r {
+		c.handHot, c.handCold, c.handTest = nil, nil, nil
+		return
+	}
+	if r == c.handHot {
+		c.handHot = c.handHot.Prev()
+	}
+	if r == c.handCold {
+		c.handCold = c.handCold.Prev()
+	}
+	if r == c.handTest {
+		c.handTest = c.handTest.Prev()
+	}
+	r.Prev().Unlink(1)
+}
+
+// runHandCold gives a referenced cold page hot status, or evicts an
+// unreferenced one into its test period (must be called with lock held)
+func (c *ClockProCache) runHandCold() {
+	page := c.handCold.Value.(*clockProEntry)
+	if page.page == pageCold {
+		if page.referenced {
+			page.page = pageHot
+			page.referenced = false
+			c.coldCount--
+			c.hotCount++
+		} else {
+			page.page = pageTest
+			page.value = ""
+			c.coldCount--
+			c.testCount++
+			c.evicted = append(c.evicted, page.key)
+			for c.testCount > c.capacity {
+				c.runHandTest()
+			}
+		}
+	}
+	c.handCold = c.handCold.Next()
+
+	for c.hotCount > c.capacity-c.coldTarget {
+		c.runHandHot()
+	}
+}
+
+// runHandHot clears reference bits and demotes unreferenced hot pages to
+// cold; test pages it passes have outlived their test period and are retired
+// (must be called with lock held)
+func (c *ClockProCache) runHandHot() {
+	page := c.handHot.Value.(*clockProEntry)
+	switch {
+	case page.page == pageTest:
+		c.retire(c.handHot)
+	case page.page == pageHot && page.referenced:
+		page.referenced = false
+	case page.page == pageHot:
+		page.page = pageCold
+		c.hotCount--
+		c.coldCount++
+	}
+	c.handHot = c.handHot.Next()
+}
+
+// runHandTest retires the next test page it finds (must be called with lock held)
+func (c *ClockProCache) runHandTest() {
+	for c.handTest.Value.(*clockProEntry).page != pageTest {
+		c.handTest = c.handTest.Next()
+	}
+	c.retire(c.handTest)
+	c.handTest = c.handTest.Next()
+}
+
+// retire ends a test page's test period without it being reused, so the cold
+// target shrinks (must be called with lock held)
+func (c *ClockProCache) retire(r *ring.Ring) {
+	c.remove(r)
+	c.testCount--
+	if c.coldTarget > 1 {
+		c.coldTarget--
+	}
+}
+
+// GetState returns current cache state in clock order starting at HAND_hot
+// Positions count every page on the clock, including non-resident test pages
+func (c *ClockProCache) GetState() CacheState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	items := []CacheItem{}
+	segments := map[clockProPage]*CacheSegment{
+		pageHot:  {Name: string(pageHot), Keys: []string{}, Capacity: c.capacity - c.coldTarget},
+		pageCold: {Name: string(pageCold), Keys: []string{}, Capacity: c.coldTarget},
+		pageTest: {Name: string(pageTest), Keys: []string{}, Capacity: c.capacity, Ghost: true},
+	}
+	hands := map[string]int{}
+
+	c.walk(func(position int, r *ring.Ring) {
+		page := r.Value.(*clockProEntry)
+		if r == c.handHot {
+			hands["hot"] = position
+		}
+		if r == c.handCold {
+			hands["cold"] = position
+		}
+		if r == c.handTest {
+			hands["test"] = position
+		}
+
+		segment := segments[page.page]
+		segment.Keys = append(segment.Keys, page.key)
+		if page.page == pageTest {
+			return
+		}
+		items = append(items, CacheItem{
+			Key:        page.key,
+			Value:      page.value,
+			InsertTime: page.insertTime,
+			AccessTime: page.accessTime,
+			Position:   position,
+			Segment:    string(page.page),
+			Referenced: page.referenced,
+		})
+	})
+
+	state := CacheState{
+		Algorithm: "CLOCK-Pro",
+		Capacity:  c.capacity,
+		Size:      c.hotCount + c.coldCount,
+		Items:     items,
+		History:   c.eventLog.recent(20),
+		Segments:  []CacheSegment{*segments[pageHot], *segments[pageCold], *segments[pageTest]},
+		Details: map[string]interface{}{
+			"coldTarget": c.coldTarget,
+			"hands":      hands,
+		},
+	}
+	if cold, exists := hands["cold"]; exists {
+		state.ClockHand = &cold
+	}
+	return state
+}
+
+// walk visits every page on the clock starting at HAND_hot (must be called with lock held)
+func (c *ClockProCache) walk(visit func(position int, r *ring.Ring)) {
+	if c.handHot == nil {
+		return
+	}
+	r := c.handHot
+	for position := 0; ; position++ {
+		visit(position, r)
+		r = r.Next()
+		if r == c.handHot {
+			return
+		}
+	}
+}
+
+// Reset clears the cache
+func (c *ClockProCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.coldTarget = c.capacity
+	c.index = make(map[string]*ring.Ring)
+	c.handHot, c.handCold, c.handTest = nil, nil, nil
+	c.hotCount, c.coldCount, c.testCount = 0, 0, 0
+	c.eventLog.clear()
+}
+
+// getCurrentKeys returns resident keys in clock order (must be called with lock held)
+func (c *ClockProCache) getCurrentKeys() []string {
+	keys := []string{}
+	c.walk(func(position int, r *ring.Ring) {
+		if page := r.Value.(*clockProEntry); page.page != pageTest {
+			keys = append(keys, page.key)
+		}
+	})
+	return keys
+}
@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestClockProPageTransitions(t *testing.T) {
+	c := NewClockProCache(3)
+	for _, key := range []string{"a", "b", "c"} {
+		c.Put(key, key)
+	}
+	c.Get("a")
+
+	steps := []struct {
+		put             string
+		evicted         string
+		hot, cold, test []string
+		coldTarget      int
+	}{
+		// The cold hand spares referenced a and evicts b into its test period
+		{put: "d", evicted: "b", cold: []string{"a", "c", "d"}, test: []string{"b"}, coldTarget: 3},
+		{put: "e", evicted: "c", cold: []string{"a", "d", "e"}, test: []string{"b", "c"}, coldTarget: 3},
+		// b is reused during its test period, so it comes back hot
+		{put: "b", evicted: "d", hot: []string{"b"}, cold: []string{"a", "e"}, test: []string{"c", "d"}, coldTarget: 3},
+		// Test pages retired without reuse shrink the cold target
+		{put: "f", evicted: "e", hot: []string{"b"}, cold: []string{"a", "f"}, test: []string{"d", "e"}, coldTarget: 2},
+		// The cold hand passes a with its reference bit set and promotes it
+		{put: "g", evicted: "f", hot: []string{"a", "b"}, cold: []string{"g"}, test: []string{"e", "f"}, coldTarget: 1},
+		{put: "h", evicted: "g", hot: []string{"a", "b"}, cold: []string{"h"}, test: []string{"e", "f", "g"}, coldTarget: 1},
+		// e comes back hot; the hot hand sweeping past the evicted h retires it
+		{put: "e", evicted: "h", hot: []string{"a", "b", "e"}, test: []string{"f", "g"}, coldTarget: 1},
+		// With no cold pages left, the hot hand demotes unreferenced b and it is evicted
+		{put: "k", evicted: "b", hot: []string{"a", "e"}, cold: []string{"k"}, test: []string{"b", "f", "g"}, coldTarget: 1},
+	}
+	for _, step := range steps {
+		if evicted := c.Put(step.put, step.put); evicted != step.evicted {
+			t.Errorf("Put(%s) evicted %q, want %q", step.put, evicted, step.evicted)
+		}
+		state := c.GetState()
+		for i, want := range [][]string{step.hot, step.cold, step.test} {
+			got := slices.Clone(state.Segments[i].Keys)
+			slices.Sort(got)
+			if want == nil {
+				want = []string{}
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("after Put(%s): %s pages = %v, want %v", step.put, state.Segments[i].Name, got, want)
+			}
+		}
+		if got := state.Details["coldTarget"]; got != step.coldTarget {
+			t.Errorf("after Put(%s): cold target = %v, want %d", step.put, got, step.coldTarget)
+		}
+	}
+
+	// Test pages are not resident
+	if _, hit := c.Get("g"); hit {
+		t.Error("Get hit a test page")
+	}
+}
@@ -0,0 +1,641 @@
+package cache
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// RoutingStrategy selects how a Cluster maps keys to nodes
+type RoutingStrategy string
+
+const (
+	// Consistent hash ring: each node owns the arcs before its virtual nodes'
+	// points, so adding or removing a node only moves the keys on its arcs
+	RoutingRing RoutingStrategy = "ring"
+	// Rendezvous (highest random weight): a key goes to the node scoring
+	// highest on hash(node, key); only the keys a node wins ever move
+	RoutingRendezvous RoutingStrategy = "rendezvous"
+	// Jump consistent hash (Lamping & Veach): no ring, perfectly even, but
+	// nodes are numbered buckets, so only the last node can leave cheaply
+	RoutingJump RoutingStrategy = "jump"
+	// hash(key) mod n: the naive baseline, which moves almost every key
+	RoutingModulo RoutingStrategy = "modulo"
+)
+
+// RoutingStrategies lists every routing strategy
+var RoutingStrategies = []RoutingStrategy{RoutingRing, RoutingRendezvous, RoutingJump, RoutingModulo}
+
+// hashKey hashes a string to 64 well-mixed bits
+// FNV alone clusters similar strings such as "node-1#7" and "node-1#8", so
+// its output goes through the splitmix64 finalizer
+func hashKey(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// keyRouter maps keys to nodes
+type keyRouter interface {
+	owner(key string) string
+	add(node string)
+	remove(node string)
+}
+
+// newKeyRouter creates an empty router for a strategy
+func newKeyRouter(strategy RoutingStrategy, vnodes int) (keyRouter, error) {
+	switch strategy {
+	case RoutingRing:
+		return &hashRing{vnodes: vnodes, owners: make(map[uint64]string)}, nil
+	case RoutingRendezvous:
+		return &rendezvousRouter{}, nil
+	case RoutingJump:
+		return &jumpRouter{}, nil
+	case RoutingModulo:
+		return &moduloRouter{}, nil
+	}
+	return nil, fmt.Errorf("unknown routing strategy %q", strategy)
+}
+
+// hashRing is a consistent hash ring with virtual nodes
+type hashRing struct {
+	vnodes int
+	points []uint64          // Sorted virtual node positions
+	owners map[uint64]string // position -> node
+}
+
+func (r *hashRing) owner(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	h := hashKey(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0 // Wrap around the ring
+	}
+	return r.owners[r.points[i]]
+}
+
+func (r *hashRing) add(node string) {
+	for i := 0; i < r.vnodes; i++ {
+		point := hashKey(node + "#" + strconv.Itoa(i))
+		if _, taken := r.owners[point]; taken {
+			continue
+		}
+		r.owners[point] = node
+		r.points = append(r.points, point)
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+}
+
+func (r *hashRing) remove(node string) {
+	points := r.points[:0]
+	for _, point := range r.points {
+		if r.owners[point] == node {
+			delete(r.owners, point)
+			continue
+		}
+		points = append(points, point)
+	}
+	r.points = points
+}
+
+// rendezvousRouter implements highest random weight hashing
+type rendezvousRouter struct {
+	nodes []string
+}
+
+func (r *rendezvousRouter) owner(key string) string {
+	best, bestScore := "", uint64(0)
+	for _, node := range r.nodes {
+		if score := hashKey(node + "|" + key); best == "" || score > bestScore {
+			best, bestScore = node, score
+		}
+	}
+	return best
+}
+
+func (r *rendezvousRouter) add(node string)    { r.nodes = append(r.nodes, node) }
+func (r *rendezvousRouter) remove(node string) { r.nodes = removeNode(r.nodes, node) }
+
+// jumpRouter implements jump consistent hashing over numbered buckets
+type jumpRouter struct {
+	nodes []string // Bucket i is nodes[i]
+}
+
+// jumpHash returns the bucket in [0, buckets) of a key (Lamping & Veach, 2014)
+func jumpHash(key uint64, buckets int) int {
+	b, j := int64(-1), int64(0)
+	for j < int64(buckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
+
+func (r *jumpRouter) owner(key string) string {
+	if len(r.nodes) == 0 {
+		return ""
+	}
+	return r.nodes[jumpHash(hashKey(key), len(r.nodes))]
+}
+
+func (r *jumpRouter) add(node string) { r.nodes = append(r.nodes, node) }
+
+// remove renumbers every bucket after the removed node, which is why
+// removing anything but the last node moves many keys
+func (r *jumpRouter) remove(node string) { r.nodes = removeNode(r.nodes, node) }
+
+// moduloRouter assigns hash(key) mod n
+type moduloRouter struct {
+	nodes []string
+}
+
+func (r *moduloRouter) owner(key string) string {
+	if len(r.nodes) == 0 {
+		return ""
+	}
+	return r.nodes[hashKey(key)%uint64(len(r.nodes))]
+}
+
+func (r *moduloRouter) add(node string)    { r.nodes = append(r.nodes, node) }
+func (r *moduloRouter) remove(node string) { r.nodes = removeNode(r.nodes, node) }
+
+// removeNode returns nodes without node, keeping the order of the rest
+func removeNode(nodes []string, node string) []string {
+	kept := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		if n != node {
+			kept = append(kept, n)
+		}
+	}
+	return kept
+}
+
+// ClusterConfig configures a Cluster
+type ClusterConfig struct {
+	Strategy     RoutingStrategy `json:"strategy"`
+	Nodes        int             `json:"nodes"`        // Initial nodes, named node-1..node-N
+	VNodes       int             `json:"vnodes"`       // ring: virtual nodes per node
+	NodeCapacity int             `json:"nodeCapacity"` // Keys each node's LRU holds
+}
+
+// DefaultClusterConfig returns 4 nodes of 100 keys on a ring with 100 virtual nodes each
+func DefaultClusterConfig() ClusterConfig {
+	return ClusterConfig{
+		Strategy:     RoutingRing,
+		Nodes:        4,
+		VNodes:       100,
+		NodeCapacity: 100,
+	}
+}
+
+// MaxClusterNodes bounds the nodes of a cluster
+const MaxClusterNodes = 64
+
+// Validate checks the config
+func (c ClusterConfig) Validate() error {
+	if c.Nodes <= 0 || c.Nodes > MaxClusterNodes {
+		return fmt.Errorf("nodes must be between 1 and %d", MaxClusterNodes)
+	}
+	if c.Strategy == RoutingRing && (c.VNodes <= 0 || c.VNodes > 1000) {
+		return fmt.Errorf("vnodes must be between 1 and 1000")
+	}
+	if c.NodeCapacity <= 0 {
+		return fmt.Errorf("nodeCapacity must be positive")
+	}
+	return nil
+}
+
+// KeyMove is a key whose owner changed in a rebalance
+type KeyMove struct {
+	Key  string `json:"key"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Rebalance reports the keys moved by adding or removing a node
+type Rebalance struct {
+	Action        string    `json:"action"` // "add" or "remove"
+	Node          string    `json:"node"`
+	Keys          int       `json:"keys"` // Keys cached before the change
+	MovedCount    int       `json:"movedCount"`
+	LostCount     int       `json:"lostCount"` // Keys evicted by migrated keys arriving at a full node
+	MovedFraction float64   `json:"movedFraction"`
+	IdealFraction float64   `json:"idealFraction"` // The minimum: the keys the new node takes, or the removed node held
+	Moves         []KeyMove `json:"moves"`         // First 100 moves
+}
+
+// clusterNode is one cache server
+type clusterNode struct {
+	name     string
+	cache    *LRUCache
+	requests int
+	hits     int
+	misses   int
+}
+
+// NodeState is the visualization state of one node
+type NodeState struct {
+	Name     string     `json:"name"`
+	Keys     int        `json:"keys"`
+	Requests int        `json:"requests"`
+	Hits     int        `json:"hits"`
+	Misses   int        `json:"misses"`
+	Share    float64    `json:"share"` // Fraction of the key space routed here
+	Cache    CacheState `json:"cache"`
+}
+
+// RingPoint is a virtual node's position on the ring, as a fraction of the hash space
+type RingPoint struct {
+	Position float64 `json:"position"`
+	Node     string  `json:"node"`
+}
+
+// ClusterState is the visualization state of a cluster
+type ClusterState struct {
+	Config        ClusterConfig `json:"config"`
+	Nodes         []NodeState   `json:"nodes"`
+	Keys          int           `json:"keys"`
+	Imbalance     float64       `json:"imbalance"` // Most keys on a node / mean keys per node
+	Ring          []RingPoint   `json:"ring,omitempty"`
+	LastRebalance *Rebalance    `json:"lastRebalance,omitempty"`
+}
+
+// shareSamples is how many synthetic keys estimate each node's share of the key space
+const shareSamples = 10000
+
+// Cluster is a distributed cache: keys are routed to nodes by a routing
+// strategy and each node is an LRUCache
+type Cluster struct {
+	mu            sync.RWMutex
+	config        ClusterConfig
+	router        keyRouter
+	nodes         map[string]*clusterNode
+	order         []string // Node names in the order they joined
+	nextID        int      // Suffix of the next default node name
+	lastRebalance *Rebalance
+}
+
+// NewCluster creates a cluster with config.Nodes empty nodes
+func NewCluster(config ClusterConfig) (*Cluster, error) {
+	c := &Cluster{}
+	if err := c.Configure(config); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Configure replaces the cluster with empty nodes
+func (c *Cluster) Configure(config ClusterConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	router, err := newKeyRouter(config.Strategy, config.VNodes)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.config = config
+	c.router = router
+	c.nodes = make(map[string]*clusterNode)
+	c.order = []string{}
+	c.nextID = 1
+	c.lastRebalance = nil
+	for i := 0; i < config.Nodes; i++ {
+		c.addNode(c.defaultName())
+	}
+	return nil
+}
+
+// Config returns the current configuration
+func (c *Cluster) Config() ClusterConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config
+}
+
+// defaultName returns the next unused node-N name (must be called with lock held)
+func (c *Cluster) defaultName() string {
+	for {
+		name := "node-" + strconv.Itoa(c.nextID)
+		c.nextID++
+		if _, taken := c.nodes[name]; !taken {
+			return name
+		}
+	}
+}
+
+// addNode joins an empty node (must be called with lock held)
+func (c *Cluster) addNode(name string) {
+	cache := NewLRUCache(c.config.NodeCapacity)
+	cache.mute()
+	c.nodes[name] = &clusterNode{name: name, cache: cache}
+	c.order = append(c.order, name)
+	c.router.add(name)
+}
+
+// Get reads a key from the node that owns it
+func (c *Cluster) Get(key string) (string, bool, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	node := c.nodes[c.router.owner(key)]
+	node.requests++
+	value, hit := node.cache.Get(key)
+	if hit {
+		node.hits++
+	} else {
+		node.misses++
+	}
+	return value, hit, node.name
+}
+
+// Put writes a key to the node that owns it, returning the node and any key it evicted
+func (c *Cluster) Put(key, value string) (string, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	node := c.nodes[c.router.owner(key)]
+	node.requests++
+	return node.name, node.cache.Put(key, value)
+}
+
+// Populate puts count synthetic keys (key-1..key-N), so rebalances have something to move
+func (c *Cluster) Populate(count int) {
+	for i := 1; i <= count; i++ {
+		key := "key-" + strconv.Itoa(i)
+		c.Put(key, "v"+strconv.Itoa(i))
+	}
+}
+
+// AddNode joins a node (named node-N if name is empty) and migrates the keys it now owns
+func (c *Cluster) AddNode(name string) (Rebalance, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if name == "" {
+		name = c.defaultName()
+	}
+	if _, exists := c.nodes[name]; exists {
+		return Rebalance{}, fmt.Errorf("node %q already exists", name)
+	}
+	if len(c.nodes) >= MaxClusterNodes {
+		return Rebalance{}, fmt.Errorf("a cluster has at most %d nodes", MaxClusterNodes)
+	}
+
+	c.addNode(name)
+	rebalance := c.rebalance("add", name)
+	rebalance.IdealFraction = 1 / float64(len(c.nodes))
+	c.lastRebalance = &rebalance
+	return rebalance, nil
+}
+
+// RemoveNode takes a node out and migrates its keys to their new owners
+func (c *Cluster) RemoveNode(name string) (Rebalance, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.nodes[name]; !exists {
+		return Rebalance{}, fmt.Errorf("unknown node %q", name)
+	}
+	if len(c.nodes) == 1 {
+		return Rebalance{}, fmt.Errorf("cannot remove the last node")
+	}
+
+	held := c.nodes[name].cache.Keys()
+	total := c.keyCount()
+	c.router.remove(name)
+	rebalance := c.rebalance("remove", name)
+	if total > 0 {
+		rebalance.IdealFraction = float64(len(held)) / float64(total)
+	}
+
+	delete(c.nodes, name)
+	c.order = removeNode(c.order, name)
+	c.lastRebalance = &rebalance
+	return rebalance, nil
+}
+
+// rebalance moves every cached key whose owner changed after the router did
+// (must be called with lock held)
+func (c *Cluster) rebalance(action, node string) Rebalance {
+	rebalance := Rebalance{Action: action, Node: node, Moves: []KeyMove{}}
+
+	// Snapshot first so keys migrated onto a later node are not visited twice
+	held := make(map[string][]string, len(c.order))
+	for _, name := range c.order {
+		held[name] = c.nodes[name].cache.Keys()
+	}
+
+	// Keys that arrived on a new owner, so evicting one later undoes its move
+	arrived := make(map[string]bool)
+	for _, name := range c.order {
+		from := c.nodes[name]
+		// Oldest first, so migrated keys keep their relative recency
+		keys := held[name]
+		for i := len(keys) - 1; i >= 0; i-- {
+			key := keys[i]
+			rebalance.Keys++
+			to := c.router.owner(key)
+			if to == name {
+				continue
+			}
+			value, ok := from.cache.Remove(key)
+			if !ok {
+				// Already evicted by a key migrated onto this node
+				continue
+			}
+			if evicted := c.nodes[to].cache.Put(key, value); evicted != "" {
+				rebalance.LostCount++
+				if arrived[evicted] {
+					delete(arrived, evicted)
+					rebalance.MovedCount--
+				}
+			}
+			arrived[key] = true
+			rebalance.MovedCount++
+			if len(rebalance.Moves) < 100 {
+				rebalance.Moves = append(rebalance.Moves, KeyMove{Key: key, From: name, To: to})
+			}
+		}
+	}
+	if rebalance.Keys > 0 {
+		rebalance.MovedFraction = float64(rebalance.MovedCount) / float64(rebalance.Keys)
+	}
+	return rebalance
+}
+
+// keyCount returns the keys cached across the cluster (must be called with lock held)
+func (c *Cluster) keyCount() int {
+	total := 0
+	for _, node := range c.nodes {
+		total += len(node.cache.Keys())
+	}
+	return total
+}
+
+// GetState returns every node with its load and, for a ring, the virtual nodes
+func (c *Cluster) GetState() ClusterState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	shares := make(map[string]int)
+	for i := 0; i < shareSamples; i++ {
+		shares[c.router.owner("sample-"+strconv.Itoa(i))]++
+	}
+
+	state := ClusterState{
+		Config:        c.config,
+		Nodes:         make([]NodeState, 0, len(c.order)),
+		LastRebalance: c.lastRebalance,
+	}
+	state.Config.Nodes = len(c.order)
+
+	most := 0
+	for _, name := range c.order {
+		node := c.nodes[name]
+		cacheState := node.cache.GetState()
+		state.Nodes = append(state.Nodes, NodeState{
+			Name:     name,
+			Keys:     cacheState.Size,
+			Requests: node.requests,
+			Hits:     node.hits,
+			Misses:   node.misses,
+			Share:    float64(shares[name]) / shareSamples,
+			Cache:    cacheState,
+		})
+		state.Keys += cacheState.Size
+		most = max(most, cacheState.Size)
+	}
+	if state.Keys > 0 {
+		state.Imbalance = float64(most) / (float64(state.Keys) / float64(len(c.order)))
+	}
+
+	if ring, ok := c.router.(*hashRing); ok && len(ring.points) <= 2000 {
+		state.Ring = make([]RingPoint, 0, len(ring.points))
+		for _, point := range ring.points {
+			state.Ring = append(state.Ring, RingPoint{
+				Position: float64(point) / math.MaxUint64,
+				Node:     ring.owners[point],
+			})
+		}
+	}
+	return state
+}
+
+// Reset empties every node, keeping the current membership
+func (c *Cluster) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, node := range c.nodes {
+		node.cache.Reset()
+		node.requests, node.hits, node.misses = 0, 0, 0
+	}
+	c.lastRebalance = nil
+}
+
+// RoutingComparison is how one strategy distributes and moves keys
+type RoutingComparison struct {
+	Strategy       RoutingStrategy `json:"strategy"`
+	Imbalance      float64         `json:"imbalance"`      // Most keys on a node / mean
+	AddMoved       float64         `json:"addMoved"`       // Fraction of keys moved by adding a node
+	AddIdeal       float64         `json:"addIdeal"`       // 1 / (n + 1)
+	RemoveMoved    float64         `json:"removeMoved"`    // Fraction moved by removing the first node
+	RemoveIdeal    float64         `json:"removeIdeal"`    // Fraction the removed node held
+	RemoveLastMove float64         `json:"removeLastMove"` // Fraction moved by removing the newest node
+}
+
+// CompareRouting routes keys synthetic keys over nodes nodes with every
+// strategy and measures balance and key movement
+func CompareRouting(nodes, vnodes, keys int) ([]RoutingComparison, error) {
+	if nodes < 2 || nodes > MaxClusterNodes {
+		return nil, fmt.Errorf("nodes must be between 2 and %d", MaxClusterNodes)
+	}
+	if vnodes <= 0 || vnodes > 1000 {
+		return nil, fmt.Errorf("vnodes must be between 1 and 1000")
+	}
+	if keys <= 0 || keys > MaxTraceLength {
+		return nil, fmt.Errorf("keys must be between 1 and %d", MaxTraceLength)
+	}
+
+	names := make([]string, nodes)
+	for i := range names {
+		names[i] = "node-" + strconv.Itoa(i+1)
+	}
+	sample := make([]string, keys)
+	for i := range sample {
+		sample[i] = "key-" + strconv.Itoa(i+1)
+	}
+	assign := func(router keyRouter) []string {
+		owners := make([]string, keys)
+		for i, key := range sample {
+			owners[i] = router.owner(key)
+		}
+		return owners
+	}
+	moved := func(before, after []string) float64 {
+		count := 0
+		for i := range before {
+			if before[i] != after[i] {
+				count++
+			}
+		}
+		return float64(count) / float64(keys)
+	}
+
+	results := make([]RoutingComparison, 0, len(RoutingStrategies))
+	for _, strategy := range RoutingStrategies {
+		router, err := newKeyRouter(strategy, vnodes)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			router.add(name)
+		}
+		before := assign(router)
+
+		counts := make(map[string]int)
+		for _, owner := range before {
+			counts[owner]++
+		}
+		most := 0
+		for _, count := range counts {
+			most = max(most, count)
+		}
+		result := RoutingComparison{
+			Strategy:  strategy,
+			Imbalance: float64(most) / (float64(keys) / float64(nodes)),
+			AddIdeal:  1 / float64(nodes+1),
+		}
+
+		// Add a node, then remove it again (the cheap case for jump hashing)
+		router.add("node-new")
+		added := assign(router)
+		result.AddMoved = moved(before, added)
+		router.remove("node-new")
+		result.RemoveLastMove = moved(added, assign(router))
+
+		// Remove the first node (renumbers every jump bucket)
+		router.remove(names[0])
+		result.RemoveMoved = moved(before, assign(router))
+		result.RemoveIdeal = float64(counts[names[0]]) / float64(keys)
+
+		results = append(results, result)
+	}
+	return results, nil
+}
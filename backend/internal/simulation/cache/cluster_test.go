@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+)
+
+// checkPlacement fails if a cached key is off its owner or lost its value
+func checkPlacement(t *testing.T, c *Cluster) int {
+	t.Helper()
+	total := 0
+	for _, name := range c.order {
+		for _, item := range c.nodes[name].cache.GetState().Items {
+			total++
+			if owner := c.router.owner(item.Key); owner != name {
+				t.Errorf("%s is on %s, owned by %s", item.Key, name, owner)
+			}
+			if item.Value != "v"+strings.TrimPrefix(item.Key, "key-") {
+				t.Errorf("%s has value %q", item.Key, item.Value)
+			}
+		}
+	}
+	return total
+}
+
+func TestClusterRebalance(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		populate int
+		change   func(*Cluster) (Rebalance, error)
+	}{
+		{name: "remove into full nodes", capacity: 5, populate: 200, change: func(c *Cluster) (Rebalance, error) { return c.RemoveNode("node-1") }},
+		{name: "remove with room", capacity: 100, populate: 60, change: func(c *Cluster) (Rebalance, error) { return c.RemoveNode("node-2") }},
+		{name: "add", capacity: 5, populate: 200, change: func(c *Cluster) (Rebalance, error) { return c.AddNode("") }},
+	}
+	for _, tt := range tests {
+		for _, strategy := range RoutingStrategies {
+			t.Run(tt.name+"/"+string(strategy), func(t *testing.T) {
+				c, err := NewCluster(ClusterConfig{Strategy: strategy, Nodes: 3, VNodes: 50, NodeCapacity: tt.capacity})
+				if err != nil {
+					t.Fatal(err)
+				}
+				c.Populate(tt.populate)
+				before := checkPlacement(t, c)
+
+				rebalance, err := tt.change(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+				after := checkPlacement(t, c)
+
+				if rebalance.Keys != before {
+					t.Errorf("rebalance saw %d keys, cluster held %d", rebalance.Keys, before)
+				}
+				if after != before-rebalance.LostCount {
+					t.Errorf("%d keys after, want %d held minus %d lost", after, before, rebalance.LostCount)
+				}
+				if rebalance.MovedCount < 0 || rebalance.MovedCount > after {
+					t.Errorf("moved %d keys, %d are cached", rebalance.MovedCount, after)
+				}
+				if tt.capacity*3 >= tt.populate && rebalance.LostCount != 0 {
+					t.Errorf("lost %d keys with room to spare", rebalance.LostCount)
+				}
+			})
+		}
+	}
+}
@@ -12,7 +12,7 @@ type FIFOCache struct {
 	capacity int
 	cache    map[string]*fifoItem // key -> item
 	queue    []string             // insertion order queue
-	history  []AccessEvent
+	eventLog
 }
 
 // fifoItem represents an item in the FIFO cache
@@ -23,13 +23,16 @@ type fifoItem struct {
 	accessTime time.Time
 }
 
+func init() {
+	Register("fifo", "FIFO (First In First Out)", func(capacity int) Policy { return NewFIFOCache(capacity) })
+}
+
 // NewFIFOCache creates a new FIFO cache with given capacity
 func NewFIFOCache(capacity int) *FIFOCache {
 	return &FIFOCache{
 		capacity: capacity,
 		cache:    make(map[string]*fifoItem),
 		queue:    make([]string, 0, capacity),
-		history:  []AccessEvent{},
 	}
 }
 
@@ -40,12 +43,12 @@ func (c *FIFOCache) Get(key string) (string, bool) {
 
 	item, exists := c.cache[key]
 	if !exists {
-		c.recordEvent("GET", key, "", false, "")
+		c.eventLog.record("GET", key, "", false, "", c.getCurrentKeys)
 		return "", false
 	}
 
 	item.accessTime = time.Now()
-	c.recordEvent("GET", key, item.value, true, "")
+	c.eventLog.record("GET", key, item.value, true, "", c.getCurrentKeys)
 	return item.value, true
 }
 
@@ -59,7 +62,7 @@ func (c *FIFOCache) Put(key, value string) string {
 	if item, exists := c.cache[key]; exists {
 		item.value = value
 		item.accessTime = time.Now()
-		c.recordEvent("PUT", key, value, true, "")
+		c.eventLog.record("PUT", key, value, true, "", c.getCurrentKeys)
 		return ""
 	}
 
@@ -83,7 +86,7 @@ func (c *FIFOCache) Put(key, value string) string {
 	c.cache[key] = item
 	c.queue = append(c.queue, key)
 
-	c.recordEvent("PUT", key, value, false, evictedKey)
+	c.eventLog.record("PUT", key, value, false, evictedKey, c.getCurrentKeys)
 	return evictedKey
 }
 
@@ -109,7 +112,7 @@ func (c *FIFOCache) GetState() CacheState {
 		Capacity:  c.capacity,
 		Size:      len(c.cache),
 		Items:     items,
-		History:   c.getRecentHistory(20),
+		History:   c.eventLog.recent(20),
 	}
 }
 
@@ -120,22 +123,7 @@ func (c *FIFOCache) Reset() {
 
 	c.cache = make(map[string]*fifoItem)
 	c.queue = make([]string, 0, c.capacity)
-	c.history = []AccessEvent{}
-}
-
-// recordEvent adds an event to history (must be called with lock held)
-func (c *FIFOCache) recordEvent(operation, key, value string, hit bool, evictedKey string) {
-	event := AccessEvent{
-		Operation:   operation,
-		Key:         key,
-		Value:       value,
-		Hit:         hit,
-		EvictedKey:  evictedKey,
-		Timestamp:   time.Now(),
-		CacheSize:   len(c.cache),
-		CacheItems:  c.getCurrentKeys(),
-	}
-	c.history = append(c.history, event)
+	c.eventLog.clear()
 }
 
 // getCurrentKeys returns current cache keys in FIFO order (must be called with lock held)
@@ -144,13 +132,3 @@ func (c *FIFOCache) getCurrentKeys() []string {
 	copy(keys, c.queue)
 	return keys
 }
-
-// getRecentHistory returns last N events (must be called with lock held)
-func (c *FIFOCache) getRecentHistory(n int) []AccessEvent {
-	start := len(c.history) - n
-	if start < 0 {
-		start = 0
-	}
-	return c.history[start:]
-}
-
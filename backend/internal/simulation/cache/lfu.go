@@ -9,12 +9,12 @@ import (
 // LFUCache implements Least Frequently Used cache eviction policy
 // When cache is full, evicts the item with lowest access frequency
 type LFUCache struct {
-	mu        sync.RWMutex
-	capacity  int
-	cache     map[string]*lfuItem // key -> item
-	freqMap   map[int]map[string]bool // frequency -> set of keys
-	minFreq   int
-	history   []AccessEvent
+	mu       sync.RWMutex
+	capacity int
+	cache    map[string]*lfuItem     // key -> item
+	freqMap  map[int]map[string]bool // frequency -> set of keys
+	minFreq  int
+	eventLog
 }
 
 // lfuItem represents an item in the LFU cache
@@ -25,6 +25,10 @@ type lfuItem struct {
 	accessTime time.Time
 }
 
+func init() {
+	Register("lfu", "LFU (Least Frequently Used)", func(capacity int) Policy { return NewLFUCache(capacity) })
+}
+
 // NewLFUCache creates a new LFU cache with given capacity
 func NewLFUCache(capacity int) *LFUCache {
 	return &LFUCache{
@@ -32,7 +36,6 @@ func NewLFUCache(capacity int) *LFUCache {
 		cache:    make(map[string]*lfuItem),
 		freqMap:  make(map[int]map[string]bool),
 		minFreq:  0,
-		history:  []AccessEvent{},
 	}
 }
 
@@ -43,7 +46,7 @@ func (c *LFUCache) Get(key string) (string, bool) {
 
 	item, exists := c.cache[key]
 	if !exists {
-		c.recordEvent("GET", key, "", false, "")
+		c.eventLog.record("GET", key, "", false, "", c.getCurrentKeys)
 		return "", false
 	}
 
@@ -51,7 +54,7 @@ func (c *LFUCache) Get(key string) (string, bool) {
 	c.incrementFrequency(item)
 	item.accessTime = time.Now()
 
-	c.recordEvent("GET", key, item.value, true, "")
+	c.eventLog.record("GET", key, item.value, true, "", c.getCurrentKeys)
 	return item.value, true
 }
 
@@ -66,7 +69,7 @@ func (c *LFUCache) Put(key, value string) string {
 		item.value = value
 		c.incrementFrequency(item)
 		item.accessTime = time.Now()
-		c.recordEvent("PUT", key, value, true, "")
+		c.eventLog.record("PUT", key, value, true, "", c.getCurrentKeys)
 		return ""
 	}
 
@@ -93,7 +96,7 @@ func (c *LFUCache) Put(key, value string) string {
 	c.freqMap[1][key] = true
 	c.minFreq = 1
 
-	c.recordEvent("PUT", key, value, false, evictedKey)
+	c.eventLog.record("PUT", key, value, false, evictedKey, c.getCurrentKeys)
 	return evictedKey
 }
 
@@ -174,7 +177,7 @@ func (c *LFUCache) GetState() CacheState {
 		Capacity:  c.capacity,
 		Size:      len(c.cache),
 		Items:     items,
-		History:   c.getRecentHistory(20),
+		History:   c.eventLog.recent(20),
 	}
 }
 
@@ -186,22 +189,7 @@ func (c *LFUCache) Reset() {
 	c.cache = make(map[string]*lfuItem)
 	c.freqMap = make(map[int]map[string]bool)
 	c.minFreq = 0
-	c.history = []AccessEvent{}
-}
-
-// recordEvent adds an event to history (must be called with lock held)
-func (c *LFUCache) recordEvent(operation, key, value string, hit bool, evictedKey string) {
-	event := AccessEvent{
-		Operation:   operation,
-		Key:         key,
-		Value:       value,
-		Hit:         hit,
-		EvictedKey:  evictedKey,
-		Timestamp:   time.Now(),
-		CacheSize:   len(c.cache),
-		CacheItems:  c.getCurrentKeys(),
-	}
-	c.history = append(c.history, event)
+	c.eventLog.clear()
 }
 
 // getCurrentKeys returns current cache keys (must be called with lock held)
@@ -212,13 +200,3 @@ func (c *LFUCache) getCurrentKeys() []string {
 	}
 	return keys
 }
-
-// getRecentHistory returns last N events (must be called with lock held)
-func (c *LFUCache) getRecentHistory(n int) []AccessEvent {
-	start := len(c.history) - n
-	if start < 0 {
-		start = 0
-	}
-	return c.history[start:]
-}
-
@@ -13,7 +13,7 @@ type LRUCache struct {
 	capacity int
 	cache    map[string]*list.Element // key -> list element
 	lruList  *list.List               // doubly linked list for LRU ordering
-	history  []AccessEvent            // History of all operations
+	eventLog                          // History of all operations
 }
 
 // cacheItem represents an item in the LRU cache
@@ -23,13 +23,16 @@ type cacheItem struct {
 	accessTime time.Time
 }
 
+func init() {
+	Register("lru", "LRU (Least Recently Used)", func(capacity int) Policy { return NewLRUCache(capacity) })
+}
+
 // NewLRUCache creates a new LRU cache with given capacity
 func NewLRUCache(capacity int) *LRUCache {
 	return &LRUCache{
 		capacity: capacity,
 		cache:    make(map[string]*list.Element),
 		lruList:  list.New(),
-		history:  []AccessEvent{},
 	}
 }
 
@@ -41,7 +44,7 @@ func (c *LRUCache) Get(key string) (string, bool) {
 
 	element, exists := c.cache[key]
 	if !exists {
-		c.recordEvent("GET", key, "", false, "")
+		c.eventLog.record("GET", key, "", false, "", c.getCurrentKeys)
 		return "", false
 	}
 
@@ -50,7 +53,7 @@ func (c *LRUCache) Get(key string) (string, bool) {
 	item := element.Value.(*cacheItem)
 	item.accessTime = time.Now()
 
-	c.recordEvent("GET", key, item.value, true, "")
+	c.eventLog.record("GET", key, item.value, true, "", c.getCurrentKeys)
 	return item.value, true
 }
 
@@ -67,7 +70,7 @@ func (c *LRUCache) Put(key, value string) string {
 		item := element.Value.(*cacheItem)
 		item.value = value
 		item.accessTime = time.Now()
-		c.recordEvent("PUT", key, value, true, "")
+		c.eventLog.record("PUT", key, value, true, "", c.getCurrentKeys)
 		return ""
 	}
 
@@ -93,10 +96,34 @@ func (c *LRUCache) Put(key, value string) string {
 	element := c.lruList.PushFront(item)
 	c.cache[key] = element
 
-	c.recordEvent("PUT", key, value, false, evictedKey)
+	c.eventLog.record("PUT", key, value, false, evictedKey, c.getCurrentKeys)
 	return evictedKey
 }
 
+// Remove deletes a key, returning its value (used to migrate keys between cluster nodes)
+func (c *LRUCache) Remove(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, exists := c.cache[key]
+	if !exists {
+		return "", false
+	}
+	item := element.Value.(*cacheItem)
+	delete(c.cache, key)
+	c.lruList.Remove(element)
+
+	c.eventLog.record("REMOVE", key, item.value, true, "", c.getCurrentKeys)
+	return item.value, true
+}
+
+// Keys returns the cached keys, most recently used first
+func (c *LRUCache) Keys() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.getCurrentKeys()
+}
+
 // GetState returns current cache state for visualization
 func (c *LRUCache) GetState() CacheState {
 	c.mu.RLock()
@@ -121,7 +148,7 @@ func (c *LRUCache) GetState() CacheState {
 		Capacity:  c.capacity,
 		Size:      len(c.cache),
 		Items:     items,
-		History:   c.getRecentHistory(20),
+		History:   c.eventLog.recent(20),
 	}
 }
 
@@ -132,22 +159,7 @@ func (c *LRUCache) Reset() {
 
 	c.cache = make(map[string]*list.Element)
 	c.lruList = list.New()
-	c.history = []AccessEvent{}
-}
-
-// recordEvent adds an event to history (must be called with lock held)
-func (c *LRUCache) recordEvent(operation, key, value string, hit bool, evictedKey string) {
-	event := AccessEvent{
-		Operation:   operation,
-		Key:         key,
-		Value:       value,
-		Hit:         hit,
-		EvictedKey:  evictedKey,
-		Timestamp:   time.Now(),
-		CacheSize:   len(c.cache),
-		CacheItems:  c.getCurrentKeys(),
-	}
-	c.history = append(c.history, event)
+	c.eventLog.clear()
 }
 
 // getCurrentKeys returns current cache keys (must be called with lock held)
@@ -159,13 +171,3 @@ func (c *LRUCache) getCurrentKeys() []string {
 	}
 	return keys
 }
-
-// getRecentHistory returns last N events (must be called with lock held)
-func (c *LRUCache) getRecentHistory(n int) []AccessEvent {
-	start := len(c.history) - n
-	if start < 0 {
-		start = 0
-	}
-	return c.history[start:]
-}
-
@@ -0,0 +1,210 @@
+package cache
+
+import (
+	"fmt"
+	"sort"
+)
+
+// MaxCurveSizes bounds how many cache sizes one miss ratio curve samples
+const MaxCurveSizes = 100
+
+// MaxCurveAccesses bounds the accesses simulated for one report (sizes x policies x trace)
+const MaxCurveAccesses = 20000000
+
+// DefaultCurveSizes is how many sizes a curve samples unless asked otherwise
+const DefaultCurveSizes = 20
+
+// DefaultTargetHitRatios are the hit ratios a curve report finds the LRU size for
+var DefaultTargetHitRatios = []float64{0.5, 0.8, 0.9, 0.95, 0.99}
+
+// MRCPoint is the hit and miss ratio of one cache size
+type MRCPoint struct {
+	Size      int     `json:"size"`
+	HitRatio  float64 `json:"hitRatio"`
+	MissRatio float64 `json:"missRatio"`
+}
+
+// MissRatioCurve is one policy's miss ratio as a function of cache size
+type MissRatioCurve struct {
+	Key    string     `json:"key"`
+	Name   string     `json:"name"`
+	Exact  bool       `json:"exact"` // From stack distances rather than simulation
+	Points []MRCPoint `json:"points"`
+}
+
+// TargetSize is the smallest LRU cache reaching a hit ratio
+type TargetSize struct {
+	HitRatio float64 `json:"hitRatio"`
+	Size     int     `json:"size"` // 0 = not reachable, even with every key cached
+}
+
+// MRCReport holds the miss ratio curves of a trace
+type MRCReport struct {
+	Accesses    int              `json:"accesses"`
+	UniqueKeys  int              `json:"uniqueKeys"`
+	ColdMisses  int              `json:"coldMisses"`  // First accesses, which miss at any size
+	MaxHitRatio float64          `json:"maxHitRatio"` // Hit ratio of a cache holding every key
+	Sizes       []int            `json:"sizes"`
+	Curves      []MissRatioCurve `json:"curves"`
+	LRUTargets  []TargetSize     `json:"lruTargets"`
+}
+
+// fenwick is a binary indexed tree of counts over trace positions
+type fenwick []int
+
+// add adds delta at position i
+func (f fenwick) add(i, delta int) {
+	for i++; i < len(f); i += i & -i {
+		f[i] += delta
+	}
+}
+
+// sum returns the total of positions 0..i-1
+func (f fenwick) sum(i int) int {
+	total := 0
+	for ; i > 0; i -= i & -i {
+		total += f[i]
+	}
+	return total
+}
+
+// StackDistances returns the LRU stack distance of every access: the number
+// of distinct keys touched since the previous access to the same key,
+// counting the key itself, or 0 for a first access. An LRU cache of size C
+// hits exactly the accesses with 0 < distance <= C (Mattson et al.), so one
+// pass gives the hit ratio of every size at once. Each key's most recent
+// access is marked in a Fenwick tree, making the pass O(n log n)
+func StackDistances(trace []string) []int {
+	distances := make([]int, len(trace))
+	lastAccess := make(map[string]int)
+	marks := make(fenwick, len(trace)+1)
+
+	for i, key := range trace {
+		if last, seen := lastAccess[key]; seen {
+			// Distinct keys whose latest access falls after this key's latest access
+			distances[i] = marks.sum(i) - marks.sum(last+1) + 1
+			marks.add(last, -1)
+		}
+		marks.add(i, 1)
+		lastAccess[key] = i
+	}
+	return distances
+}
+
+// curveSizes spreads count sizes evenly up to maxSize
+func curveSizes(maxSize, count int) []int {
+	count = min(count, maxSize)
+	sizes := make([]int, 0, count)
+	for i := 1; i <= count; i++ {
+		size := (maxSize*i + count - 1) / count
+		if len(sizes) == 0 || size > sizes[len(sizes)-1] {
+			sizes = append(sizes, size)
+		}
+	}
+	return sizes
+}
+
+// newMRCPoint builds a curve point from hits at a size
+func newMRCPoint(size, hits, accesses int) MRCPoint {
+	hitRatio := float64(hits) / float64(accesses)
+	return MRCPoint{Size: size, HitRatio: hitRatio, MissRatio: 1 - hitRatio}
+}
+
+// ComputeMRC computes miss ratio curves for the trace. LRU's comes from stack
+// distances in a single pass; every other policy, and OPT, is simulated once
+// per size. sizes defaults to DefaultCurveSizes sizes up to the number of
+// unique keys, past which no policy improves
+func ComputeMRC(trace []string, sizes []int, policies []string) (MRCReport, error) {
+	if err := validateTrace(trace, 1); err != nil {
+		return MRCReport{}, err
+	}
+
+	distances := StackDistances(trace)
+	// hitsAt[d] = accesses with stack distance d; cold misses land in hitsAt[0]
+	hitsAt := []int{0}
+	for _, d := range distances {
+		for len(hitsAt) <= d {
+			hitsAt = append(hitsAt, 0)
+		}
+		hitsAt[d]++
+	}
+	unique := hitsAt[0]
+
+	if len(sizes) == 0 {
+		sizes = curveSizes(unique, DefaultCurveSizes)
+	}
+	if len(sizes) > MaxCurveSizes {
+		return MRCReport{}, fmt.Errorf("at most %d sizes are allowed", MaxCurveSizes)
+	}
+	sizes = append([]int(nil), sizes...)
+	sort.Ints(sizes)
+	for _, size := range sizes {
+		if size <= 0 {
+			return MRCReport{}, fmt.Errorf("sizes must be positive")
+		}
+	}
+
+	if simulated := len(sizes) * (len(policies) + 1) * len(trace); simulated > MaxCurveAccesses {
+		return MRCReport{}, fmt.Errorf("%d sizes x %d policies x %d accesses is too much to simulate, use fewer sizes or a shorter trace", len(sizes), len(policies)+1, len(trace))
+	}
+
+	// hitsWithin[c] = LRU hits with a cache of c keys
+	hitsWithin := make([]int, len(hitsAt))
+	for d := 1; d < len(hitsAt); d++ {
+		hitsWithin[d] = hitsWithin[d-1] + hitsAt[d]
+	}
+	lruHits := func(size int) int {
+		return hitsWithin[min(size, len(hitsWithin)-1)]
+	}
+
+	report := MRCReport{
+		Accesses:    len(trace),
+		UniqueKeys:  unique,
+		ColdMisses:  unique,
+		MaxHitRatio: float64(len(trace)-unique) / float64(len(trace)),
+		Sizes:       sizes,
+		Curves:      []MissRatioCurve{},
+		LRUTargets:  []TargetSize{},
+	}
+
+	lru := MissRatioCurve{Key: "lru", Name: policyName("lru"), Exact: true}
+	for _, size := range sizes {
+		lru.Points = append(lru.Points, newMRCPoint(size, lruHits(size), len(trace)))
+	}
+	report.Curves = append(report.Curves, lru)
+
+	for _, key := range policies {
+		if key == "lru" {
+			continue
+		}
+		curve := MissRatioCurve{Key: key, Name: policyName(key)}
+		for _, size := range sizes {
+			policy, err := newReplayPolicy(key, size)
+			if err != nil {
+				return MRCReport{}, err
+			}
+			curve.Points = append(curve.Points, newMRCPoint(size, replay(policy, trace), len(trace)))
+		}
+		report.Curves = append(report.Curves, curve)
+	}
+
+	opt := MissRatioCurve{Key: "opt", Name: "OPT (Belady's MIN)"}
+	for _, size := range sizes {
+		policy := NewOPTCache(size, trace)
+		policy.mute()
+		opt.Points = append(opt.Points, newMRCPoint(size, replay(policy, trace), len(trace)))
+	}
+	report.Curves = append(report.Curves, opt)
+
+	for _, target := range DefaultTargetHitRatios {
+		need := TargetSize{HitRatio: target}
+		for size := 1; size < len(hitsWithin); size++ {
+			if float64(hitsWithin[size]) >= target*float64(len(trace)) {
+				need.Size = size
+				break
+			}
+		}
+		report.LRUTargets = append(report.LRUTargets, need)
+	}
+	return report, nil
+}
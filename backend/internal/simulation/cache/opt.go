@@ -0,0 +1,206 @@
+package cache
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// never is the next use of a key that does not occur again in the trace
+const never = math.MaxInt
+
+// OPTCache implements Belady's optimal (MIN) policy
+// It is an offline policy: it is given the whole trace of Gets up front and,
+// when full, evicts the resident key whose next use lies furthest in the
+// future, or bypasses the incoming key if it is needed later than all of them
+// (or never again). No online policy can have more hits on the same trace, which makes
+// OPT the upper bound the other policies are measured against
+type OPTCache struct {
+	mu          sync.RWMutex
+	capacity    int
+	trace       []string
+	occurrences map[string][]int // key -> positions in the trace, ascending
+	cursor      int              // Gets of the trace consumed so far
+	resident    map[string]*optEntry
+	byNextUse   optHeap // resident keys, furthest next use on top
+	bypassed    int     // Puts not cached because the key is needed last
+	eventLog
+}
+
+// optEntry is a resident key and the trace position of its next use
+type optEntry struct {
+	entry
+	nextUse int
+	index   int // position in the heap
+}
+
+// optHeap is a max-heap of resident keys by next use
+type optHeap []*optEntry
+
+func (h optHeap) Len() int           { return len(h) }
+func (h optHeap) Less(i, j int) bool { return h[i].nextUse > h[j].nextUse }
+func (h optHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *optHeap) Push(x interface{}) {
+	e := x.(*optEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+func (h *optHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
+
+// NewOPTCache creates an OPT cache that will be driven by the given trace of Gets
+func NewOPTCache(capacity int, trace []string) *OPTCache {
+	occurrences := make(map[string][]int)
+	for position, key := range trace {
+		occurrences[key] = append(occurrences[key], position)
+	}
+	return &OPTCache{
+		capacity:    capacity,
+		trace:       trace,
+		occurrences: occurrences,
+		resident:    make(map[string]*optEntry),
+	}
+}
+
+// nextUseAfter returns the first position >= from at which key is requested
+// (must be called with lock held)
+func (c *OPTCache) nextUseAfter(key string, from int) int {
+	positions := c.occurrences[key]
+	i := sort.SearchInts(positions, from)
+	if i == len(positions) {
+		return never
+	}
+	return positions[i]
+}
+
+// Get retrieves a value, consuming the next access of the trace
+// Gets that do not follow the trace are served but do not advance it
+func (c *OPTCache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cursor < len(c.trace) && c.trace[c.cursor] == key {
+		c.cursor++
+	}
+
+	item, exists := c.resident[key]
+	if !exists {
+		c.eventLog.record("GET", key, "", false, "", c.getCurrentKeys)
+		return "", false
+	}
+
+	item.accessTime = time.Now()
+	item.nextUse = c.nextUseAfter(key, c.cursor)
+	heap.Fix(&c.byNextUse, item.index)
+
+	c.eventLog.record("GET", key, item.value, true, "", c.getCurrentKeys)
+	return item.value, true
+}
+
+// Put adds or updates a key-value pair, evicting the key used furthest in the
+// future; when that is the incoming key itself it is not cached and is returned
+func (c *OPTCache) Put(key, value string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if item, exists := c.resident[key]; exists {
+		item.value = value
+		item.accessTime = time.Now()
+		c.eventLog.record("PUT", key, value, true, "", c.getCurrentKeys)
+		return ""
+	}
+
+	nextUse := c.nextUseAfter(key, c.cursor)
+	evictedKey := ""
+	if len(c.resident) >= c.capacity {
+		if len(c.byNextUse) == 0 || nextUse >= c.byNextUse[0].nextUse {
+			c.bypassed++
+			c.eventLog.record("PUT", key, value, false, key, c.getCurrentKeys)
+			return key
+		}
+		victim := heap.Pop(&c.byNextUse).(*optEntry)
+		evictedKey = victim.key
+		delete(c.resident, victim.key)
+	}
+
+	item := &optEntry{entry: *newEntry(key, value), nextUse: nextUse}
+	heap.Push(&c.byNextUse, item)
+	c.resident[key] = item
+
+	c.eventLog.record("PUT", key, value, false, evictedKey, c.getCurrentKeys)
+	return evictedKey
+}
+
+// GetState returns current cache state, soonest next use first
+func (c *OPTCache) GetState() CacheState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entries := append([]*optEntry(nil), c.byNextUse...)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].nextUse < entries[j].nextUse })
+
+	items := make([]CacheItem, 0, len(entries))
+	nextUse := make(map[string]int, len(entries))
+	for position, item := range entries {
+		items = append(items, CacheItem{
+			Key:        item.key,
+			Value:      item.value,
+			InsertTime: item.insertTime,
+			AccessTime: item.accessTime,
+			Position:   position,
+		})
+		if item.nextUse == never {
+			nextUse[item.key] = -1
+		} else {
+			nextUse[item.key] = item.nextUse - c.cursor
+		}
+	}
+
+	return CacheState{
+		Algorithm: "OPT (Belady's MIN)",
+		Capacity:  c.capacity,
+		Size:      len(c.resident),
+		Items:     items,
+		History:   c.eventLog.recent(20),
+		Details: map[string]interface{}{
+			"cursor":      c.cursor,
+			"traceLength": len(c.trace),
+			"bypassed":    c.bypassed,
+			"nextUseIn":   nextUse, // Accesses until each key is needed again (-1 = never)
+		},
+	}
+}
+
+// Reset clears the cache and rewinds the trace
+func (c *OPTCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cursor = 0
+	c.bypassed = 0
+	c.resident = make(map[string]*optEntry)
+	c.byNextUse = nil
+	c.eventLog.clear()
+}
+
+// getCurrentKeys returns resident keys, soonest next use first (must be called with lock held)
+func (c *OPTCache) getCurrentKeys() []string {
+	entries := append([]*optEntry(nil), c.byNextUse...)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].nextUse < entries[j].nextUse })
+
+	keys := make([]string, len(entries))
+	for i, item := range entries {
+		keys[i] = item.key
+	}
+	return keys
+}
@@ -0,0 +1,50 @@
+package cache
+
+import "testing"
+
+func TestNoPolicyBeatsOPT(t *testing.T) {
+	configs := []TraceConfig{
+		{Pattern: TraceZipf, Length: 20000, Keys: 1000, Skew: 1.0},
+		{Pattern: TraceShifting, Length: 20000, Keys: 1000, PhaseLength: 2000},
+		{Pattern: TraceScan, Length: 20000, Keys: 2000, HotKeys: 50, ScanLength: 200, ScanEvery: 500},
+		{Pattern: TraceLoop, Length: 20000, Keys: 1000, LoopLength: 120},
+	}
+	var policies []string
+	for _, info := range RegisteredPolicies() {
+		policies = append(policies, info.Key)
+	}
+
+	for _, config := range configs {
+		trace, err := GenerateTrace(config)
+		if err != nil {
+			t.Fatalf("%s: %v", config.Pattern, err)
+		}
+		for _, capacity := range []int{10, 100} {
+			comparison, err := CompareToOPT(trace, capacity, policies)
+			if err != nil {
+				t.Fatalf("%s/%d: %v", config.Pattern, capacity, err)
+			}
+			for _, result := range comparison.Policies {
+				if result.Hits > comparison.OPT.Hits {
+					t.Errorf("%s capacity %d: %s has %d hits, OPT has %d", config.Pattern, capacity, result.Key, result.Hits, comparison.OPT.Hits)
+				}
+			}
+		}
+	}
+}
+
+func TestOPTBypassesKeyNeededLast(t *testing.T) {
+	trace := []string{"a", "b", "c", "a", "b", "c"}
+	opt := NewOPTCache(2, trace)
+
+	for _, key := range trace[:3] {
+		access(opt, key)
+	}
+	// c is needed after a and b, so it must not displace either of them
+	if _, hit := opt.Get("a"); !hit {
+		t.Error("a was evicted for c")
+	}
+	if _, hit := opt.Get("b"); !hit {
+		t.Error("b was evicted for c")
+	}
+}
@@ -0,0 +1,251 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Policy is a cache with a particular eviction policy
+// Every policy records its operations as AccessEvents for visualization
+type Policy interface {
+	// Get retrieves a value, returning (value, hit)
+	Get(key string) (string, bool)
+
+	// Put adds or updates a value, returning the evicted key (if any)
+	Put(key, value string) string
+
+	// GetState returns the current cache state for visualization
+	GetState() CacheState
+
+	// Reset clears the cache
+	Reset()
+}
+
+// Factory creates a policy with the given capacity
+type Factory func(capacity int) Policy
+
+// PolicyInfo describes a registered policy
+type PolicyInfo struct {
+	Key  string `json:"key"`
+	Name string `json:"name"`
+}
+
+// registration is a registry entry
+type registration struct {
+	info    PolicyInfo
+	factory Factory
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]registration{}
+	order      []string // registration order, used for listing
+)
+
+// Register makes a policy available under key
+// Policies register themselves from an init function in their own file
+func Register(key, name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, exists := registry[key]; exists {
+		panic("cache: policy registered twice: " + key)
+	}
+	registry[key] = registration{info: PolicyInfo{Key: key, Name: name}, factory: factory}
+	order = append(order, key)
+}
+
+// NewPolicy creates a registered policy by key
+func NewPolicy(key string, capacity int) (Policy, error) {
+	if capacity <= 0 {
+		return nil, fmt.Errorf("capacity must be positive")
+	}
+
+	registryMu.RLock()
+	entry, exists := registry[key]
+	registryMu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("unknown cache policy %q", key)
+	}
+	return entry.factory(capacity), nil
+}
+
+// RegisteredPolicies lists every registered policy in registration order
+func RegisteredPolicies() []PolicyInfo {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	infos := make([]PolicyInfo, 0, len(order))
+	for _, key := range order {
+		infos = append(infos, registry[key].info)
+	}
+	return infos
+}
+
+// DefaultPolicies are the policies a new session compares
+var DefaultPolicies = []string{"lru", "lfu", "fifo"}
+
+// NamedPolicy pairs a policy instance with its registry key
+type NamedPolicy struct {
+	Key    string
+	Policy Policy
+}
+
+// Group is the set of policies a session compares side by side
+// All policies in a group share one capacity and receive the same operations
+type Group struct {
+	mu       sync.RWMutex
+	capacity int
+	policies []NamedPolicy
+}
+
+// NewGroup creates a group with one instance of each listed policy
+func NewGroup(keys []string, capacity int) (*Group, error) {
+	g := &Group{}
+	if err := g.Configure(keys, capacity); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Configure replaces the group's policies with fresh, empty instances
+func (g *Group) Configure(keys []string, capacity int) error {
+	if len(keys) == 0 {
+		return fmt.Errorf("at least one policy is required")
+	}
+
+	policies := make([]NamedPolicy, 0, len(keys))
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if seen[key] {
+			return fmt.Errorf("duplicate cache policy %q", key)
+		}
+		seen[key] = true
+
+		policy, err := NewPolicy(key, capacity)
+		if err != nil {
+			return err
+		}
+		policies = append(policies, NamedPolicy{Key: key, Policy: policy})
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.capacity = capacity
+	g.policies = policies
+	return nil
+}
+
+// Policies returns the group's policies in configured order
+func (g *Group) Policies() []NamedPolicy {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return append([]NamedPolicy(nil), g.policies...)
+}
+
+// Capacity returns the capacity shared by every policy in the group
+func (g *Group) Capacity() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.capacity
+}
+
+// States returns the state of every policy keyed by registry key
+func (g *Group) States() map[string]CacheState {
+	states := make(map[string]CacheState)
+	for _, named := range g.Policies() {
+		states[named.Key] = named.Policy.GetState()
+	}
+	return states
+}
+
+// Reset clears every policy in the group
+func (g *Group) Reset() {
+	for _, named := range g.Policies() {
+		named.Policy.Reset()
+	}
+}
+
+// maxHistory is how many events an eventLog keeps; older ones are dropped
+const maxHistory = 1000
+
+// eventLog is the operation history shared by every policy implementation
+// Policies embed it, which lets replays of long traces mute it
+type eventLog struct {
+	history []AccessEvent
+	muted   bool
+}
+
+// record appends an event with the cache contents after the operation
+// keys is only called when the event is actually kept
+func (l *eventLog) record(operation, key, value string, hit bool, evictedKey string, keys func() []string) {
+	if l.muted {
+		return
+	}
+	current := keys()
+	l.history = append(l.history, AccessEvent{
+		Operation:  operation,
+		Key:        key,
+		Value:      value,
+		Hit:        hit,
+		EvictedKey: evictedKey,
+		Timestamp:  time.Now(),
+		CacheSize:  len(current),
+		CacheItems: current,
+	})
+
+	// Trim in batches so appends stay amortized O(1)
+	if len(l.history) >= 2*maxHistory {
+		l.history = append([]AccessEvent{}, l.history[len(l.history)-maxHistory:]...)
+	}
+}
+
+// recordEvictions is record for operations that may evict several keys
+func (l *eventLog) recordEvictions(operation, key, value string, hit bool, evicted []string, keys func() []string) {
+	first := ""
+	if len(evicted) > 0 {
+		first = evicted[0]
+	}
+	l.record(operation, key, value, hit, first, keys)
+	if !l.muted && len(evicted) > 1 {
+		l.history[len(l.history)-1].Evicted = evicted
+	}
+}
+
+// recent returns the last n events
+func (l *eventLog) recent(n int) []AccessEvent {
+	start := len(l.history) - n
+	if start < 0 {
+		start = 0
+	}
+	return append([]AccessEvent{}, l.history[start:]...)
+}
+
+// clear drops all events
+func (l *eventLog) clear() {
+	l.history = []AccessEvent{}
+}
+
+// mute stops recording events (used for trace replays, which only need hit counts)
+func (l *eventLog) mute() {
+	l.muted = true
+}
+
+// muter is implemented by every policy through its embedded eventLog
+type muter interface {
+	mute()
+}
+
+// newReplayPolicy creates a policy that records no access history
+func newReplayPolicy(key string, capacity int) (Policy, error) {
+	policy, err := NewPolicy(key, capacity)
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := policy.(muter); ok {
+		m.mute()
+	}
+	return policy, nil
+}
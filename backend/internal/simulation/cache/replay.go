@@ -0,0 +1,113 @@
+package cache
+
+import "fmt"
+
+// MaxTraceLength bounds the traces a single replay accepts
+const MaxTraceLength = 100000
+
+// PolicyResult is how one policy did on a trace
+type PolicyResult struct {
+	Key          string  `json:"key"`
+	Name         string  `json:"name"`
+	Hits         int     `json:"hits"`
+	Misses       int     `json:"misses"`
+	HitRatio     float64 `json:"hitRatio"`
+	PercentOfOPT float64 `json:"percentOfOpt"` // Hits as a percentage of OPT's hits on the same trace
+}
+
+// OPTComparison reports every policy against Belady's OPT on the same trace
+type OPTComparison struct {
+	Capacity int            `json:"capacity"`
+	Accesses int            `json:"accesses"`
+	OPT      PolicyResult   `json:"opt"`
+	Policies []PolicyResult `json:"policies"`
+}
+
+// validateTrace checks a trace and capacity before a replay
+func validateTrace(trace []string, capacity int) error {
+	if len(trace) == 0 {
+		return fmt.Errorf("trace is empty")
+	}
+	if len(trace) > MaxTraceLength {
+		return fmt.Errorf("trace has %d accesses, at most %d are allowed", len(trace), MaxTraceLength)
+	}
+	if capacity <= 0 {
+		return fmt.Errorf("capacity must be positive")
+	}
+	return nil
+}
+
+// access performs one demand-filled access: a Get, and a Put on a miss
+func access(policy Policy, key string) bool {
+	if _, hit := policy.Get(key); hit {
+		return true
+	}
+	policy.Put(key, key)
+	return false
+}
+
+// replay runs the whole trace through the policy and returns its hits
+func replay(policy Policy, trace []string) int {
+	hits := 0
+	for _, key := range trace {
+		if access(policy, key) {
+			hits++
+		}
+	}
+	return hits
+}
+
+// policyName returns the registered display name of a policy
+func policyName(key string) string {
+	for _, info := range RegisteredPolicies() {
+		if info.Key == key {
+			return info.Name
+		}
+	}
+	return key
+}
+
+// newResult fills in the ratios of a policy result
+func newResult(key, name string, hits, accesses, optHits int) PolicyResult {
+	result := PolicyResult{
+		Key:          key,
+		Name:         name,
+		Hits:         hits,
+		Misses:       accesses - hits,
+		HitRatio:     float64(hits) / float64(accesses),
+		PercentOfOPT: 100,
+	}
+	if optHits > 0 {
+		result.PercentOfOPT = 100 * float64(hits) / float64(optHits)
+	}
+	return result
+}
+
+// CompareToOPT replays the trace (each access a Get, followed by a Put on a
+// miss) through fresh instances of the policies and through OPT
+func CompareToOPT(trace []string, capacity int, policies []string) (OPTComparison, error) {
+	if err := validateTrace(trace, capacity); err != nil {
+		return OPTComparison{}, err
+	}
+
+	opt := NewOPTCache(capacity, trace)
+	opt.mute()
+	optHits := replay(opt, trace)
+
+	comparison := OPTComparison{
+		Capacity: capacity,
+		Accesses: len(trace),
+		OPT:      newResult("opt", "OPT (Belady's MIN)", optHits, len(trace), optHits),
+		Policies: make([]PolicyResult, 0, len(policies)),
+	}
+
+	for _, key := range policies {
+		policy, err := newReplayPolicy(key, capacity)
+		if err != nil {
+			return OPTComparison{}, err
+		}
+		hits := replay(policy, trace)
+		comparison.Policies = append(comparison.Policies, newResult(key, policyName(key), hits, len(trace), optHits))
+	}
+	return comparison, nil
+}
@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"container/list"
+	"time"
+)
+
+// entry is a cached key/value shared by the segmented policies
+type entry struct {
+	key        string
+	value      string
+	insertTime time.Time
+	accessTime time.Time
+}
+
+// keyList is an LRU-ordered list of entries with O(1) lookup
+// Front = most recently used, back = next to be evicted
+type keyList struct {
+	order *list.List
+	index map[string]*list.Element
+}
+
+// newKeyList creates an empty key list
+func newKeyList() *keyList {
+	return &keyList{
+		order: list.New(),
+		index: make(map[string]*list.Element),
+	}
+}
+
+// size returns the number of entries
+func (l *keyList) size() int {
+	return l.order.Len()
+}
+
+// get returns the entry for key without changing its position
+func (l *keyList) get(key string) (*entry, bool) {
+	element, exists := l.index[key]
+	if !exists {
+		return nil, false
+	}
+	return element.Value.(*entry), true
+}
+
+// pushFront inserts an entry as most recently used
+func (l *keyList) pushFront(e *entry) {
+	l.index[e.key] = l.order.PushFront(e)
+}
+
+// moveToFront marks an entry as most recently used
+func (l *keyList) moveToFront(key string) {
+	if element, exists := l.index[key]; exists {
+		l.order.MoveToFront(element)
+	}
+}
+
+// remove deletes key, returning its entry (nil if absent)
+func (l *keyList) remove(key string) *entry {
+	element, exists := l.index[key]
+	if !exists {
+		return nil
+	}
+	delete(l.index, key)
+	return l.order.Remove(element).(*entry)
+}
+
+// back returns the least recently used entry (nil if empty)
+func (l *keyList) back() *entry {
+	if l.order.Len() == 0 {
+		return nil
+	}
+	return l.order.Back().Value.(*entry)
+}
+
+// popBack removes and returns the least recently used entry (nil if empty)
+func (l *keyList) popBack() *entry {
+	e := l.back()
+	if e != nil {
+		l.remove(e.key)
+	}
+	return e
+}
+
+// keys returns the keys from most to least recently used
+func (l *keyList) keys() []string {
+	keys := make([]string, 0, l.order.Len())
+	for e := l.order.Front(); e != nil; e = e.Next() {
+		keys = append(keys, e.Value.(*entry).key)
+	}
+	return keys
+}
+
+// appendItems adds the list's entries to items for visualization, labelled with segment
+func (l *keyList) appendItems(items []CacheItem, segment string) []CacheItem {
+	for e := l.order.Front(); e != nil; e = e.Next() {
+		item := e.Value.(*entry)
+		items = append(items, CacheItem{
+			Key:        item.key,
+			Value:      item.value,
+			InsertTime: item.insertTime,
+			AccessTime: item.accessTime,
+			Position:   len(items),
+			Segment:    segment,
+		})
+	}
+	return items
+}
+
+// segment describes the list for CacheState
+func (l *keyList) segment(name string, capacity int, ghost bool) CacheSegment {
+	return CacheSegment{Name: name, Keys: l.keys(), Capacity: capacity, Ghost: ghost}
+}
+
+// newEntry creates an entry inserted now
+func newEntry(key, value string) *entry {
+	now := time.Now()
+	return &entry{key: key, value: value, insertTime: now, accessTime: now}
+}
@@ -0,0 +1,203 @@
+package cache
+
+import (
+	"container/list"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// ShardedCache is a concurrent LRU cache meant for real use rather than visualization
+// Keys are striped over independently locked shards, so operations on different
+// shards never contend, and no access history is recorded. Each shard evicts its
+// own least recently used key, which approximates a global LRU when keys hash evenly
+type ShardedCache struct {
+	shards   []*cacheShard
+	mask     uint64
+	capacity int
+}
+
+// cacheShard is one lock stripe: an ordinary LRU over its slice of the key space
+type cacheShard struct {
+	mu        sync.Mutex
+	capacity  int
+	items     map[string]*list.Element
+	order     *list.List // front = most recently used
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+// shardEntry is a key-value pair in a shard's LRU list
+type shardEntry struct {
+	key   string
+	value string
+}
+
+// ShardedStats summarizes a sharded cache across all shards
+type ShardedStats struct {
+	Shards     int     `json:"shards"`
+	Capacity   int     `json:"capacity"`
+	Size       int     `json:"size"`
+	Hits       uint64  `json:"hits"`
+	Misses     uint64  `json:"misses"`
+	Evictions  uint64  `json:"evictions"`
+	HitRatio   float64 `json:"hitRatio"`
+	ShardSizes []int   `json:"shardSizes"`
+}
+
+// NewShardedCache creates a cache holding about capacity keys over the given number of shards
+// shards is rounded up to a power of two; zero or less picks four per CPU
+func NewShardedCache(capacity, shards int) (*ShardedCache, error) {
+	if capacity <= 0 {
+		return nil, fmt.Errorf("capacity must be positive")
+	}
+	if shards <= 0 {
+		shards = 4 * runtime.GOMAXPROCS(0)
+	}
+
+	if shards > capacity {
+		return nil, fmt.Errorf("shards must not exceed capacity")
+	}
+
+	count := 1
+	for count < shards {
+		// Doubling stays within capacity, so it cannot overflow
+		if count > capacity/2 {
+			return nil, fmt.Errorf("shards (%d) rounded up to a power of two must not exceed capacity", shards)
+		}
+		count <<= 1
+	}
+
+	// Round per-shard capacity up so the total is never below what was asked for
+	perShard := capacity / count
+	if capacity%count != 0 {
+		perShard++
+	}
+	c := &ShardedCache{
+		shards:   make([]*cacheShard, count),
+		mask:     uint64(count - 1),
+		capacity: perShard * count,
+	}
+	for i := range c.shards {
+		c.shards[i] = &cacheShard{
+			capacity: perShard,
+			items:    make(map[string]*list.Element, perShard),
+			order:    list.New(),
+		}
+	}
+	return c, nil
+}
+
+// shardFor picks a key's shard with an allocation-free FNV-1a hash
+func (c *ShardedCache) shardFor(key string) *cacheShard {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= 1099511628211
+	}
+	h ^= h >> 32
+	return c.shards[h&c.mask]
+}
+
+// Get retrieves a value and marks it as recently used
+func (c *ShardedCache) Get(key string) (string, bool) {
+	s := c.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, exists := s.items[key]
+	if !exists {
+		s.misses++
+		return "", false
+	}
+	s.hits++
+	s.order.MoveToFront(element)
+	return element.Value.(*shardEntry).value, true
+}
+
+// Put adds or updates a value, returning the key evicted from its shard (if any)
+func (c *ShardedCache) Put(key, value string) string {
+	s := c.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, exists := s.items[key]; exists {
+		element.Value.(*shardEntry).value = value
+		s.order.MoveToFront(element)
+		return ""
+	}
+
+	evictedKey := ""
+	if len(s.items) >= s.capacity {
+		oldest := s.order.Back()
+		entry := oldest.Value.(*shardEntry)
+		evictedKey = entry.key
+		delete(s.items, entry.key)
+		s.order.Remove(oldest)
+		s.evictions++
+	}
+
+	s.items[key] = s.order.PushFront(&shardEntry{key: key, value: value})
+	return evictedKey
+}
+
+// Remove deletes a key, reporting whether it was present
+func (c *ShardedCache) Remove(key string) bool {
+	s := c.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, exists := s.items[key]
+	if !exists {
+		return false
+	}
+	delete(s.items, key)
+	s.order.Remove(element)
+	return true
+}
+
+// Len returns the number of cached keys
+func (c *ShardedCache) Len() int {
+	total := 0
+	for _, s := range c.shards {
+		s.mu.Lock()
+		total += len(s.items)
+		s.mu.Unlock()
+	}
+	return total
+}
+
+// Stats returns hit, miss and eviction counts summed over shards
+// Shards are read one at a time, so the totals are not an atomic snapshot
+func (c *ShardedCache) Stats() ShardedStats {
+	stats := ShardedStats{
+		Shards:     len(c.shards),
+		Capacity:   c.capacity,
+		ShardSizes: make([]int, len(c.shards)),
+	}
+	for i, s := range c.shards {
+		s.mu.Lock()
+		stats.ShardSizes[i] = len(s.items)
+		stats.Size += len(s.items)
+		stats.Hits += s.hits
+		stats.Misses += s.misses
+		stats.Evictions += s.evictions
+		s.mu.Unlock()
+	}
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(lookups)
+	}
+	return stats
+}
+
+// Reset empties every shard and clears the statistics
+func (c *ShardedCache) Reset() {
+	for _, s := range c.shards {
+		s.mu.Lock()
+		s.items = make(map[string]*list.Element, s.capacity)
+		s.order = list.New()
+		s.hits, s.misses, s.evictions = 0, 0, 0
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,241 @@
+package cache
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// Run with: go test -bench Parallel -cpu 1,4,16 ./internal/simulation/cache
+
+const (
+	benchCapacity = 1000
+	benchKeys     = 10000
+)
+
+// getPutter is the part of a cache the benchmarks exercise
+type getPutter interface {
+	Get(key string) (string, bool)
+	Put(key, value string) string
+}
+
+// benchTrace is a Zipf-distributed key sequence shared by every benchmark
+func benchTrace(b *testing.B) []string {
+	trace, err := GenerateTrace(TraceConfig{Pattern: TraceZipf, Length: 1 << 16, Keys: benchKeys, Skew: 0.99, Seed: 1})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return trace
+}
+
+// runParallel demand-fills the cache from every goroutine: a get, then a put on a miss
+// Each goroutine starts at a different offset into the trace
+func runParallel(b *testing.B, c getPutter, trace []string) {
+	var offset uint64
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := int(atomic.AddUint64(&offset, 7919))
+		for pb.Next() {
+			key := trace[i%len(trace)]
+			if _, hit := c.Get(key); !hit {
+				c.Put(key, key)
+			}
+			i++
+		}
+	})
+}
+
+// BenchmarkParallelPolicies measures the visualization caches, which take one
+// lock per operation and snapshot their contents into the event history
+func BenchmarkParallelPolicies(b *testing.B) {
+	trace := benchTrace(b)
+	for _, key := range []string{"lru", "lfu", "fifo"} {
+		b.Run(key, func(b *testing.B) {
+			policy, err := NewPolicy(key, benchCapacity)
+			if err != nil {
+				b.Fatal(err)
+			}
+			runParallel(b, policy, trace)
+		})
+		b.Run(key+"-muted", func(b *testing.B) {
+			policy, err := newReplayPolicy(key, benchCapacity)
+			if err != nil {
+				b.Fatal(err)
+			}
+			runParallel(b, policy, trace)
+		})
+	}
+}
+
+// BenchmarkParallelSharded measures the lock-striped cache; one shard is a
+// single global lock without history, isolating the cost of contention
+func BenchmarkParallelSharded(b *testing.B) {
+	trace := benchTrace(b)
+	for _, shards := range []int{1, 4, 16, 64} {
+		b.Run("shards-"+strconv.Itoa(shards), func(b *testing.B) {
+			c, err := NewShardedCache(benchCapacity, shards)
+			if err != nil {
+				b.Fatal(err)
+			}
+			runParallel(b, c, trace)
+			b.ReportMetric(c.Stats().HitRatio, "hit-ratio")
+		})
+	}
+}
+
+func TestNewShardedCache(t *testing.T) {
+	tests := []struct {
+		name         string
+		capacity     int
+		shards       int
+		wantShards   int
+		wantCapacity int
+		wantErr      bool
+	}{
+		{name: "power of two", capacity: 64, shards: 4, wantShards: 4, wantCapacity: 64},
+		{name: "shards rounded up", capacity: 64, shards: 3, wantShards: 4, wantCapacity: 64},
+		{name: "capacity rounded up", capacity: 10, shards: 4, wantShards: 4, wantCapacity: 12},
+		{name: "one shard", capacity: 5, shards: 1, wantShards: 1, wantCapacity: 5},
+		{name: "zero capacity", capacity: 0, shards: 1, wantErr: true},
+		{name: "more shards than capacity", capacity: 3, shards: 4, wantErr: true},
+		{name: "shards rounded past capacity", capacity: 5, shards: 5, wantErr: true},
+		{name: "shards past the largest power of two", capacity: 8, shards: 1<<62 + 1, wantErr: true},
+		{name: "huge capacity and shards", capacity: math.MaxInt, shards: 1<<62 + 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewShardedCache(tt.capacity, tt.shards)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewShardedCache(%d, %d) error = %v, wantErr %v", tt.capacity, tt.shards, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			stats := c.Stats()
+			if stats.Shards != tt.wantShards || stats.Capacity != tt.wantCapacity {
+				t.Errorf("%d shards holding %d keys, want %d holding %d", stats.Shards, stats.Capacity, tt.wantShards, tt.wantCapacity)
+			}
+		})
+	}
+}
+
+func TestShardedCacheOneShardIsLRU(t *testing.T) {
+	trace, err := GenerateTrace(TraceConfig{Pattern: TraceZipf, Length: 5000, Keys: 200, Skew: 0.9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sharded, err := NewShardedCache(20, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lru := NewLRUCache(20)
+	lru.mute()
+
+	for i, key := range trace {
+		_, shardedHit := sharded.Get(key)
+		_, lruHit := lru.Get(key)
+		if shardedHit != lruHit {
+			t.Fatalf("access %d (%s): sharded hit %v, LRU hit %v", i, key, shardedHit, lruHit)
+		}
+		if !shardedHit {
+			if a, b := sharded.Put(key, key), lru.Put(key, key); a != b {
+				t.Fatalf("access %d (%s): sharded evicted %q, LRU evicted %q", i, key, a, b)
+			}
+		}
+	}
+}
+
+func TestShardedCacheOperations(t *testing.T) {
+	c, err := NewShardedCache(2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		op      string
+		key     string
+		want    string // value for get, evicted key for put
+		wantHit bool   // get: hit; remove: was present
+	}{
+		{op: "put", key: "a", want: ""},
+		{op: "put", key: "b", want: ""},
+		{op: "get", key: "a", want: "a", wantHit: true},
+		{op: "put", key: "c", want: "b"}, // b is least recently used
+		{op: "get", key: "b", wantHit: false},
+		{op: "put", key: "a", want: ""}, // update in place
+		{op: "remove", key: "a", wantHit: true},
+		{op: "remove", key: "a", wantHit: false},
+		{op: "put", key: "d", want: ""}, // the removal freed a slot
+	}
+	for i, step := range steps {
+		switch step.op {
+		case "put":
+			if evicted := c.Put(step.key, step.key); evicted != step.want {
+				t.Errorf("step %d: put %s evicted %q, want %q", i, step.key, evicted, step.want)
+			}
+		case "get":
+			value, hit := c.Get(step.key)
+			if hit != step.wantHit || value != step.want {
+				t.Errorf("step %d: get %s = %q, %v, want %q, %v", i, step.key, value, hit, step.want, step.wantHit)
+			}
+		case "remove":
+			if removed := c.Remove(step.key); removed != step.wantHit {
+				t.Errorf("step %d: remove %s = %v, want %v", i, step.key, removed, step.wantHit)
+			}
+		}
+	}
+
+	stats := c.Stats()
+	want := ShardedStats{Shards: 1, Capacity: 2, Size: 2, Hits: 1, Misses: 1, Evictions: 1, HitRatio: 0.5}
+	if stats.Size != want.Size || stats.Hits != want.Hits || stats.Misses != want.Misses || stats.Evictions != want.Evictions || stats.HitRatio != want.HitRatio {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+
+	c.Reset()
+	stats = c.Stats()
+	if stats.Size != 0 || stats.Hits != 0 || stats.Misses != 0 || stats.Evictions != 0 {
+		t.Errorf("stats after Reset = %+v, want empty", stats)
+	}
+	if _, hit := c.Get("c"); hit {
+		t.Error("c survived Reset")
+	}
+}
+
+func TestShardedCacheConcurrent(t *testing.T) {
+	c, err := NewShardedCache(256, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				key := "k" + strconv.Itoa((g*7919+i)%1000)
+				if _, hit := c.Get(key); !hit {
+					c.Put(key, key)
+				}
+				if i%10 == 0 {
+					c.Remove(key)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	stats := c.Stats()
+	if stats.Size > stats.Capacity || stats.Size != c.Len() {
+		t.Errorf("size %d (Len %d) with capacity %d", stats.Size, c.Len(), stats.Capacity)
+	}
+	if stats.Hits+stats.Misses != 8*2000 {
+		t.Errorf("%d lookups counted, want %d", stats.Hits+stats.Misses, 8*2000)
+	}
+}
@@ -0,0 +1,570 @@
+package cache
+
+import (
+	"container/heap"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SizedEviction selects how a SizedCache picks victims
+type SizedEviction string
+
+const (
+	// Least recently used first, however large
+	SizedLRU SizedEviction = "lru"
+	// Oldest insertion first
+	SizedFIFO SizedEviction = "fifo"
+	// GreedyDual-Size (Cao & Irani): lowest H = L + cost/size first, L being
+	// the H of the last victim, so large cheap objects go before small costly
+	// ones and untouched objects age out as L inflates
+	SizedGDS SizedEviction = "gds"
+	// GreedyDual-Size-Frequency: H = L + frequency*cost/size
+	SizedGDSF SizedEviction = "gdsf"
+)
+
+// SizedEvictions lists every eviction strategy of a SizedCache
+var SizedEvictions = []SizedEviction{SizedLRU, SizedFIFO, SizedGDS, SizedGDSF}
+
+// SizedConfig configures a SizedCache
+type SizedConfig struct {
+	CapacityBytes int           `json:"capacityBytes"`
+	Eviction      SizedEviction `json:"eviction"`
+	ActiveExpiry  bool          `json:"activeExpiry"` // Sample and delete expired keys in the background, as well as on access
+	ExpiryHz      int           `json:"expiryHz"`     // Active expiry cycles per second (Redis: 10)
+	SampleSize    int           `json:"sampleSize"`   // Keys with a TTL sampled per round (Redis: 20)
+}
+
+// DefaultSizedConfig returns a 1 KB GreedyDual-Size cache with Redis' expiry settings
+func DefaultSizedConfig() SizedConfig {
+	return SizedConfig{
+		CapacityBytes: 1024,
+		Eviction:      SizedGDS,
+		ActiveExpiry:  true,
+		ExpiryHz:      10,
+		SampleSize:    20,
+	}
+}
+
+// Validate checks the config
+func (c SizedConfig) Validate() error {
+	if c.CapacityBytes <= 0 {
+		return fmt.Errorf("capacityBytes must be positive")
+	}
+	known := false
+	for _, eviction := range SizedEvictions {
+		known = known || eviction == c.Eviction
+	}
+	if !known {
+		return fmt.Errorf("unknown eviction %q", c.Eviction)
+	}
+	if c.ActiveExpiry && (c.ExpiryHz <= 0 || c.ExpiryHz > 1000) {
+		return fmt.Errorf("expiryHz must be between 1 and 1000")
+	}
+	if c.ActiveExpiry && c.SampleSize <= 0 {
+		return fmt.Errorf("sampleSize must be positive")
+	}
+	return nil
+}
+
+// activeExpireRounds bounds the sampling rounds of one active expiry cycle
+// (Redis bounds the cycle by CPU time instead)
+const activeExpireRounds = 16
+
+// SizedStats counts what happened to a SizedCache
+type SizedStats struct {
+	Hits          int     `json:"hits"`
+	Misses        int     `json:"misses"`
+	HitBytes      int     `json:"hitBytes"`
+	MissBytes     int     `json:"missBytes"` // Sizes of objects filled after a miss
+	HitRatio      float64 `json:"hitRatio"`
+	ByteHitRatio  float64 `json:"byteHitRatio"`
+	Evictions     int     `json:"evictions"`
+	EvictedBytes  int     `json:"evictedBytes"`
+	ExpiredLazy   int     `json:"expiredLazy"`   // Found expired on access
+	ExpiredActive int     `json:"expiredActive"` // Found expired by sampling
+	ExpiryCycles  int     `json:"expiryCycles"`
+	Rejected      int     `json:"rejected"` // Objects larger than the whole cache
+}
+
+// sizedEntry is one object of a SizedCache
+type sizedEntry struct {
+	entry
+	size      int
+	cost      float64
+	frequency int
+	expiresAt time.Time // Zero = no TTL
+	priority  float64   // Lowest is evicted first
+	heapIndex int
+}
+
+// sizedHeap orders entries by priority, lowest first
+type sizedHeap []*sizedEntry
+
+func (h sizedHeap) Len() int           { return len(h) }
+func (h sizedHeap) Less(i, j int) bool { return h[i].priority < h[j].priority }
+func (h sizedHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapIndex = i
+	h[j].heapIndex = j
+}
+func (h *sizedHeap) Push(x interface{}) {
+	item := x.(*sizedEntry)
+	item.heapIndex = len(*h)
+	*h = append(*h, item)
+}
+func (h *sizedHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// SizedCache is a cache whose capacity is in bytes rather than keys, with
+// per-key TTLs expired both lazily (on access) and actively (by sampling, as
+// Redis does). It runs on its own virtual clock, which only moves on Advance
+type SizedCache struct {
+	mu        sync.RWMutex
+	config    SizedConfig
+	now       time.Time
+	nextCycle time.Time // When the next active expiry cycle runs
+	entries   map[string]*sizedEntry
+	order     sizedHeap
+	volatile  []string       // Keys with a TTL, sampled by active expiry
+	volIndex  map[string]int // key -> position in volatile
+	used      int
+	inflation float64 // GreedyDual's L
+	tick      float64 // Logical time for LRU and FIFO priorities
+	stats     SizedStats
+	rng       *rand.Rand
+	eventLog
+}
+
+// NewSizedCache creates an empty sized cache
+func NewSizedCache(config SizedConfig) (*SizedCache, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	c := &SizedCache{config: config, now: time.Now()}
+	c.clearLocked()
+	return c, nil
+}
+
+// clearLocked empties the cache and its statistics (must be called with lock held)
+func (c *SizedCache) clearLocked() {
+	c.entries = make(map[string]*sizedEntry)
+	c.order = sizedHeap{}
+	c.volatile = []string{}
+	c.volIndex = make(map[string]int)
+	c.used = 0
+	c.inflation = 0
+	c.tick = 0
+	c.stats = SizedStats{}
+	c.rng = rand.New(rand.NewSource(1))
+	c.nextCycle = c.now.Add(c.cyclePeriod())
+	c.eventLog.clear()
+}
+
+// cyclePeriod is the time between active expiry cycles (must be called with lock held)
+func (c *SizedCache) cyclePeriod() time.Duration {
+	if c.config.ExpiryHz <= 0 {
+		return time.Second
+	}
+	return time.Second / time.Duration(c.config.ExpiryHz)
+}
+
+// Configure replaces the configuration and empties the cache
+func (c *SizedCache) Configure(config SizedConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.config = config
+	c.clearLocked()
+	return nil
+}
+
+// Config returns the current configuration
+func (c *SizedCache) Config() SizedConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config
+}
+
+// Now returns the cache's virtual time
+func (c *SizedCache) Now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.now
+}
+
+// Get retrieves a value, deleting it instead if its TTL has passed
+func (c *SizedCache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item := c.lookup(key)
+	if item == nil {
+		c.stats.Misses++
+		c.eventLog.record("GET", key, "", false, "", c.getCurrentKeys)
+		return "", false
+	}
+
+	c.stats.Hits++
+	c.stats.HitBytes += item.size
+	c.eventLog.record("GET", key, item.value, true, "", c.getCurrentKeys)
+	return item.value, true
+}
+
+// lookup finds a live key and records the access, expiring it lazily if due
+// (must be called with lock held)
+func (c *SizedCache) lookup(key string) *sizedEntry {
+	item, exists := c.entries[key]
+	if !exists {
+		return nil
+	}
+	if c.expired(item) {
+		c.remove(item)
+		c.stats.ExpiredLazy++
+		c.eventLog.record("EXPIRE", key, item.value, false, "", c.getCurrentKeys)
+		return nil
+	}
+
+	item.frequency++
+	item.accessTime = c.now
+	c.prioritize(item)
+	heap.Fix(&c.order, item.heapIndex)
+	return item
+}
+
+// Put stores an object of the given size; cost is what a miss on it costs
+// (1 maximizes hit ratio, the size maximizes byte hit ratio) and a positive
+// ttl makes it expire. Returns the keys evicted to make room
+func (c *SizedCache) Put(key, value string, size int, cost float64, ttl time.Duration) ([]string, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("size must be positive")
+	}
+	if cost <= 0 {
+		cost = 1
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if size > c.config.CapacityBytes {
+		c.stats.Rejected++
+		return nil, fmt.Errorf("object of %d bytes does not fit in a %d byte cache", size, c.config.CapacityBytes)
+	}
+
+	frequency := 1
+	if old, exists := c.entries[key]; exists {
+		// Like a Redis SET, an update replaces the value and any TTL
+		frequency = old.frequency + 1
+		c.remove(old)
+	}
+
+	evicted := []string{}
+	for c.used+size > c.config.CapacityBytes {
+		victim := c.order[0]
+		if c.config.Eviction == SizedGDS || c.config.Eviction == SizedGDSF {
+			c.inflation = victim.priority
+		}
+		c.remove(victim)
+		c.stats.Evictions++
+		c.stats.EvictedBytes += victim.size
+		evicted = append(evicted, victim.key)
+	}
+
+	item := &sizedEntry{
+		entry:     *newEntry(key, value),
+		size:      size,
+		cost:      cost,
+		frequency: frequency,
+	}
+	item.insertTime = c.now
+	item.accessTime = c.now
+	if ttl > 0 {
+		item.expiresAt = c.now.Add(ttl)
+		c.volIndex[key] = len(c.volatile)
+		c.volatile = append(c.volatile, key)
+	}
+	c.prioritize(item)
+	heap.Push(&c.order, item)
+	c.entries[key] = item
+	c.used += size
+
+	c.eventLog.recordEvictions("PUT", key, value, false, evicted, c.getCurrentKeys)
+	return evicted, nil
+}
+
+// Access is a demand-filled read: a Get, then a Put of the object on a miss
+func (c *SizedCache) Access(key string, size int, cost float64, ttl time.Duration) (bool, []string, error) {
+	if _, hit := c.Get(key); hit {
+		return true, nil, nil
+	}
+	c.mu.Lock()
+	c.stats.MissBytes += size
+	c.mu.Unlock()
+
+	evicted, err := c.Put(key, key, size, cost, ttl)
+	return false, evicted, err
+}
+
+// prioritize sets an entry's eviction priority for the configured strategy
+// (must be called with lock held)
+func (c *SizedCache) prioritize(item *sizedEntry) {
+	switch c.config.Eviction {
+	case SizedLRU:
+		c.tick++
+		item.priority = c.tick
+	case SizedFIFO:
+		if item.priority == 0 {
+			c.tick++
+			item.priority = c.tick
+		}
+	case SizedGDS:
+		item.priority = c.inflation + item.cost/float64(item.size)
+	case SizedGDSF:
+		item.priority = c.inflation + float64(item.frequency)*item.cost/float64(item.size)
+	}
+}
+
+// expired reports whether an entry's TTL has passed (must be called with lock held)
+func (c *SizedCache) expired(item *sizedEntry) bool {
+	return !item.expiresAt.IsZero() && !c.now.Before(item.expiresAt)
+}
+
+// remove deletes an entry that is in the heap (must be called with lock held)
+func (c *SizedCache) remove(item *sizedEntry) {
+	heap.Remove(&c.order, item.heapIndex)
+	delete(c.entries, item.key)
+	c.used -= item.size
+
+	if i, exists := c.volIndex[item.key]; exists {
+		last := c.volatile[len(c.volatile)-1]
+		c.volatile[i] = last
+		c.volIndex[last] = i
+		c.volatile = c.volatile[:len(c.volatile)-1]
+		delete(c.volIndex, item.key)
+	}
+}
+
+// Advance moves the virtual clock forward by d, running every active expiry
+// cycle that falls due on the way
+func (c *SizedCache) Advance(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	target := c.now.Add(d)
+	if c.config.ActiveExpiry {
+		// With no TTL keys to sample, due cycles only count themselves, so skip them in one go
+		if len(c.volatile) == 0 && !c.nextCycle.After(target) {
+			period := c.cyclePeriod()
+			due := int(target.Sub(c.nextCycle)/period) + 1
+			c.stats.ExpiryCycles += due
+			c.nextCycle = c.nextCycle.Add(time.Duration(due) * period)
+		}
+		for !c.nextCycle.After(target) {
+			c.now = c.nextCycle
+			c.activeExpireCycle()
+			c.nextCycle = c.nextCycle.Add(c.cyclePeriod())
+		}
+	}
+	c.now = target
+}
+
+// activeExpireCycle samples keys with a TTL and deletes the expired ones,
+// sampling again while more than a quarter of a sample had expired, like
+// Redis' activeExpireCycle (must be called with lock held)
+func (c *SizedCache) activeExpireCycle() {
+	c.stats.ExpiryCycles++
+	for round := 0; round < activeExpireRounds && len(c.volatile) > 0; round++ {
+		sample := min(c.config.SampleSize, len(c.volatile))
+		expired := 0
+		for i := 0; i < sample && len(c.volatile) > 0; i++ {
+			item := c.entries[c.volatile[c.rng.Intn(len(c.volatile))]]
+			if c.expired(item) {
+				c.remove(item)
+				c.stats.ExpiredActive++
+				expired++
+				c.eventLog.record("EXPIRE", item.key, item.value, false, "", c.getCurrentKeys)
+			}
+		}
+		if expired*4 <= sample {
+			return
+		}
+	}
+}
+
+// Stats returns the counters with ratios filled in
+func (c *SizedCache) Stats() SizedStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.statsLocked()
+}
+
+// statsLocked fills in the ratios (must be called with lock held)
+func (c *SizedCache) statsLocked() SizedStats {
+	stats := c.stats
+	if accesses := stats.Hits + stats.Misses; accesses > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(accesses)
+	}
+	if bytes := stats.HitBytes + stats.MissBytes; bytes > 0 {
+		stats.ByteHitRatio = float64(stats.HitBytes) / float64(bytes)
+	}
+	return stats
+}
+
+// GetState returns current cache state, cheapest to evict first
+// Capacity and Size are in bytes
+func (c *SizedCache) GetState() CacheState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ordered := append([]*sizedEntry(nil), c.order...)
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].priority < ordered[j].priority })
+
+	items := make([]CacheItem, 0, len(ordered))
+	for position, item := range ordered {
+		cacheItem := CacheItem{
+			Key:        item.key,
+			Value:      item.value,
+			Frequency:  item.frequency,
+			InsertTime: item.insertTime,
+			AccessTime: item.accessTime,
+			Position:   position,
+			Size:       item.size,
+			Cost:       item.cost,
+			Priority:   item.priority,
+		}
+		if !item.expiresAt.IsZero() {
+			expiresAt := item.expiresAt
+			cacheItem.ExpiresAt = &expiresAt
+		}
+		items = append(items, cacheItem)
+	}
+
+	return CacheState{
+		Algorithm: "Size-weighted (" + string(c.config.Eviction) + ")",
+		Capacity:  c.config.CapacityBytes,
+		Size:      c.used,
+		Items:     items,
+		History:   c.eventLog.recent(20),
+		Details: map[string]interface{}{
+			"config":    c.config,
+			"now":       c.now,
+			"keys":      len(c.entries),
+			"volatile":  len(c.volatile),
+			"inflation": c.inflation,
+			"stats":     c.statsLocked(),
+		},
+	}
+}
+
+// Reset clears the cache, keeping its configuration
+func (c *SizedCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.clearLocked()
+}
+
+// getCurrentKeys returns resident keys, cheapest to evict first (must be called with lock held)
+func (c *SizedCache) getCurrentKeys() []string {
+	ordered := append([]*sizedEntry(nil), c.order...)
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].priority < ordered[j].priority })
+	keys := make([]string, len(ordered))
+	for i, item := range ordered {
+		keys[i] = item.key
+	}
+	return keys
+}
+
+// MaxSizedReplayMs bounds the virtual time a sized replay covers, since every
+// active expiry cycle in it runs (a day at Redis' 10 Hz is 864,000 cycles)
+const MaxSizedReplayMs = 24 * 60 * 60 * 1000
+
+// SizedWorkload gives the keys of a trace sizes, costs and TTLs for a
+// size-weighted replay
+type SizedWorkload struct {
+	CapacityBytes int  `json:"capacityBytes"`
+	MinSize       int  `json:"minSize"`    // Sizes are log-uniform between MinSize and MaxSize
+	MaxSize       int  `json:"maxSize"`    // bytes, so most objects are small and a few are huge
+	CostBySize    bool `json:"costBySize"` // Miss cost = size (optimizes byte hit ratio) instead of 1 (hit ratio)
+	TTLMs         int  `json:"ttlMs"`      // TTL of every object, 0 = none
+	StepMs        int  `json:"stepMs"`     // Virtual time between accesses
+}
+
+// SizedResult is how one eviction strategy did on a sized replay
+type SizedResult struct {
+	Eviction SizedEviction `json:"eviction"`
+	Stats    SizedStats    `json:"stats"`
+}
+
+// objectSize derives a key's size from its hash, so every replay (and every
+// strategy) sees the same object at the same size
+func objectSize(key string, minSize, maxSize int) int {
+	hash := fnv.New64a()
+	hash.Write([]byte(key))
+	u := float64(hash.Sum64()%1000000) / 1000000
+	return int(float64(minSize) * math.Pow(float64(maxSize)/float64(minSize), u))
+}
+
+// CompareSized replays the trace through a fresh SizedCache per eviction strategy
+func CompareSized(trace []string, workload SizedWorkload, evictions []SizedEviction) ([]SizedResult, error) {
+	if err := validateTrace(trace, workload.CapacityBytes); err != nil {
+		return nil, err
+	}
+	if workload.MinSize <= 0 || workload.MaxSize < workload.MinSize {
+		return nil, fmt.Errorf("sizes must satisfy 0 < minSize <= maxSize")
+	}
+	if workload.MaxSize > workload.CapacityBytes {
+		return nil, fmt.Errorf("maxSize must fit in capacityBytes")
+	}
+	if workload.TTLMs < 0 || workload.StepMs < 0 {
+		return nil, fmt.Errorf("ttlMs and stepMs must not be negative")
+	}
+	if int64(workload.StepMs)*int64(len(trace)) > MaxSizedReplayMs {
+		return nil, fmt.Errorf("stepMs times the trace length must not exceed %d ms of virtual time", MaxSizedReplayMs)
+	}
+	if len(evictions) == 0 {
+		evictions = SizedEvictions
+	}
+
+	ttl := time.Duration(workload.TTLMs) * time.Millisecond
+	step := time.Duration(workload.StepMs) * time.Millisecond
+	results := make([]SizedResult, 0, len(evictions))
+	for _, eviction := range evictions {
+		config := DefaultSizedConfig()
+		config.CapacityBytes = workload.CapacityBytes
+		config.Eviction = eviction
+		c, err := NewSizedCache(config)
+		if err != nil {
+			return nil, err
+		}
+		c.mute()
+
+		for _, key := range trace {
+			size := objectSize(key, workload.MinSize, workload.MaxSize)
+			cost := 1.0
+			if workload.CostBySize {
+				cost = float64(size)
+			}
+			if _, _, err := c.Access(key, size, cost, ttl); err != nil {
+				return nil, err
+			}
+			c.Advance(step)
+		}
+		results = append(results, SizedResult{Eviction: eviction, Stats: c.Stats()})
+	}
+	return results, nil
+}
@@ -0,0 +1,28 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompareSizedVirtualTimeLimit(t *testing.T) {
+	trace := []string{"a", "b", "c", "a", "b", "a"}
+	workload := SizedWorkload{CapacityBytes: 1000, MinSize: 10, MaxSize: 100, StepMs: MaxSizedReplayMs}
+	if _, err := CompareSized(trace, workload, nil); err == nil {
+		t.Error("replay spanning six days of virtual time accepted")
+	}
+
+	// Without TTLs the expiry cycles of a whole day are skipped, not run one by one
+	workload.StepMs = MaxSizedReplayMs / len(trace)
+	start := time.Now()
+	results, err := CompareSized(trace, workload, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("took %v", elapsed)
+	}
+	if cycles := results[0].Stats.ExpiryCycles; cycles != 864000 {
+		t.Errorf("ran %d expiry cycles, want 864000 (10 Hz for a day)", cycles)
+	}
+}
@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// SLRUCache implements Segmented LRU
+// New keys enter the probationary segment; a second access promotes them to
+// the protected segment (80% of capacity). When protected overflows, its LRU
+// key is demoted back to probation, so only keys in probation are ever evicted
+type SLRUCache struct {
+	mu           sync.RWMutex
+	capacity     int
+	protectedCap int
+	probation    *keyList
+	protected    *keyList
+	eventLog
+}
+
+func init() {
+	Register("slru", "SLRU (Segmented LRU)", func(capacity int) Policy { return NewSLRUCache(capacity) })
+}
+
+// NewSLRUCache creates a new SLRU cache with given capacity
+func NewSLRUCache(capacity int) *SLRUCache {
+	return &SLRUCache{
+		capacity:     capacity,
+		protectedCap: capacity * 4 / 5,
+		probation:    newKeyList(),
+		protected:    newKeyList(),
+	}
+}
+
+// Get retrieves a value; a hit in probation promotes the key
+func (c *SLRUCache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item := c.touch(key)
+	if item == nil {
+		c.eventLog.record("GET", key, "", false, "", c.getCurrentKeys)
+		return "", false
+	}
+
+	c.eventLog.record("GET", key, item.value, true, "", c.getCurrentKeys)
+	return item.value, true
+}
+
+// touch records an access to a resident key (must be called with lock held)
+func (c *SLRUCache) touch(key string) *entry {
+	if item, exists := c.protected.get(key); exists {
+		c.protected.moveToFront(key)
+		item.accessTime = time.Now()
+		return item
+	}
+
+	item := c.probation.remove(key)
+	if item == nil {
+		return nil
+	}
+	item.accessTime = time.Now()
+	if c.protectedCap == 0 {
+		c.probation.pushFront(item)
+		return item
+	}
+
+	c.protected.pushFront(item)
+	if c.protected.size() > c.protectedCap {
+		c.probation.pushFront(c.protected.popBack())
+	}
+	return item
+}
+
+// Put adds or updates a key-value pair
+func (c *SLRUCache) Put(key, value string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if item := c.touch(key); item != nil {
+		item.value = value
+		c.eventLog.record("PUT", key, value, true, "", c.getCurrentKeys)
+		return ""
+	}
+
+	evictedKey := ""
+	if c.probation.size()+c.protected.size() >= c.capacity {
+		victim := c.probation.popBack()
+		if victim == nil {
+			victim = c.protected.popBack()
+		}
+		evictedKey = victim.key
+	}
+	c.probation.pushFront(newEntry(key, value))
+
+	c.eventLog.record("PUT", key, value, false, evictedKey, c.getCurrentKeys)
+	return evictedKey
+}
+
+// GetState returns current cache state with both segments
+func (c *SLRUCache) GetState() CacheState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	items := c.protected.appendItems([]CacheItem{}, "protected")
+	items = c.probation.appendItems(items, "probation")
+
+	return CacheState{
+		Algorithm: "SLRU (Segmented LRU)",
+		Capacity:  c.capacity,
+		Size:      c.probation.size() + c.protected.size(),
+		Items:     items,
+		History:   c.eventLog.recent(20),
+		Segments: []CacheSegment{
+			c.protected.segment("protected", c.protectedCap, false),
+			c.probation.segment("probation", c.capacity-c.protectedCap, false),
+		},
+	}
+}
+
+// Reset clears the cache
+func (c *SLRUCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.probation = newKeyList()
+	c.protected = newKeyList()
+	c.eventLog.clear()
+}
+
+// getCurrentKeys returns resident keys, protected then probation (must be called with lock held)
+func (c *SLRUCache) getCurrentKeys() []string {
+	return append(c.protected.keys(), c.probation.keys()...)
+}
@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+)
+
+// hotHitsAfterScan warms the policy with a small hot set, replays a one-pass
+// scan of cold keys and reports how many hot keys still hit afterwards
+func hotHitsAfterScan(policy Policy, hot int) int {
+	for round := 0; round < 10; round++ {
+		for i := 0; i < hot; i++ {
+			access(policy, fmt.Sprintf("hot-%d", i))
+		}
+		// Fresh keys between rounds push the hot keys through any admission queue
+		for i := 0; i < hot; i++ {
+			access(policy, fmt.Sprintf("warm-%d-%d", round, i))
+		}
+	}
+	for i := 0; i < 100; i++ {
+		access(policy, fmt.Sprintf("scan-%d", i))
+	}
+
+	hits := 0
+	for i := 0; i < hot; i++ {
+		if _, hit := policy.Get(fmt.Sprintf("hot-%d", i)); hit {
+			hits++
+		}
+	}
+	return hits
+}
+
+func TestScanResistance(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		want   int
+	}{
+		{"lru", NewLRUCache(8), 0}, // Control: a scan longer than the cache flushes LRU
+		{"slru", NewSLRUCache(8), 4},
+		{"2q", NewTwoQCache(8), 4},
+	}
+	for _, tt := range tests {
+		if got := hotHitsAfterScan(tt.policy, 4); got != tt.want {
+			t.Errorf("%s: %d of 4 hot keys survived the scan, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSLRUPromotesOnSecondAccess(t *testing.T) {
+	c := NewSLRUCache(5) // 4 protected, 1 probation
+	segment := func(key string) string {
+		for _, item := range c.GetState().Items {
+			if item.Key == key {
+				return item.Segment
+			}
+		}
+		return ""
+	}
+
+	c.Put("a", "a")
+	if got := segment("a"); got != "probation" {
+		t.Errorf("new key in %q, want probation", got)
+	}
+	c.Get("a")
+	if got := segment("a"); got != "protected" {
+		t.Errorf("key accessed twice in %q, want protected", got)
+	}
+
+	// Overflowing protected demotes its LRU key instead of evicting it
+	for _, key := range []string{"b", "c", "d", "e"} {
+		c.Put(key, key)
+		c.Get(key)
+	}
+	if got := segment("a"); got != "probation" {
+		t.Errorf("LRU protected key in %q after overflow, want probation", got)
+	}
+	if evicted := c.Put("f", "f"); evicted != "a" {
+		t.Errorf("Put(f) evicted %q, want the demoted a", evicted)
+	}
+}
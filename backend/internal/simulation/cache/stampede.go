@@ -0,0 +1,458 @@
+package cache
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+)
+
+// StampedeMitigation is a defence against a cache stampede: the herd of
+// requests that all miss, and all hit the backing store, when a hot key expires
+type StampedeMitigation string
+
+const (
+	// Every request that misses recomputes the value itself
+	StampedeNone StampedeMitigation = "none"
+	// Requests missing on the same server wait for that server's single
+	// in-flight recompute (Go's singleflight); each server still recomputes
+	StampedeSingleflight StampedeMitigation = "singleflight"
+	// The first request to miss takes a lock in the cache and recomputes;
+	// the rest poll the cache every PollMs until the value reappears
+	StampedeLock StampedeMitigation = "lock"
+	// Probabilistic early expiration (Vattani et al.): each hit recomputes
+	// early with a probability that rises as expiry nears, so one request
+	// usually refreshes the key before the herd arrives
+	StampedeXFetch StampedeMitigation = "xfetch"
+	// Requests keep being served the old value for StaleMs after expiry
+	// while a single background refresh replaces it
+	StampedeSWR StampedeMitigation = "stale-while-revalidate"
+)
+
+// StampedeMitigations lists every mitigation in presentation order
+var StampedeMitigations = []StampedeMitigation{StampedeNone, StampedeSingleflight, StampedeLock, StampedeXFetch, StampedeSWR}
+
+// Limits on one simulation
+const (
+	MaxStampedeRequests  = 500000 // Requests generated
+	MaxStampedeKeys      = 10000  // Hot keys, each cached up front per mitigation
+	MaxStampedePollRatio = 100    // recomputeMs / pollMs: retries a lock loser may schedule while it waits
+)
+
+// StampedeConfig describes the traffic and the mitigations to compare
+type StampedeConfig struct {
+	Servers     int                  `json:"servers"`     // Application servers; singleflight coalesces within one
+	RequestRate float64              `json:"requestRate"` // Requests per second across all servers (Poisson arrivals)
+	Keys        int                  `json:"keys"`        // Hot keys, requested uniformly, all cached at time 0
+	TTLMs       int                  `json:"ttlMs"`
+	RecomputeMs int                  `json:"recomputeMs"` // Backing-store time to recompute a value
+	DurationMs  int                  `json:"durationMs"`
+	PollMs      int                  `json:"pollMs"`   // lock: retry interval of requests that lost the lock
+	Beta        float64              `json:"beta"`     // xfetch: > 1 recomputes earlier, < 1 later
+	StaleMs     int                  `json:"staleMs"`  // stale-while-revalidate: how long an expired value may be served
+	BucketMs    int                  `json:"bucketMs"` // Width of a series bucket
+	Seed        int64                `json:"seed"`
+	Mitigations []StampedeMitigation `json:"mitigations"` // Empty = all
+}
+
+// DefaultStampedeConfig returns one hot key read 1000 times a second by 4
+// servers, expiring every 2s and taking 200ms to recompute
+func DefaultStampedeConfig() StampedeConfig {
+	return StampedeConfig{
+		Servers:     4,
+		RequestRate: 1000,
+		Keys:        1,
+		TTLMs:       2000,
+		RecomputeMs: 200,
+		DurationMs:  10000,
+		PollMs:      50,
+		Beta:        1,
+		StaleMs:     1000,
+		BucketMs:    100,
+		Seed:        1,
+	}
+}
+
+// Validate checks the config
+func (c StampedeConfig) Validate() error {
+	if c.Servers <= 0 || c.Keys <= 0 {
+		return fmt.Errorf("servers and keys must be positive")
+	}
+	if c.Keys > MaxStampedeKeys {
+		return fmt.Errorf("keys must be at most %d", MaxStampedeKeys)
+	}
+	if c.RequestRate <= 0 {
+		return fmt.Errorf("requestRate must be positive")
+	}
+	if c.TTLMs <= 0 || c.RecomputeMs <= 0 || c.DurationMs <= 0 || c.PollMs <= 0 || c.BucketMs <= 0 {
+		return fmt.Errorf("ttlMs, recomputeMs, durationMs, pollMs and bucketMs must be positive")
+	}
+	if c.RecomputeMs/c.PollMs > MaxStampedePollRatio {
+		return fmt.Errorf("recomputeMs must be at most %d times pollMs", MaxStampedePollRatio)
+	}
+	if c.Beta <= 0 || c.StaleMs < 0 {
+		return fmt.Errorf("beta must be positive and staleMs not negative")
+	}
+	if c.RequestRate*float64(c.DurationMs)/1000 > MaxStampedeRequests {
+		return fmt.Errorf("requestRate x durationMs generates more than %d requests", MaxStampedeRequests)
+	}
+	if c.DurationMs/c.BucketMs > 1000 {
+		return fmt.Errorf("at most 1000 buckets are allowed")
+	}
+	for _, mitigation := range c.Mitigations {
+		known := false
+		for _, m := range StampedeMitigations {
+			known = known || m == mitigation
+		}
+		if !known {
+			return fmt.Errorf("unknown mitigation %q", mitigation)
+		}
+	}
+	return nil
+}
+
+// StampedeBucket is what happened during one bucket of the simulation
+type StampedeBucket struct {
+	StartMs      int `json:"startMs"`
+	Requests     int `json:"requests"`
+	Hits         int `json:"hits"`
+	Stale        int `json:"stale"`        // Served an expired value
+	StoreCalls   int `json:"storeCalls"`   // Recomputes started
+	PeakInFlight int `json:"peakInFlight"` // Most recomputes running at once
+}
+
+// StampedeLatency summarizes request latencies
+type StampedeLatency struct {
+	MeanMs float64 `json:"meanMs"`
+	P50Ms  float64 `json:"p50Ms"`
+	P99Ms  float64 `json:"p99Ms"`
+	MaxMs  float64 `json:"maxMs"`
+}
+
+// StampedeResult is how one mitigation handled the traffic
+type StampedeResult struct {
+	Mitigation      StampedeMitigation `json:"mitigation"`
+	Requests        int                `json:"requests"`
+	Hits            int                `json:"hits"`
+	StaleServed     int                `json:"staleServed"`
+	StoreCalls      int                `json:"storeCalls"`
+	PeakInFlight    int                `json:"peakInFlight"`    // Most recomputes hitting the store at once
+	PeakBucketCalls int                `json:"peakBucketCalls"` // Most recomputes started in one bucket
+	Latency         StampedeLatency    `json:"latency"`
+	Series          []StampedeBucket   `json:"series"`
+}
+
+// StampedeReport compares the mitigations on identical traffic
+type StampedeReport struct {
+	Config  StampedeConfig   `json:"config"`
+	Results []StampedeResult `json:"results"`
+}
+
+// stampedeArrival is one generated request
+type stampedeArrival struct {
+	at     float64
+	key    string
+	server int
+}
+
+// stampedeEventKind orders events at the same instant: completions first
+type stampedeEventKind int
+
+const (
+	stampedeDone stampedeEventKind = iota
+	stampedeRetry
+	stampedeArrive
+)
+
+// stampedeEvent is a scheduled step of the simulation
+type stampedeEvent struct {
+	at      float64
+	kind    stampedeEventKind
+	seq     int
+	request int    // arrive, retry and done with a waiting request (-1 = none)
+	key     string // done
+	server  int    // done
+}
+
+// stampedeQueue is the event queue, earliest first
+type stampedeQueue []stampedeEvent
+
+func (q stampedeQueue) Len() int { return len(q) }
+func (q stampedeQueue) Less(i, j int) bool {
+	if q[i].at != q[j].at {
+		return q[i].at < q[j].at
+	}
+	if q[i].kind != q[j].kind {
+		return q[i].kind < q[j].kind
+	}
+	return q[i].seq < q[j].seq
+}
+func (q stampedeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *stampedeQueue) Push(x interface{}) { *q = append(*q, x.(stampedeEvent)) }
+func (q *stampedeQueue) Pop() interface{} {
+	old := *q
+	e := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return e
+}
+
+// flightKey identifies one server's in-flight recompute of a key
+type flightKey struct {
+	server int
+	key    string
+}
+
+// stampedeSim runs one mitigation over the generated arrivals
+type stampedeSim struct {
+	config     StampedeConfig
+	mitigation StampedeMitigation
+	arrivals   []stampedeArrival
+	rng        *rand.Rand
+	cache      *LRUCache          // Holds the values
+	expires    map[string]float64 // TTLs, tracked beside the LRU
+	flights    map[flightKey][]int
+	locks      map[string]bool
+	refreshing map[string]bool
+	queue      stampedeQueue
+	seq        int
+	inFlight   int
+	latencies  []float64
+	result     StampedeResult
+}
+
+// SimulateStampede runs every requested mitigation on the same Poisson traffic
+func SimulateStampede(config StampedeConfig) (StampedeReport, error) {
+	if err := config.Validate(); err != nil {
+		return StampedeReport{}, err
+	}
+	if len(config.Mitigations) == 0 {
+		config.Mitigations = StampedeMitigations
+	}
+
+	rng := rand.New(rand.NewSource(config.Seed))
+	arrivals := []stampedeArrival{}
+	for at := rng.ExpFloat64() * 1000 / config.RequestRate; at < float64(config.DurationMs); at += rng.ExpFloat64() * 1000 / config.RequestRate {
+		arrivals = append(arrivals, stampedeArrival{
+			at:     at,
+			key:    traceKey(rng.Intn(config.Keys)),
+			server: rng.Intn(config.Servers),
+		})
+	}
+
+	report := StampedeReport{Config: config, Results: make([]StampedeResult, 0, len(config.Mitigations))}
+	for _, mitigation := range config.Mitigations {
+		sim := &stampedeSim{
+			config:     config,
+			mitigation: mitigation,
+			arrivals:   arrivals,
+			rng:        rand.New(rand.NewSource(config.Seed ^ seedSalt)),
+			cache:      NewLRUCache(config.Keys),
+			expires:    make(map[string]float64),
+			flights:    make(map[flightKey][]int),
+			locks:      make(map[string]bool),
+			refreshing: make(map[string]bool),
+		}
+		sim.cache.mute()
+		report.Results = append(report.Results, sim.run())
+	}
+	return report, nil
+}
+
+// run plays the arrivals through the mitigation
+func (s *stampedeSim) run() StampedeResult {
+	buckets := (s.config.DurationMs + s.config.BucketMs - 1) / s.config.BucketMs
+	s.result = StampedeResult{Mitigation: s.mitigation, Series: make([]StampedeBucket, buckets)}
+	for i := range s.result.Series {
+		s.result.Series[i].StartMs = i * s.config.BucketMs
+	}
+
+	// Every key starts freshly cached, so they all expire together
+	for i := 0; i < s.config.Keys; i++ {
+		key := traceKey(i)
+		s.cache.Put(key, "v0")
+		s.expires[key] = float64(s.config.TTLMs)
+	}
+
+	for i, arrival := range s.arrivals {
+		s.schedule(stampedeEvent{at: arrival.at, kind: stampedeArrive, request: i})
+	}
+
+	for s.queue.Len() > 0 {
+		event := heap.Pop(&s.queue).(stampedeEvent)
+		switch event.kind {
+		case stampedeArrive:
+			s.result.Requests++
+			s.bucket(event.at).Requests++
+			s.handle(event.request, event.at)
+		case stampedeRetry:
+			s.handle(event.request, event.at)
+		case stampedeDone:
+			s.finishRecompute(event)
+		}
+	}
+
+	for _, bucket := range s.result.Series {
+		s.result.PeakBucketCalls = max(s.result.PeakBucketCalls, bucket.StoreCalls)
+	}
+	s.result.Latency = summarizeLatencies(s.latencies)
+	return s.result
+}
+
+// schedule queues an event
+func (s *stampedeSim) schedule(event stampedeEvent) {
+	event.seq = s.seq
+	s.seq++
+	heap.Push(&s.queue, event)
+}
+
+// bucket returns the series bucket covering a time (the last one past the end)
+func (s *stampedeSim) bucket(at float64) *StampedeBucket {
+	i := min(int(at)/s.config.BucketMs, len(s.result.Series)-1)
+	return &s.result.Series[i]
+}
+
+// complete records a served request
+func (s *stampedeSim) complete(request int, at float64) {
+	s.latencies = append(s.latencies, at-s.arrivals[request].at)
+}
+
+// handle serves a request arriving (or retrying) at a time
+func (s *stampedeSim) handle(request int, at float64) {
+	arrival := s.arrivals[request]
+	key := arrival.key
+	_, cached := s.cache.Get(key)
+	fresh := cached && at < s.expires[key]
+
+	switch s.mitigation {
+	case StampedeXFetch:
+		// Recompute if now - delta*beta*ln(rand) >= expiry: always once
+		// expired, and with growing probability as expiry nears
+		early := float64(s.config.RecomputeMs) * s.config.Beta * -math.Log(1-s.rng.Float64())
+		if cached && at+early < s.expires[key] {
+			s.hit(request, at)
+			return
+		}
+		s.recompute(key, arrival.server, request, at)
+		return
+
+	case StampedeSWR:
+		if fresh {
+			s.hit(request, at)
+			return
+		}
+		if cached && at < s.expires[key]+float64(s.config.StaleMs) {
+			s.result.StaleServed++
+			s.bucket(at).Stale++
+			s.complete(request, at)
+			if !s.refreshing[key] {
+				s.refreshing[key] = true
+				s.recompute(key, arrival.server, -1, at)
+			}
+			return
+		}
+		// Too stale to serve: fetch like an unprotected cache
+		s.recompute(key, arrival.server, request, at)
+		return
+	}
+
+	if fresh {
+		s.hit(request, at)
+		return
+	}
+
+	switch s.mitigation {
+	case StampedeNone:
+		s.recompute(key, arrival.server, request, at)
+
+	case StampedeSingleflight:
+		flight := flightKey{server: arrival.server, key: key}
+		if waiters, inFlight := s.flights[flight]; inFlight {
+			s.flights[flight] = append(waiters, request)
+			return
+		}
+		s.flights[flight] = []int{request}
+		s.recompute(key, arrival.server, -1, at)
+
+	case StampedeLock:
+		if s.locks[key] {
+			s.schedule(stampedeEvent{at: at + float64(s.config.PollMs), kind: stampedeRetry, request: request})
+			return
+		}
+		s.locks[key] = true
+		s.recompute(key, arrival.server, request, at)
+	}
+}
+
+// hit serves a fresh value
+func (s *stampedeSim) hit(request int, at float64) {
+	s.result.Hits++
+	s.bucket(at).Hits++
+	s.complete(request, at)
+}
+
+// recompute starts a backing-store call that a request (or nobody, -1) waits for
+func (s *stampedeSim) recompute(key string, server, request int, at float64) {
+	s.result.StoreCalls++
+	s.inFlight++
+	s.result.PeakInFlight = max(s.result.PeakInFlight, s.inFlight)
+	bucket := s.bucket(at)
+	bucket.StoreCalls++
+	bucket.PeakInFlight = max(bucket.PeakInFlight, s.inFlight)
+
+	s.schedule(stampedeEvent{
+		at:      at + float64(s.config.RecomputeMs),
+		kind:    stampedeDone,
+		request: request,
+		key:     key,
+		server:  server,
+	})
+}
+
+// finishRecompute stores the new value and releases whoever waited for it
+func (s *stampedeSim) finishRecompute(event stampedeEvent) {
+	s.inFlight--
+	s.cache.Put(event.key, "v"+strconv.FormatFloat(event.at, 'f', 0, 64))
+	s.expires[event.key] = event.at + float64(s.config.TTLMs)
+
+	if event.request >= 0 {
+		s.complete(event.request, event.at)
+	}
+	switch s.mitigation {
+	case StampedeSingleflight:
+		flight := flightKey{server: event.server, key: event.key}
+		for _, waiter := range s.flights[flight] {
+			s.complete(waiter, event.at)
+		}
+		delete(s.flights, flight)
+	case StampedeLock:
+		delete(s.locks, event.key)
+	case StampedeSWR:
+		delete(s.refreshing, event.key)
+	}
+}
+
+// summarizeLatencies computes mean and nearest-rank percentiles
+func summarizeLatencies(latencies []float64) StampedeLatency {
+	if len(latencies) == 0 {
+		return StampedeLatency{}
+	}
+	sorted := append([]float64(nil), latencies...)
+	sort.Float64s(sorted)
+
+	total := 0.0
+	for _, latency := range sorted {
+		total += latency
+	}
+	rank := func(p float64) float64 {
+		i := int(math.Ceil(p*float64(len(sorted)))) - 1
+		return sorted[max(0, i)]
+	}
+	return StampedeLatency{
+		MeanMs: total / float64(len(sorted)),
+		P50Ms:  rank(0.50),
+		P99Ms:  rank(0.99),
+		MaxMs:  sorted[len(sorted)-1],
+	}
+}
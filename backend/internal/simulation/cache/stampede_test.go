@@ -0,0 +1,26 @@
+package cache
+
+import "testing"
+
+func TestStampedeConfigLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*StampedeConfig)
+		ok     bool
+	}{
+		{name: "default", modify: func(*StampedeConfig) {}, ok: true},
+		{name: "keys at limit", modify: func(c *StampedeConfig) { c.Keys = MaxStampedeKeys }, ok: true},
+		{name: "too many keys", modify: func(c *StampedeConfig) { c.Keys = 1e9 }},
+		{name: "poll ratio at limit", modify: func(c *StampedeConfig) { c.PollMs = 2; c.RecomputeMs = 2 * MaxStampedePollRatio }, ok: true},
+		{name: "recompute dwarfs poll", modify: func(c *StampedeConfig) { c.PollMs = 1; c.RecomputeMs = 1e9 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultStampedeConfig()
+			tt.modify(&config)
+			if err := config.Validate(); (err == nil) != tt.ok {
+				t.Errorf("Validate() = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+)
+
+// StoreConfig sets the simulated latencies behind a cache
+// Latencies are accounted, not slept, so replays stay instant
+type StoreConfig struct {
+	ReadLatencyMs  float64 `json:"readLatencyMs"`  // One backing-store read
+	WriteLatencyMs float64 `json:"writeLatencyMs"` // One backing-store write
+	CacheLatencyMs float64 `json:"cacheLatencyMs"` // One round trip to the cache
+}
+
+// DefaultStoreConfig models a database 10-20x slower than the cache in front of it
+func DefaultStoreConfig() StoreConfig {
+	return StoreConfig{
+		ReadLatencyMs:  10,
+		WriteLatencyMs: 20,
+		CacheLatencyMs: 1,
+	}
+}
+
+// Validate checks the config
+func (c StoreConfig) Validate() error {
+	if c.ReadLatencyMs < 0 || c.WriteLatencyMs < 0 || c.CacheLatencyMs < 0 {
+		return fmt.Errorf("latencies must not be negative")
+	}
+	return nil
+}
+
+// StoreStats counts the load on a backing store
+type StoreStats struct {
+	Reads  int     `json:"reads"`
+	Writes int     `json:"writes"`
+	BusyMs float64 `json:"busyMs"` // Total time spent serving reads and writes
+}
+
+// BackingStore is the durable database behind a cache
+// Its contents survive a cache crash
+type BackingStore struct {
+	mu     sync.RWMutex
+	config StoreConfig
+	data   map[string]string
+	stats  StoreStats
+}
+
+// NewBackingStore creates an empty store
+func NewBackingStore(config StoreConfig) *BackingStore {
+	return &BackingStore{
+		config: config,
+		data:   make(map[string]string),
+	}
+}
+
+// Read returns a key's value (empty if never written) and the time it took
+func (s *BackingStore) Read(key string) (string, float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats.Reads++
+	s.stats.BusyMs += s.config.ReadLatencyMs
+	return s.data[key], s.config.ReadLatencyMs
+}
+
+// Write stores a key's value and returns the time it took
+func (s *BackingStore) Write(key, value string) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data[key] = value
+	s.stats.Writes++
+	s.stats.BusyMs += s.config.WriteLatencyMs
+	return s.config.WriteLatencyMs
+}
+
+// Stats returns the load so far
+func (s *BackingStore) Stats() StoreStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.stats
+}
+
+// Snapshot returns a copy of the stored data
+func (s *BackingStore) Snapshot() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data := make(map[string]string, len(s.data))
+	for key, value := range s.data {
+		data[key] = value
+	}
+	return data
+}
+
+// Reset empties the store and its statistics
+func (s *BackingStore) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data = make(map[string]string)
+	s.stats = StoreStats{}
+}
@@ -0,0 +1,375 @@
+package cache
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// countMinSketch estimates access frequencies in fixed memory
+// Counters are 4 bits wide (saturating at 15) as in Caffeine
+type countMinSketch struct {
+	width      int
+	rows       [][]uint8
+	additions  int // Increments since the last aging
+	sampleSize int // Age (halve every counter) after this many increments
+}
+
+// sketchDepth is the number of hash rows in the sketch
+const sketchDepth = 4
+
+// maxCounter is the largest value a 4-bit counter holds
+const maxCounter = 15
+
+// newCountMinSketch sizes the sketch for a cache of the given capacity
+func newCountMinSketch(capacity int) *countMinSketch {
+	width := 1
+	for width < capacity {
+		width <<= 1
+	}
+	width = max(16, width)
+
+	rows := make([][]uint8, sketchDepth)
+	for i := range rows {
+		rows[i] = make([]uint8, width)
+	}
+	return &countMinSketch{width: width, rows: rows, sampleSize: 10 * max(1, capacity)}
+}
+
+// hashes returns two independent hashes of key for double hashing
+func hashes(key string) (uint32, uint32) {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	return uint32(sum), uint32(sum>>32) | 1
+}
+
+// slot returns the counter index of key in row i
+func (s *countMinSketch) slot(h1, h2 uint32, i int) int {
+	return int((h1 + uint32(i)*h2) % uint32(s.width))
+}
+
+// increment adds one to key's counters, aging the sketch once the sample is full
+// Returns true when this increment triggered aging
+func (s *countMinSketch) increment(key string) bool {
+	h1, h2 := hashes(key)
+	for i := range s.rows {
+		if j := s.slot(h1, h2, i); s.rows[i][j] < maxCounter {
+			s.rows[i][j]++
+		}
+	}
+
+	s.additions++
+	if s.additions < s.sampleSize {
+		return false
+	}
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.additions /= 2
+	return true
+}
+
+// estimate returns the minimum of key's counters
+func (s *countMinSketch) estimate(key string) int {
+	h1, h2 := hashes(key)
+	estimate := maxCounter
+	for i := range s.rows {
+		estimate = min(estimate, int(s.rows[i][s.slot(h1, h2, i)]))
+	}
+	return estimate
+}
+
+// doorkeeper is a small Bloom filter in front of the sketch: a key's first
+// access only sets its doorkeeper bits, so one-hit wonders never reach the sketch
+type doorkeeper struct {
+	bits []bool
+	set  int
+}
+
+// doorkeeperHashes is the number of bits each key sets
+const doorkeeperHashes = 2
+
+// newDoorkeeper creates a doorkeeper with the given number of bits
+func newDoorkeeper(size int) *doorkeeper {
+	return &doorkeeper{bits: make([]bool, size)}
+}
+
+// contains reports whether key may have been seen since the last reset
+func (d *doorkeeper) contains(key string) bool {
+	h1, h2 := hashes(key)
+	for i := 0; i < doorkeeperHashes; i++ {
+		if !d.bits[(h1+uint32(i)*h2)%uint32(len(d.bits))] {
+			return false
+		}
+	}
+	return true
+}
+
+// add sets key's bits
+func (d *doorkeeper) add(key string) {
+	h1, h2 := hashes(key)
+	for i := 0; i < doorkeeperHashes; i++ {
+		if j := (h1 + uint32(i)*h2) % uint32(len(d.bits)); !d.bits[j] {
+			d.bits[j] = true
+			d.set++
+		}
+	}
+}
+
+// clear resets every bit
+func (d *doorkeeper) clear() {
+	d.bits = make([]bool, len(d.bits))
+	d.set = 0
+}
+
+// WTinyLFUCache implements Window TinyLFU (Einziger, Friedman & Manes), the
+// policy behind Caffeine. New keys enter a small LRU window (1% of capacity).
+// When the window overflows, its victim competes with the main cache's
+// victim and is admitted only if the frequency sketch says it is used more
+// often. The main cache is an SLRU (80% protected). The sketch is aged
+// periodically so that frequencies follow a changing workload
+type WTinyLFUCache struct {
+	mu           sync.RWMutex
+	capacity     int
+	windowCap    int
+	protectedCap int
+	window       *keyList
+	probation    *keyList
+	protected    *keyList
+	sketch       *countMinSketch
+	doorkeeper   *doorkeeper
+	agings       int
+	admitted     int // Window victims that won admission to the main cache
+	rejected     int // Window victims that lost to the main cache's victim
+	// missedKey is the key whose Get just missed; the Put that fills it is the
+	// same logical access and is not counted again
+	missedKey string
+	missed    bool
+	eventLog
+}
+
+func init() {
+	Register("wtinylfu", "W-TinyLFU (Window TinyLFU)", func(capacity int) Policy { return NewWTinyLFUCache(capacity) })
+}
+
+// NewWTinyLFUCache creates a new W-TinyLFU cache with given capacity
+func NewWTinyLFUCache(capacity int) *WTinyLFUCache {
+	windowCap := max(1, capacity/100)
+	if capacity == 1 {
+		windowCap = 0
+	}
+	sketch := newCountMinSketch(capacity)
+	return &WTinyLFUCache{
+		capacity:     capacity,
+		windowCap:    windowCap,
+		protectedCap: (capacity - windowCap) * 4 / 5,
+		window:       newKeyList(),
+		probation:    newKeyList(),
+		protected:    newKeyList(),
+		sketch:       sketch,
+		doorkeeper:   newDoorkeeper(sketch.width * 2),
+	}
+}
+
+// recordAccess counts an access to key in the doorkeeper or sketch (must be called with lock held)
+func (c *WTinyLFUCache) recordAccess(key string) {
+	if !c.doorkeeper.contains(key) {
+		c.doorkeeper.add(key)
+		return
+	}
+	if c.sketch.increment(key) {
+		c.doorkeeper.clear()
+		c.agings++
+	}
+}
+
+// frequency estimates how often key has been used recently (must be called with lock held)
+func (c *WTinyLFUCache) frequency(key string) int {
+	estimate := c.sketch.estimate(key)
+	if c.doorkeeper.contains(key) {
+		estimate++
+	}
+	return estimate
+}
+
+// Get retrieves a value; every lookup, hit or miss, feeds the sketch
+func (c *WTinyLFUCache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.recordAccess(key)
+
+	item := c.touch(key)
+	c.missedKey, c.missed = key, item == nil
+	if item == nil {
+		c.eventLog.record("GET", key, "", false, "", c.getCurrentKeys)
+		return "", false
+	}
+
+	c.eventLog.record("GET", key, item.value, true, "", c.getCurrentKeys)
+	return item.value, true
+}
+
+// touch records a hit on a resident key, promoting it within the main SLRU
+// (must be called with lock held)
+func (c *WTinyLFUCache) touch(key string) *entry {
+	if item, exists := c.window.get(key); exists {
+		c.window.moveToFront(key)
+		item.accessTime = time.Now()
+		return item
+	}
+	if item, exists := c.protected.get(key); exists {
+		c.protected.moveToFront(key)
+		item.accessTime = time.Now()
+		return item
+	}
+
+	item := c.probation.remove(key)
+	if item == nil {
+		return nil
+	}
+	item.accessTime = time.Now()
+	if c.protectedCap == 0 {
+		c.probation.pushFront(item)
+		return item
+	}
+	c.protected.pushFront(item)
+	if c.protected.size() > c.protectedCap {
+		c.probation.pushFront(c.protected.popBack())
+	}
+	return item
+}
+
+// Put adds or updates a key-value pair; a Put filling the key whose Get
+// just missed is not counted as a second access
+func (c *WTinyLFUCache) Put(key, value string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.missed || c.missedKey != key {
+		c.recordAccess(key)
+	}
+	c.missedKey, c.missed = "", false
+
+	if item := c.touch(key); item != nil {
+		item.value = value
+		c.eventLog.record("PUT", key, value, true, "", c.getCurrentKeys)
+		return ""
+	}
+
+	evictedKey := ""
+	candidate := newEntry(key, value)
+	if c.windowCap > 0 {
+		c.window.pushFront(candidate)
+		candidate = nil
+		if c.window.size() > c.windowCap {
+			candidate = c.window.popBack()
+		}
+	}
+
+	if candidate != nil {
+		if c.probation.size()+c.protected.size() < c.capacity-c.windowCap {
+			c.probation.pushFront(candidate)
+		} else {
+			evictedKey = c.admit(candidate)
+		}
+	}
+
+	c.eventLog.record("PUT", key, value, false, evictedKey, c.getCurrentKeys)
+	return evictedKey
+}
+
+// admit lets the window's victim replace the main cache's victim if it is
+// used more often, returning whichever key was evicted (must be called with lock held)
+func (c *WTinyLFUCache) admit(candidate *entry) string {
+	victims := c.probation
+	if victims.size() == 0 {
+		victims = c.protected
+	}
+	victim := victims.back()
+
+	if c.frequency(candidate.key) > c.frequency(victim.key) {
+		victims.remove(victim.key)
+		c.probation.pushFront(candidate)
+		c.admitted++
+		return victim.key
+	}
+	c.rejected++
+	return candidate.key
+}
+
+// GetState returns current cache state with the window, main segments and sketch
+func (c *WTinyLFUCache) GetState() CacheState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	items := c.window.appendItems([]CacheItem{}, "window")
+	items = c.protected.appendItems(items, "protected")
+	items = c.probation.appendItems(items, "probation")
+	for i := range items {
+		items[i].Frequency = c.frequency(items[i].Key)
+	}
+
+	details := map[string]interface{}{
+		"sketchWidth":    c.sketch.width,
+		"sketchDepth":    sketchDepth,
+		"sampleSize":     c.sketch.sampleSize,
+		"additions":      c.sketch.additions,
+		"agings":         c.agings,
+		"doorkeeperBits": len(c.doorkeeper.bits),
+		"doorkeeperSet":  c.doorkeeper.set,
+		"admitted":       c.admitted,
+		"rejected":       c.rejected,
+	}
+	if c.sketch.width <= 64 {
+		// Small sketches are shown counter by counter
+		rows := make([][]int, len(c.sketch.rows))
+		for i, row := range c.sketch.rows {
+			rows[i] = make([]int, len(row))
+			for j, counter := range row {
+				rows[i][j] = int(counter)
+			}
+		}
+		details["sketch"] = rows
+	}
+
+	return CacheState{
+		Algorithm: "W-TinyLFU (Window TinyLFU)",
+		Capacity:  c.capacity,
+		Size:      c.window.size() + c.probation.size() + c.protected.size(),
+		Items:     items,
+		History:   c.eventLog.recent(20),
+		Segments: []CacheSegment{
+			c.window.segment("window", c.windowCap, false),
+			c.protected.segment("protected", c.protectedCap, false),
+			c.probation.segment("probation", c.capacity-c.windowCap-c.protectedCap, false),
+		},
+		Details: details,
+	}
+}
+
+// Reset clears the cache and its frequency sketch
+func (c *WTinyLFUCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.window = newKeyList()
+	c.probation = newKeyList()
+	c.protected = newKeyList()
+	c.sketch = newCountMinSketch(c.capacity)
+	c.doorkeeper = newDoorkeeper(c.sketch.width * 2)
+	c.agings = 0
+	c.admitted = 0
+	c.rejected = 0
+	c.missedKey, c.missed = "", false
+	c.eventLog.clear()
+}
+
+// getCurrentKeys returns resident keys: window, protected, then probation (must be called with lock held)
+func (c *WTinyLFUCache) getCurrentKeys() []string {
+	keys := append(c.window.keys(), c.protected.keys()...)
+	return append(keys, c.probation.keys()...)
+}
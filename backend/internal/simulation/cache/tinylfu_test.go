@@ -0,0 +1,70 @@
+package cache
+
+import "testing"
+
+func TestWTinyLFUCountsDemandFillOnce(t *testing.T) {
+	c := NewWTinyLFUCache(100)
+
+	// A miss followed by the Put that fills it is one access: the key only
+	// reaches the doorkeeper, not the sketch
+	access(c, "once")
+	if got := c.sketch.estimate("once"); got != 0 {
+		t.Errorf("sketch estimate after one access = %d, want 0", got)
+	}
+	if !c.doorkeeper.contains("once") {
+		t.Error("doorkeeper does not contain the accessed key")
+	}
+
+	// A second access, a hit, reaches the sketch
+	access(c, "once")
+	if got := c.sketch.estimate("once"); got != 1 {
+		t.Errorf("sketch estimate after two accesses = %d, want 1", got)
+	}
+
+	// A Put without a preceding miss is an access of its own
+	c.Put("direct", "v")
+	c.Put("direct", "w")
+	if got := c.frequency("direct"); got != 2 {
+		t.Errorf("frequency after two Puts = %d, want 2", got)
+	}
+}
+
+func TestWTinyLFUAdmission(t *testing.T) {
+	c := NewWTinyLFUCache(1) // No window: every new key competes with the resident one
+	details := func() map[string]interface{} { return c.GetState().Details }
+
+	for i := 0; i < 5; i++ {
+		access(c, "hot")
+	}
+
+	// "new" is rejected while it has been seen no more often than "hot"
+	for i := 1; i <= 5; i++ {
+		if access(c, "new") {
+			t.Fatalf("access %d of new hit", i)
+		}
+		if _, hit := c.Get("hot"); !hit {
+			t.Fatalf("hot evicted after %d accesses of new", i)
+		}
+	}
+	if got := details()["rejected"]; got != 5 {
+		t.Errorf("rejected = %v, want 5", got)
+	}
+
+	// The Gets of hot above raised its frequency too; new is admitted by
+	// the access that makes it the more frequent key
+	for i := 0; c.frequency("new") <= c.frequency("hot"); i++ {
+		if i == 30 {
+			t.Fatal("new never overtook hot")
+		}
+		access(c, "new")
+	}
+	if _, hit := c.Get("new"); !hit {
+		t.Error("more frequent candidate was not admitted")
+	}
+	if _, hit := c.Get("hot"); hit {
+		t.Error("less frequent victim was kept")
+	}
+	if got := details()["admitted"]; got != 1 {
+		t.Errorf("admitted = %v, want 1", got)
+	}
+}
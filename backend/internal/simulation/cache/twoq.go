@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// TwoQCache implements the full 2Q policy (Johnson & Shasha)
+// New keys enter A1in, a small FIFO. Keys evicted from A1in are remembered in
+// the ghost queue A1out; only a key requested again while in A1out is judged
+// hot and admitted to Am, the main LRU. One-off scans therefore pass through
+// A1in without flushing Am
+type TwoQCache struct {
+	mu       sync.RWMutex
+	capacity int
+	kin      int      // Max size of A1in
+	kout     int      // Max size of A1out
+	a1in     *keyList // FIFO of recently added keys
+	a1out    *keyList // Ghosts evicted from A1in
+	am       *keyList // LRU of keys seen again after leaving A1in
+	eventLog
+}
+
+func init() {
+	Register("2q", "2Q (Two Queue)", func(capacity int) Policy { return NewTwoQCache(capacity) })
+}
+
+// NewTwoQCache creates a new 2Q cache with given capacity
+// A1in holds 25% of the capacity and A1out remembers 50% of it, as in the paper
+func NewTwoQCache(capacity int) *TwoQCache {
+	return &TwoQCache{
+		capacity: capacity,
+		kin:      max(1, capacity/4),
+		kout:     max(1, capacity/2),
+		a1in:     newKeyList(),
+		a1out:    newKeyList(),
+		am:       newKeyList(),
+	}
+}
+
+// Get retrieves a value; hits in Am refresh recency, hits in A1in do not
+func (c *TwoQCache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item := c.lookup(key)
+	if item == nil {
+		c.eventLog.record("GET", key, "", false, "", c.getCurrentKeys)
+		return "", false
+	}
+
+	c.eventLog.record("GET", key, item.value, true, "", c.getCurrentKeys)
+	return item.value, true
+}
+
+// lookup finds a resident key and records the access (must be called with lock held)
+func (c *TwoQCache) lookup(key string) *entry {
+	if item, exists := c.am.get(key); exists {
+		c.am.moveToFront(key)
+		item.accessTime = time.Now()
+		return item
+	}
+	if item, exists := c.a1in.get(key); exists {
+		// Correlated references while in A1in do not make a key hot
+		item.accessTime = time.Now()
+		return item
+	}
+	return nil
+}
+
+// Put adds or updates a key-value pair
+func (c *TwoQCache) Put(key, value string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if item := c.lookup(key); item != nil {
+		item.value = value
+		c.eventLog.record("PUT", key, value, true, "", c.getCurrentKeys)
+		return ""
+	}
+
+	// Check A1out before reclaiming, which may push the key off its tail
+	hot := c.a1out.remove(key) != nil
+
+	evictedKey := ""
+	if c.a1in.size()+c.am.size() >= c.capacity {
+		evictedKey = c.reclaim()
+	}
+
+	if hot {
+		// Requested again after leaving A1in: hot
+		c.am.pushFront(newEntry(key, value))
+	} else {
+		c.a1in.pushFront(newEntry(key, value))
+	}
+
+	c.eventLog.record("PUT", key, value, false, evictedKey, c.getCurrentKeys)
+	return evictedKey
+}
+
+// reclaim frees one slot, preferring A1in once it exceeds its share
+// (must be called with lock held)
+func (c *TwoQCache) reclaim() string {
+	if c.a1in.size() > c.kin || c.am.size() == 0 {
+		victim := c.a1in.popBack()
+		c.a1out.pushFront(&entry{key: victim.key})
+		if c.a1out.size() > c.kout {
+			c.a1out.popBack()
+		}
+		return victim.key
+	}
+	return c.am.popBack().key
+}
+
+// GetState returns current cache state, including the A1out ghost queue
+func (c *TwoQCache) GetState() CacheState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	items := c.a1in.appendItems([]CacheItem{}, "A1in")
+	items = c.am.appendItems(items, "Am")
+
+	return CacheState{
+		Algorithm: "2Q (Two Queue)",
+		Capacity:  c.capacity,
+		Size:      c.a1in.size() + c.am.size(),
+		Items:     items,
+		History:   c.eventLog.recent(20),
+		Segments: []CacheSegment{
+			c.a1in.segment("A1in", c.kin, false),
+			c.a1out.segment("A1out", c.kout, true),
+			c.am.segment("Am", 0, false),
+		},
+	}
+}
+
+// Reset clears the cache
+func (c *TwoQCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.a1in = newKeyList()
+	c.a1out = newKeyList()
+	c.am = newKeyList()
+	c.eventLog.clear()
+}
+
+// getCurrentKeys returns resident keys, A1in then Am (must be called with lock held)
+func (c *TwoQCache) getCurrentKeys() []string {
+	return append(c.a1in.keys(), c.am.keys()...)
+}
@@ -0,0 +1,19 @@
+package cache
+
+import "testing"
+
+func TestTwoQPromotesGhostAtTail(t *testing.T) {
+	c := NewTwoQCache(4) // A1in holds 1, A1out remembers 2
+	for _, key := range []string{"a", "b", "c", "d", "e", "f"} {
+		c.Put(key, key)
+	}
+	// a is now the oldest ghost in a full A1out; reclaiming a slot for it
+	// pushes c in at the head
+	c.Put("a", "a")
+
+	for _, item := range c.GetState().Items {
+		if item.Key == "a" && item.Segment != "Am" {
+			t.Errorf("a requested again from A1out went to %s, want Am", item.Segment)
+		}
+	}
+}
@@ -9,16 +9,33 @@ type CacheItem struct {
 	Frequency  int       `json:"frequency,omitempty"`  // For LFU
 	InsertTime time.Time `json:"insertTime,omitempty"` // For FIFO
 	AccessTime time.Time `json:"accessTime"`
-	Position   int       `json:"position"` // Position in cache (for ordering)
+	Position   int       `json:"position"`             // Position in cache (for ordering)
+	Segment    string    `json:"segment,omitempty"`    // Internal list holding the item (ARC, 2Q, SLRU, W-TinyLFU, CLOCK-Pro)
+	Referenced bool      `json:"referenced,omitempty"` // Reference bit (CLOCK, CLOCK-Pro)
+
+	// Size-weighted caches only
+	Size      int        `json:"size,omitempty"`      // Bytes
+	Cost      float64    `json:"cost,omitempty"`      // Cost of a miss
+	Priority  float64    `json:"priority,omitempty"`  // Eviction priority, lowest first (GreedyDual's H)
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"` // TTL deadline
+}
+
+// CacheSegment is one internal list of a policy, e.g. ARC's T1 or a ghost list
+type CacheSegment struct {
+	Name     string   `json:"name"`
+	Keys     []string `json:"keys"` // Front = next to be kept longest (most recent first)
+	Capacity int      `json:"capacity,omitempty"`
+	Ghost    bool     `json:"ghost,omitempty"` // Remembers evicted keys only, holds no values
 }
 
 // AccessEvent represents a cache access operation
 type AccessEvent struct {
-	Operation  string    `json:"operation"`  // "GET" or "PUT"
+	Operation  string    `json:"operation"`  // "GET", "PUT", "EXPIRE" or "REMOVE"
 	Key        string    `json:"key"`
 	Value      string    `json:"value"`
 	Hit        bool      `json:"hit"`        // true if cache hit, false if miss
 	EvictedKey string    `json:"evictedKey,omitempty"` // Key that was evicted (if any)
+	Evicted    []string  `json:"evicted,omitempty"`    // Every evicted key, when one put evicted several
 	Timestamp  time.Time `json:"timestamp"`
 	CacheSize  int       `json:"cacheSize"`  // Cache size after operation
 	CacheItems []string  `json:"cacheItems"` // Current cache keys
@@ -31,5 +48,10 @@ type CacheState struct {
 	Size      int           `json:"size"`
 	Items     []CacheItem   `json:"items"`
 	History   []AccessEvent `json:"history"`
+
+	// Internal structure of the more advanced policies
+	Segments  []CacheSegment         `json:"segments,omitempty"`
+	ClockHand *int                   `json:"clockHand,omitempty"` // Slot the CLOCK hand points at
+	Details   map[string]interface{} `json:"details,omitempty"`   // Policy-specific parameters (e.g. ARC's target p)
 }
 
@@ -0,0 +1,293 @@
+package cache
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TracePattern selects how a cache access trace is generated
+type TracePattern string
+
+const (
+	// Every key equally likely
+	TraceUniform TracePattern = "uniform"
+	// Key i requested with probability proportional to 1/i^skew
+	TraceZipf TracePattern = "zipf"
+	// Zipf whose popular keys change every phaseLength accesses (stale frequencies hurt LFU)
+	TraceShifting TracePattern = "shifting"
+	// A small hot set interrupted by sequential scans over cold keys (scans pollute LRU)
+	TraceScan TracePattern = "scan"
+	// The same sequence of loopLength keys over and over (LRU misses every time once it exceeds capacity)
+	TraceLoop TracePattern = "loop"
+	// A trace supplied by the caller
+	TraceUpload TracePattern = "upload"
+)
+
+// seedSalt derives a second random stream from a seed that already generated a trace
+const seedSalt = 0x5DEECE66D
+
+// MaxTraceKeys bounds the key space of a generated trace
+const MaxTraceKeys = 100000
+
+// TraceConfig describes a generated trace
+type TraceConfig struct {
+	Pattern     TracePattern `json:"pattern"`
+	Length      int          `json:"length"`      // Accesses to generate
+	Keys        int          `json:"keys"`        // Size of the key space
+	Skew        float64      `json:"skew"`        // zipf/shifting: exponent (default 1.0)
+	PhaseLength int          `json:"phaseLength"` // shifting: accesses before the popular keys change
+	HotKeys     int          `json:"hotKeys"`     // scan: size of the hot set
+	ScanLength  int          `json:"scanLength"`  // scan: cold keys read by each scan
+	ScanEvery   int          `json:"scanEvery"`   // scan: hot-set accesses between scans
+	LoopLength  int          `json:"loopLength"`  // loop: keys in the loop
+	Seed        int64        `json:"seed"`        // 0 = fixed default seed, so traces are reproducible
+}
+
+// traceKey names the i-th key of a generated trace
+func traceKey(i int) string {
+	return "k" + strconv.Itoa(i)
+}
+
+// zipfSampler draws ranks 0..n-1 with probability proportional to 1/(rank+1)^skew
+// Unlike rand.Zipf it accepts any positive skew, including the common s < 1
+type zipfSampler struct {
+	cdf []float64
+}
+
+// newZipfSampler precomputes the cumulative distribution
+func newZipfSampler(n int, skew float64) *zipfSampler {
+	cdf := make([]float64, n)
+	total := 0.0
+	for i := range cdf {
+		total += 1 / math.Pow(float64(i+1), skew)
+		cdf[i] = total
+	}
+	for i := range cdf {
+		cdf[i] /= total
+	}
+	return &zipfSampler{cdf: cdf}
+}
+
+// sample draws one rank
+func (z *zipfSampler) sample(rng *rand.Rand) int {
+	i := sort.SearchFloat64s(z.cdf, rng.Float64())
+	return min(i, len(z.cdf)-1)
+}
+
+// GenerateTrace builds an access trace from the config
+func GenerateTrace(config TraceConfig) ([]string, error) {
+	if config.Length <= 0 {
+		return nil, fmt.Errorf("length must be positive")
+	}
+	if config.Length > MaxTraceLength {
+		return nil, fmt.Errorf("length must be at most %d", MaxTraceLength)
+	}
+	if config.Keys <= 0 {
+		config.Keys = 1000
+	}
+	if config.Keys > MaxTraceKeys {
+		return nil, fmt.Errorf("keys must be at most %d", MaxTraceKeys)
+	}
+	if config.Skew <= 0 {
+		config.Skew = 1.0
+	}
+	seed := config.Seed
+	if seed == 0 {
+		seed = 1
+	}
+	rng := rand.New(rand.NewSource(seed))
+
+	trace := make([]string, 0, config.Length)
+	switch config.Pattern {
+	case TraceUniform:
+		for len(trace) < config.Length {
+			trace = append(trace, traceKey(rng.Intn(config.Keys)))
+		}
+
+	case TraceZipf:
+		zipf := newZipfSampler(config.Keys, config.Skew)
+		for len(trace) < config.Length {
+			trace = append(trace, traceKey(zipf.sample(rng)))
+		}
+
+	case TraceShifting:
+		if config.PhaseLength <= 0 {
+			config.PhaseLength = max(1, config.Length/4)
+		}
+		// Each phase rotates the ranking so a different part of the key space is popular
+		zipf := newZipfSampler(config.Keys, config.Skew)
+		shift := max(1, config.Keys/3)
+		for len(trace) < config.Length {
+			phase := len(trace) / config.PhaseLength
+			rank := zipf.sample(rng)
+			trace = append(trace, traceKey((rank+phase*shift)%config.Keys))
+		}
+
+	case TraceScan:
+		if config.HotKeys <= 0 {
+			config.HotKeys = max(1, config.Keys/10)
+		}
+		if config.ScanLength <= 0 {
+			config.ScanLength = config.Keys - config.HotKeys
+		}
+		if config.ScanEvery <= 0 {
+			config.ScanEvery = config.ScanLength
+		}
+		if config.HotKeys >= config.Keys {
+			return nil, fmt.Errorf("hotKeys must be smaller than keys")
+		}
+		// Hot keys are k0..k(hot-1); scans walk the cold keys in order, resuming where the last scan stopped
+		cold := config.Keys - config.HotKeys
+		next := 0
+		for len(trace) < config.Length {
+			for i := 0; i < config.ScanEvery && len(trace) < config.Length; i++ {
+				trace = append(trace, traceKey(rng.Intn(config.HotKeys)))
+			}
+			for i := 0; i < config.ScanLength && len(trace) < config.Length; i++ {
+				trace = append(trace, traceKey(config.HotKeys+next))
+				next = (next + 1) % cold
+			}
+		}
+
+	case TraceLoop:
+		if config.LoopLength <= 0 {
+			config.LoopLength = config.Keys
+		}
+		for len(trace) < config.Length {
+			trace = append(trace, traceKey(len(trace)%config.LoopLength))
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown trace pattern %q", config.Pattern)
+	}
+	return trace, nil
+}
+
+// ParseTrace reads an uploaded trace: one access per line, the key being the
+// first comma- or whitespace-separated field. Blank lines and lines starting
+// with # are skipped
+func ParseTrace(r io.Reader) ([]string, error) {
+	trace := []string{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("line %d: no key", line)
+		}
+		trace = append(trace, fields[0])
+		if len(trace) > MaxTraceLength {
+			return nil, fmt.Errorf("line %d: trace has more than %d accesses", line, MaxTraceLength)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(trace) == 0 {
+		return nil, fmt.Errorf("trace is empty")
+	}
+	return trace, nil
+}
+
+// HitRatioPoint is one sample of a policy's hit ratio during a replay
+type HitRatioPoint struct {
+	Access     int     `json:"access"`     // Accesses replayed so far
+	Window     float64 `json:"window"`     // Hit ratio over the accesses since the previous point
+	Cumulative float64 `json:"cumulative"` // Hit ratio since the start of the trace
+}
+
+// WorkloadResult is one policy's outcome and hit ratio over time
+type WorkloadResult struct {
+	PolicyResult
+	Series []HitRatioPoint `json:"series"`
+}
+
+// WorkloadReport is a trace replayed through every policy and OPT
+type WorkloadReport struct {
+	Capacity   int              `json:"capacity"`
+	Accesses   int              `json:"accesses"`
+	UniqueKeys int              `json:"uniqueKeys"`
+	Window     int              `json:"window"` // Accesses per series point
+	OPT        WorkloadResult   `json:"opt"`
+	Policies   []WorkloadResult `json:"policies"`
+}
+
+// DefaultSeriesPoints is how many points a workload series has unless asked otherwise
+const DefaultSeriesPoints = 50
+
+// replaySeries runs the trace through the policy, sampling the hit ratio every window accesses
+func replaySeries(policy Policy, trace []string, window int) (int, []HitRatioPoint) {
+	hits, windowHits := 0, 0
+	series := make([]HitRatioPoint, 0, len(trace)/window+1)
+	for i, key := range trace {
+		if access(policy, key) {
+			hits++
+			windowHits++
+		}
+		if done := i + 1; done%window == 0 || done == len(trace) {
+			since := done - len(series)*window
+			series = append(series, HitRatioPoint{
+				Access:     done,
+				Window:     float64(windowHits) / float64(since),
+				Cumulative: float64(hits) / float64(done),
+			})
+			windowHits = 0
+		}
+	}
+	return hits, series
+}
+
+// RunWorkload replays the trace through fresh instances of the policies and
+// OPT, returning hit-ratio-over-time series sampled at up to points points
+func RunWorkload(trace []string, capacity int, policies []string, points int) (WorkloadReport, error) {
+	if err := validateTrace(trace, capacity); err != nil {
+		return WorkloadReport{}, err
+	}
+	if points <= 0 {
+		points = DefaultSeriesPoints
+	}
+	window := max(1, (len(trace)+points-1)/points)
+
+	unique := make(map[string]struct{})
+	for _, key := range trace {
+		unique[key] = struct{}{}
+	}
+
+	opt := NewOPTCache(capacity, trace)
+	opt.mute()
+	optHits, optSeries := replaySeries(opt, trace, window)
+
+	report := WorkloadReport{
+		Capacity:   capacity,
+		Accesses:   len(trace),
+		UniqueKeys: len(unique),
+		Window:     window,
+		OPT: WorkloadResult{
+			PolicyResult: newResult("opt", "OPT (Belady's MIN)", optHits, len(trace), optHits),
+			Series:       optSeries,
+		},
+		Policies: make([]WorkloadResult, 0, len(policies)),
+	}
+
+	for _, key := range policies {
+		policy, err := newReplayPolicy(key, capacity)
+		if err != nil {
+			return WorkloadReport{}, err
+		}
+		hits, series := replaySeries(policy, trace, window)
+		report.Policies = append(report.Policies, WorkloadResult{
+			PolicyResult: newResult(key, policyName(key), hits, len(trace), optHits),
+			Series:       series,
+		})
+	}
+	return report, nil
+}
@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTrace(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{name: "one key per line", input: "a\nb\na\n", want: []string{"a", "b", "a"}},
+		{name: "first field", input: "a,1,x\nb 2\nc\t3", want: []string{"a", "b", "c"}},
+		{name: "comments and blank lines", input: "# header\n\n  a  \n#b\nc", want: []string{"a", "c"}},
+		{name: "separators only", input: "a\n,\nb", wantErr: true},
+		{name: "mixed separators only", input: ", \t", wantErr: true},
+		{name: "empty", input: "\n# nothing\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTrace(strings.NewReader(tt.input))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseTrace(%q) = %v, want error", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTrace(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,474 @@
+package cache
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// WritePolicy is how a cache and its backing store handle reads and writes
+type WritePolicy string
+
+const (
+	// The application reads the cache, loads from the store itself on a miss
+	// and fills the cache, unless the store has no such key; writes go to the
+	// store and invalidate the cache
+	CacheAside WritePolicy = "cache-aside"
+	// The cache loads from the store itself on a miss, saving the application
+	// a round trip and remembering absent keys too; writes go to the store
+	// through the cache, which refreshes a cached copy instead of dropping it
+	ReadThrough WritePolicy = "read-through"
+	// Writes update the cache and the store before being acknowledged
+	WriteThrough WritePolicy = "write-through"
+	// Writes update only the cache and mark the entry dirty; the store is
+	// written when a dirty entry is evicted or flushed, so a crash loses it
+	WriteBack WritePolicy = "write-back"
+	// Writes go only to the store, invalidating any cached copy, so write-once
+	// data never displaces anything from the cache; reads load through the cache
+	WriteAround WritePolicy = "write-around"
+)
+
+// WritePolicies lists every write policy in presentation order
+var WritePolicies = []WritePolicy{CacheAside, ReadThrough, WriteThrough, WriteBack, WriteAround}
+
+// WriteStats reports how a write policy did
+type WriteStats struct {
+	Reads          int        `json:"reads"`
+	Writes         int        `json:"writes"`
+	Hits           int        `json:"hits"`
+	Misses         int        `json:"misses"`
+	HitRatio       float64    `json:"hitRatio"`
+	Store          StoreStats `json:"store"`
+	StoreLoad      int        `json:"storeLoad"`      // Store reads + writes
+	WriteBacks     int        `json:"writeBacks"`     // Dirty entries written on eviction or flush
+	ReadLatencyMs  float64    `json:"readLatencyMs"`  // Mean latency the application saw per read
+	WriteLatencyMs float64    `json:"writeLatencyMs"` // Mean latency the application saw per write
+	Dirty          int        `json:"dirty"`          // Entries a crash right now would lose
+	Crashes        int        `json:"crashes"`
+	LostKeys       int        `json:"lostKeys"`   // Keys whose latest write was lost in a crash
+	LostWrites     int        `json:"lostWrites"` // Acknowledged writes never persisted
+	StaleReads     int        `json:"staleReads"` // Reads that did not return the latest acknowledged write
+}
+
+// WriteCache is a cache in front of a backing store under one write policy
+// Residency and eviction come from a registered eviction policy
+type WriteCache struct {
+	mu       sync.Mutex
+	mode     WritePolicy
+	cache    Policy
+	store    *BackingStore
+	config   StoreConfig
+	resident map[string]bool
+	invalid  map[string]bool   // Resident but invalidated: never served, refilled on the next read
+	dirty    map[string]int    // Key -> acknowledged writes not yet in the store
+	latest   map[string]string // Latest acknowledged write per key, to detect stale reads
+	stats    WriteStats
+	readMs   float64
+	writeMs  float64
+}
+
+// NewWriteCache creates a write-policy cache over an empty store
+func NewWriteCache(mode WritePolicy, policyKey string, capacity int, config StoreConfig) (*WriteCache, error) {
+	known := false
+	for _, policy := range WritePolicies {
+		known = known || policy == mode
+	}
+	if !known {
+		return nil, fmt.Errorf("unknown write policy %q", mode)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	cache, err := NewPolicy(policyKey, capacity)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &WriteCache{
+		mode:   mode,
+		cache:  cache,
+		store:  NewBackingStore(config),
+		config: config,
+	}
+	c.clearLocked()
+	return c, nil
+}
+
+// clearLocked forgets everything but the configuration (must be called with lock held)
+func (c *WriteCache) clearLocked() {
+	c.cache.Reset()
+	c.store.Reset()
+	c.resident = make(map[string]bool)
+	c.invalid = make(map[string]bool)
+	c.dirty = make(map[string]int)
+	c.latest = make(map[string]string)
+	c.stats = WriteStats{}
+	c.readMs = 0
+	c.writeMs = 0
+}
+
+// Policy returns the write policy
+func (c *WriteCache) Policy() WritePolicy {
+	return c.mode
+}
+
+// mute stops the underlying policy recording history (used for replays)
+func (c *WriteCache) mute() {
+	if m, ok := c.cache.(muter); ok {
+		m.mute()
+	}
+}
+
+// Read returns a key's value as the application sees it, and whether the cache served it
+func (c *WriteCache) Read(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stats.Reads++
+	latency := c.config.CacheLatencyMs
+
+	value, hit := "", false
+	if !c.invalid[key] {
+		value, hit = c.cache.Get(key)
+	}
+
+	if hit {
+		c.stats.Hits++
+	} else {
+		c.stats.Misses++
+		var loadMs float64
+		value, loadMs = c.store.Read(key)
+		latency += loadMs
+		switch {
+		case c.mode != CacheAside:
+			// The cache loads the key itself, caching an absent key as empty
+			latency += c.fill(key, value, false)
+		case value != "":
+			// The application fills the cache itself: one more round trip.
+			// The store returns "" for a key it does not hold, which it skips
+			latency += c.fill(key, value, false)
+			latency += c.config.CacheLatencyMs
+		}
+	}
+
+	if value != c.latest[key] {
+		c.stats.StaleReads++
+	}
+	c.readMs += latency
+	return value, hit
+}
+
+// Write stores a value under the configured policy
+func (c *WriteCache) Write(key, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stats.Writes++
+	c.latest[key] = value
+	latency := c.config.CacheLatencyMs
+
+	switch c.mode {
+	case CacheAside, WriteAround:
+		latency += c.store.Write(key, value)
+		if c.resident[key] {
+			c.invalid[key] = true
+		}
+	case ReadThrough:
+		latency += c.store.Write(key, value)
+		if c.resident[key] {
+			latency += c.fill(key, value, false)
+		}
+	case WriteThrough:
+		latency += c.fill(key, value, false)
+		latency += c.store.Write(key, value)
+	case WriteBack:
+		latency += c.fill(key, value, true)
+	}
+	c.writeMs += latency
+}
+
+// fill puts a value in the cache, writing back a dirty victim, and returns the
+// store time that took (must be called with lock held)
+func (c *WriteCache) fill(key, value string, dirty bool) float64 {
+	latency := 0.0
+	evicted := c.cache.Put(key, value)
+	if evicted == key {
+		// The eviction policy did not admit the key (W-TinyLFU, OPT), so it is
+		// not cached and a write-back write has to go to the store now
+		delete(c.resident, key)
+		delete(c.invalid, key)
+		if dirty {
+			latency += c.store.Write(key, value)
+			delete(c.dirty, key)
+		}
+		return latency
+	}
+	if evicted != "" {
+		delete(c.resident, evicted)
+		delete(c.invalid, evicted)
+		if c.dirty[evicted] > 0 {
+			// Flush on eviction: the victim's value is the latest acknowledged write
+			latency += c.store.Write(evicted, c.latest[evicted])
+			c.stats.WriteBacks++
+			delete(c.dirty, evicted)
+		}
+	}
+	c.resident[key] = true
+	delete(c.invalid, key)
+	if dirty {
+		c.dirty[key]++
+	}
+	return latency
+}
+
+// Flush writes every dirty entry to the store, returning how many were written
+func (c *WriteCache) Flush() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := c.dirtyKeys()
+	for _, key := range keys {
+		c.store.Write(key, c.latest[key])
+		c.stats.WriteBacks++
+		delete(c.dirty, key)
+	}
+	return len(keys)
+}
+
+// Crash loses the cache's contents; the store survives. Returns the keys
+// whose latest writes were lost
+func (c *WriteCache) Crash() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	lost := c.dirtyKeys()
+	for _, key := range lost {
+		c.stats.LostWrites += c.dirty[key]
+	}
+	c.stats.LostKeys += len(lost)
+	c.stats.Crashes++
+
+	c.cache.Reset()
+	c.resident = make(map[string]bool)
+	c.invalid = make(map[string]bool)
+	c.dirty = make(map[string]int)
+	return lost
+}
+
+// dirtyKeys returns the dirty keys in sorted order (must be called with lock held)
+func (c *WriteCache) dirtyKeys() []string {
+	keys := make([]string, 0, len(c.dirty))
+	for key := range c.dirty {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Stats returns the statistics so far
+func (c *WriteCache) Stats() WriteStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.statsLocked()
+}
+
+// statsLocked fills in the derived statistics (must be called with lock held)
+func (c *WriteCache) statsLocked() WriteStats {
+	stats := c.stats
+	stats.Store = c.store.Stats()
+	stats.StoreLoad = stats.Store.Reads + stats.Store.Writes
+	stats.Dirty = len(c.dirty)
+	if stats.Reads > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(stats.Reads)
+		stats.ReadLatencyMs = c.readMs / float64(stats.Reads)
+	}
+	if stats.Writes > 0 {
+		stats.WriteLatencyMs = c.writeMs / float64(stats.Writes)
+	}
+	return stats
+}
+
+// WriteCacheState is the visualization state of one write-policy cache
+type WriteCacheState struct {
+	Policy  WritePolicy       `json:"policy"`
+	Cache   CacheState        `json:"cache"`
+	Store   map[string]string `json:"store"`
+	Dirty   []string          `json:"dirty"`
+	Invalid []string          `json:"invalid"`
+	Stats   WriteStats        `json:"stats"`
+}
+
+// GetState returns the cache, store and statistics
+func (c *WriteCache) GetState() WriteCacheState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	invalid := make([]string, 0, len(c.invalid))
+	for key := range c.invalid {
+		invalid = append(invalid, key)
+	}
+	sort.Strings(invalid)
+
+	return WriteCacheState{
+		Policy:  c.mode,
+		Cache:   c.cache.GetState(),
+		Store:   c.store.Snapshot(),
+		Dirty:   c.dirtyKeys(),
+		Invalid: invalid,
+		Stats:   c.statsLocked(),
+	}
+}
+
+// Reset empties the cache and the store
+func (c *WriteCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.clearLocked()
+}
+
+// WriteSimulator runs every write policy side by side, each with its own store
+type WriteSimulator struct {
+	mu        sync.RWMutex
+	policyKey string
+	capacity  int
+	config    StoreConfig
+	caches    []*WriteCache
+}
+
+// WriteSimulatorConfig configures a WriteSimulator
+type WriteSimulatorConfig struct {
+	Policy   string      `json:"policy"` // Eviction policy of every cache
+	Capacity int         `json:"capacity"`
+	Store    StoreConfig `json:"store"`
+}
+
+// newWriteCaches creates one cache per write policy (used by simulator and replays)
+func newWriteCaches(config WriteSimulatorConfig) ([]*WriteCache, error) {
+	caches := make([]*WriteCache, 0, len(WritePolicies))
+	for _, mode := range WritePolicies {
+		c, err := NewWriteCache(mode, config.Policy, config.Capacity, config.Store)
+		if err != nil {
+			return nil, err
+		}
+		caches = append(caches, c)
+	}
+	return caches, nil
+}
+
+// NewWriteSimulator creates a simulator with an LRU cache of the given capacity per policy
+func NewWriteSimulator(capacity int, config StoreConfig) (*WriteSimulator, error) {
+	s := &WriteSimulator{}
+	if err := s.Configure(WriteSimulatorConfig{Policy: "lru", Capacity: capacity, Store: config}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Configure replaces every cache and store with fresh, empty ones
+func (s *WriteSimulator) Configure(config WriteSimulatorConfig) error {
+	caches, err := newWriteCaches(config)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policyKey = config.Policy
+	s.capacity = config.Capacity
+	s.config = config.Store
+	s.caches = caches
+	return nil
+}
+
+// Config returns the current configuration
+func (s *WriteSimulator) Config() WriteSimulatorConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return WriteSimulatorConfig{Policy: s.policyKey, Capacity: s.capacity, Store: s.config}
+}
+
+// Caches returns one cache per write policy, in WritePolicies order
+func (s *WriteSimulator) Caches() []*WriteCache {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]*WriteCache(nil), s.caches...)
+}
+
+// States returns the state of every write policy keyed by policy
+func (s *WriteSimulator) States() map[WritePolicy]WriteCacheState {
+	states := make(map[WritePolicy]WriteCacheState)
+	for _, c := range s.Caches() {
+		states[c.mode] = c.GetState()
+	}
+	return states
+}
+
+// Reset empties every cache and store
+func (s *WriteSimulator) Reset() {
+	for _, c := range s.Caches() {
+		c.Reset()
+	}
+}
+
+// WriteReport is how one write policy did on a replayed workload
+type WriteReport struct {
+	Policy WritePolicy `json:"policy"`
+	Stats  WriteStats  `json:"stats"`
+}
+
+// WriteWorkload turns a key trace into reads and writes
+type WriteWorkload struct {
+	WriteRatio float64 `json:"writeRatio"` // Fraction of accesses that are writes
+	CrashAt    int     `json:"crashAt"`    // Crash the caches after this many accesses (0 = never)
+	Seed       int64   `json:"seed"`
+}
+
+// CompareWritePolicies replays the trace through every write policy; each
+// access is a write with probability WriteRatio (the same accesses are writes
+// for every policy) and a read otherwise
+func CompareWritePolicies(trace []string, workload WriteWorkload, config WriteSimulatorConfig) ([]WriteReport, error) {
+	if err := validateTrace(trace, config.Capacity); err != nil {
+		return nil, err
+	}
+	if workload.WriteRatio < 0 || workload.WriteRatio > 1 {
+		return nil, fmt.Errorf("writeRatio must be between 0 and 1")
+	}
+	if workload.CrashAt < 0 || workload.CrashAt > len(trace) {
+		return nil, fmt.Errorf("crashAt must be between 0 and the trace length")
+	}
+	caches, err := newWriteCaches(config)
+	if err != nil {
+		return nil, err
+	}
+
+	seed := workload.Seed
+	if seed == 0 {
+		seed = 1
+	}
+	// Salted so the write decisions are not the same random stream that generated the trace
+	rng := rand.New(rand.NewSource(seed ^ seedSalt))
+	writes := make([]bool, len(trace))
+	for i := range writes {
+		writes[i] = rng.Float64() < workload.WriteRatio
+	}
+
+	reports := make([]WriteReport, 0, len(caches))
+	for _, c := range caches {
+		c.mute()
+		for i, key := range trace {
+			if writes[i] {
+				c.Write(key, "v"+strconv.Itoa(i))
+			} else {
+				c.Read(key)
+			}
+			if i+1 == workload.CrashAt {
+				c.Crash()
+			}
+		}
+		reports = append(reports, WriteReport{Policy: c.mode, Stats: c.Stats()})
+	}
+	return reports, nil
+}
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestWriteCacheTracksOnlyAdmittedKeys(t *testing.T) {
+	trace, err := GenerateTrace(TraceConfig{Pattern: TraceZipf, Length: 5000, Keys: 500, Skew: 0.8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, info := range RegisteredPolicies() {
+		for _, mode := range WritePolicies {
+			// At capacity 1 W-TinyLFU has no window, so it can turn the missed key away
+			for _, capacity := range []int{1, 20} {
+				t.Run(info.Key+"/"+string(mode)+"/"+strconv.Itoa(capacity), func(t *testing.T) {
+					c, err := NewWriteCache(mode, info.Key, capacity, DefaultStoreConfig())
+					if err != nil {
+						t.Fatal(err)
+					}
+					c.mute()
+					for i, key := range trace {
+						if i%3 == 0 {
+							c.Write(key, "v"+strconv.Itoa(i))
+						} else {
+							c.Read(key)
+						}
+					}
+
+					cached := make(map[string]bool)
+					for _, item := range c.cache.GetState().Items {
+						cached[item.Key] = true
+					}
+					for key := range c.resident {
+						if !cached[key] {
+							t.Errorf("%s is marked resident but is not cached", key)
+						}
+					}
+					for key := range c.dirty {
+						if !cached[key] {
+							t.Errorf("%s is dirty but is not cached", key)
+						}
+					}
+					if stale := c.Stats().StaleReads; stale != 0 && mode != WriteBack {
+						t.Errorf("%d stale reads", stale)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestWritePoliciesDiffer(t *testing.T) {
+	trace, err := GenerateTrace(TraceConfig{Pattern: TraceZipf, Length: 5000, Keys: 500, Skew: 1.0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reports, err := CompareWritePolicies(trace, WriteWorkload{WriteRatio: 0.2}, WriteSimulatorConfig{Policy: "lru", Capacity: 50, Store: DefaultStoreConfig()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storeReads := make(map[WritePolicy]int)
+	for _, report := range reports {
+		storeReads[report.Policy] = report.Stats.Store.Reads
+	}
+	pairs := [][2]WritePolicy{{CacheAside, ReadThrough}, {CacheAside, WriteAround}, {ReadThrough, WriteAround}}
+	for _, pair := range pairs {
+		if storeReads[pair[0]] == storeReads[pair[1]] {
+			t.Errorf("%s and %s both read the store %d times", pair[0], pair[1], storeReads[pair[0]])
+		}
+	}
+}
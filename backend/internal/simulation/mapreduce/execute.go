@@ -0,0 +1,494 @@
+package mapreduce
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ShuffleMode selects where intermediate data lives between map and reduce
+type ShuffleMode string
+
+const (
+	ShuffleMemory   ShuffleMode = "memory"   // Map output stays in memory and each reducer sorts its share
+	ShuffleExternal ShuffleMode = "external" // Map output is spilled to sorted run files that reducers merge
+)
+
+// Limits on concurrent execution
+const (
+	MaxExecWorkers   = 256
+	MaxGenerateBytes = 64 << 20
+	MaxExecOutput    = 1000 // Output pairs returned; the rest are only counted
+	maxExecTasks     = 2000 // Task timings returned
+)
+
+// ExecConfig controls concurrent execution
+type ExecConfig struct {
+	Workers     int         `json:"workers"`    // Goroutines running tasks at once
+	SplitBytes  int         `json:"splitBytes"` // Maximum input split, cut at a line or word boundary; one map task per split
+	Shuffle     ShuffleMode `json:"shuffle"`
+	SpillBytes  int         `json:"spillBytes"`  // Map output buffered before a sorted run is spilled (external shuffle)
+	MergeFactor int         `json:"mergeFactor"` // Most runs a reducer merges at once; more need extra passes
+	TempDir     string      `json:"-"`           // Parent of the spill directory; empty uses the system default
+}
+
+// DefaultExecConfig uses one worker per CPU, 64 KiB splits and an external shuffle spilling every 256 KiB
+func DefaultExecConfig() ExecConfig {
+	return ExecConfig{
+		Workers:     runtime.GOMAXPROCS(0),
+		SplitBytes:  64 << 10,
+		Shuffle:     ShuffleExternal,
+		SpillBytes:  256 << 10,
+		MergeFactor: 16,
+	}
+}
+
+// Validate checks the config
+func (c ExecConfig) Validate() error {
+	if c.Workers < 1 || c.Workers > MaxExecWorkers {
+		return fmt.Errorf("workers must be between 1 and %d", MaxExecWorkers)
+	}
+	if c.SplitBytes < 64 {
+		return fmt.Errorf("splitBytes must be at least 64")
+	}
+	if c.Shuffle != ShuffleMemory && c.Shuffle != ShuffleExternal {
+		return fmt.Errorf("shuffle must be memory or external")
+	}
+	if c.Shuffle == ShuffleExternal && c.SpillBytes < 1024 {
+		return fmt.Errorf("spillBytes must be at least 1024")
+	}
+	if c.MergeFactor < 2 {
+		return fmt.Errorf("mergeFactor must be at least 2")
+	}
+	return nil
+}
+
+// ExecTask is the measured run of one task
+type ExecTask struct {
+	ID          string   `json:"id"`
+	Kind        TaskKind `json:"kind"`
+	Worker      int      `json:"worker"` // Goroutine that ran the task
+	InputID     string   `json:"inputId,omitempty"`
+	InputBytes  int      `json:"inputBytes"`
+	InputPairs  int64    `json:"inputPairs"` // Reduce tasks: pairs merged
+	OutputPairs int64    `json:"outputPairs"`
+	Spills      int      `json:"spills"`      // Map tasks: sorted runs written
+	MergePasses int      `json:"mergePasses"` // Reduce tasks: passes over the runs
+	StartMs     float64  `json:"startMs"`
+	EndMs       float64  `json:"endMs"`
+}
+
+// ExecStats summarizes a concurrent run
+type ExecStats struct {
+	InputBytes     int64 `json:"inputBytes"`
+	Splits         int   `json:"splits"`
+	MapOutputPairs int64 `json:"mapOutputPairs"` // Emitted by map functions
+	ShuffledPairs  int64 `json:"shuffledPairs"`  // Reached reducers, after combining
+	SpillFiles     int64 `json:"spillFiles"`
+	SpilledBytes   int64 `json:"spilledBytes"`
+	MergePasses    int   `json:"mergePasses"` // Most passes any reducer needed
+	OutputPairs    int64 `json:"outputPairs"`
+	PeakWorkers    int64 `json:"peakWorkers"` // Most tasks observed running at once
+}
+
+// ExecResult is the outcome of a concurrent run with real timings
+type ExecResult struct {
+	Config      ExecConfig `json:"config"`
+	JobType     string     `json:"jobType"`
+	Reducers    int        `json:"reducers"`
+	Combine     bool       `json:"combine"`
+	Partitioner string     `json:"partitioner"`
+	MapMs       float64    `json:"mapMs"`
+	ReduceMs    float64    `json:"reduceMs"`
+	TotalMs     float64    `json:"totalMs"`
+	MBPerSecond float64    `json:"mbPerSecond"` // Input throughput
+	Stats       ExecStats  `json:"stats"`
+	Tasks       []ExecTask `json:"tasks"`
+	Output      []KeyValue `json:"output"` // First MaxExecOutput pairs in key order
+	Truncated   bool       `json:"truncated"`
+}
+
+// inputSplit is the input of one map task
+type inputSplit struct {
+	inputID string
+	text    string
+}
+
+// executor holds the shared state of one concurrent run
+type executor struct {
+	job      preparedJob
+	config   ExecConfig
+	dir      string
+	start    time.Time
+	reducers int
+
+	runs      [][]string     // Per reducer, run files (external shuffle)
+	memory    [][][]KeyValue // Per reducer, map outputs (memory shuffle)
+	runsMu    sync.Mutex
+	fileCount int64
+	running   int64
+
+	stats ExecStats
+	err   error
+	errMu sync.Mutex
+}
+
+// Execute runs a job with map and reduce tasks on a bounded pool of goroutines
+// Unlike Job, it holds no lock while tasks run and is not limited to small inputs;
+// map tasks follow the input splits, so NumMappers is ignored
+func Execute(job JobConfig, config ExecConfig) (ExecResult, error) {
+	if err := config.Validate(); err != nil {
+		return ExecResult{}, err
+	}
+	prepared, err := prepareJob(job)
+	if err != nil {
+		return ExecResult{}, err
+	}
+
+	e := &executor{
+		job:      prepared,
+		config:   config,
+		start:    time.Now(),
+		reducers: prepared.config.NumReducers,
+		runs:     make([][]string, prepared.config.NumReducers),
+		memory:   make([][][]KeyValue, prepared.config.NumReducers),
+	}
+	if config.Shuffle == ShuffleExternal {
+		e.dir, err = os.MkdirTemp(config.TempDir, "mapreduce-shuffle-")
+		if err != nil {
+			return ExecResult{}, err
+		}
+		defer os.RemoveAll(e.dir)
+	}
+
+	splits := splitInput(prepared.documents, config.SplitBytes)
+	for _, split := range splits {
+		e.stats.InputBytes += int64(len(split.text))
+	}
+	e.stats.Splits = len(splits)
+
+	// Map phase: every reduce task needs all map output, so the phases do not overlap
+	mapTasks := make([]ExecTask, len(splits))
+	e.runPool(len(splits), func(index, worker int) {
+		mapTasks[index] = e.runMapTask(index, worker, splits[index])
+	})
+	mapEnd := time.Now()
+	if e.err != nil {
+		return ExecResult{}, e.err
+	}
+
+	reduceTasks := make([]ExecTask, e.reducers)
+	outputs := make([][]KeyValue, e.reducers)
+	e.runPool(e.reducers, func(index, worker int) {
+		reduceTasks[index], outputs[index] = e.runReduceTask(index, worker)
+	})
+	end := time.Now()
+	if e.err != nil {
+		return ExecResult{}, e.err
+	}
+
+	result := ExecResult{
+		Config:      config,
+		JobType:     prepared.info.Key,
+		Reducers:    e.reducers,
+		Combine:     prepared.config.Combine,
+		Partitioner: prepared.config.Partitioner,
+		MapMs:       millisSince(e.start, mapEnd),
+		ReduceMs:    millisSince(mapEnd, end),
+		TotalMs:     millisSince(e.start, end),
+		Output:      []KeyValue{},
+	}
+	if result.TotalMs > 0 {
+		result.MBPerSecond = float64(e.stats.InputBytes) / (1 << 20) / (result.TotalMs / 1000)
+	}
+
+	for _, task := range reduceTasks {
+		e.stats.ShuffledPairs += task.InputPairs
+		e.stats.OutputPairs += task.OutputPairs
+		if task.MergePasses > e.stats.MergePasses {
+			e.stats.MergePasses = task.MergePasses
+		}
+	}
+	result.Stats = e.stats
+
+	tasks := append(mapTasks, reduceTasks...)
+	if len(tasks) > maxExecTasks {
+		tasks = tasks[:maxExecTasks]
+	}
+	result.Tasks = tasks
+
+	for _, output := range outputs {
+		result.Output = append(result.Output, output...)
+	}
+	sort.Slice(result.Output, func(a, b int) bool { return result.Output[a].Key < result.Output[b].Key })
+	if len(result.Output) > MaxExecOutput {
+		result.Output = result.Output[:MaxExecOutput]
+		result.Truncated = true
+	}
+	return result, nil
+}
+
+// runPool runs count tasks on the configured number of goroutines pulling from a channel
+func (e *executor) runPool(count int, run func(index, worker int)) {
+	tasks := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < e.config.Workers; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for index := range tasks {
+				if e.failed() {
+					continue
+				}
+				e.trackRunning(1)
+				run(index, worker)
+				e.trackRunning(-1)
+			}
+		}(worker)
+	}
+	for index := 0; index < count; index++ {
+		tasks <- index
+	}
+	close(tasks)
+	wg.Wait()
+}
+
+// trackRunning counts tasks in flight and records the peak
+func (e *executor) trackRunning(delta int64) {
+	now := atomic.AddInt64(&e.running, delta)
+	for {
+		peak := atomic.LoadInt64(&e.stats.PeakWorkers)
+		if now <= peak || atomic.CompareAndSwapInt64(&e.stats.PeakWorkers, peak, now) {
+			return
+		}
+	}
+}
+
+// fail records the first error; remaining tasks are skipped
+func (e *executor) fail(err error) {
+	e.errMu.Lock()
+	defer e.errMu.Unlock()
+	if e.err == nil {
+		e.err = err
+	}
+}
+
+// failed reports whether any task has failed
+func (e *executor) failed() bool {
+	e.errMu.Lock()
+	defer e.errMu.Unlock()
+	return e.err != nil
+}
+
+// runMapTask maps one split, spilling sorted runs per reducer when the buffer fills
+func (e *executor) runMapTask(index, worker int, split inputSplit) ExecTask {
+	task := ExecTask{
+		ID:         fmt.Sprintf("m%d", index),
+		Kind:       TaskMap,
+		Worker:     worker,
+		InputID:    split.inputID,
+		InputBytes: len(split.text),
+		StartMs:    millisSince(e.start, time.Now()),
+	}
+
+	buffer := make([][]KeyValue, e.reducers)
+	buffered := 0
+	emitted := int64(0)
+	spill := func() {
+		for r, pairs := range buffer {
+			if len(pairs) == 0 {
+				continue
+			}
+			if e.job.config.Combine {
+				pairs = combinePairs(pairs, e.job.functions.Combine)
+			} else {
+				sort.SliceStable(pairs, func(a, b int) bool { return pairs[a].Key < pairs[b].Key })
+			}
+			task.OutputPairs += int64(len(pairs))
+
+			if e.config.Shuffle == ShuffleMemory {
+				e.runsMu.Lock()
+				e.memory[r] = append(e.memory[r], pairs)
+				e.runsMu.Unlock()
+				continue
+			}
+
+			path := filepath.Join(e.dir, fmt.Sprintf("map-%d-r%d.run", index, atomic.AddInt64(&e.fileCount, 1)))
+			size, err := writeRun(path, pairs)
+			if err != nil {
+				e.fail(err)
+				return
+			}
+			atomic.AddInt64(&e.stats.SpillFiles, 1)
+			atomic.AddInt64(&e.stats.SpilledBytes, size)
+			e.runsMu.Lock()
+			e.runs[r] = append(e.runs[r], path)
+			e.runsMu.Unlock()
+		}
+		if e.config.Shuffle == ShuffleExternal {
+			task.Spills++
+		}
+		buffer = make([][]KeyValue, e.reducers)
+		buffered = 0
+	}
+
+	e.job.functions.Map(split.inputID, split.text, func(key, value string) {
+		r := e.job.partition(key, e.reducers)
+		buffer[r] = append(buffer[r], KeyValue{Key: key, Value: value})
+		buffered += len(key) + len(value) + 32 // Rough per-pair overhead
+		emitted++
+		if e.config.Shuffle == ShuffleExternal && buffered >= e.config.SpillBytes {
+			spill()
+		}
+	})
+	if buffered > 0 {
+		spill()
+	}
+	atomic.AddInt64(&e.stats.MapOutputPairs, emitted)
+	task.EndMs = millisSince(e.start, time.Now())
+	return task
+}
+
+// runReduceTask merges one reducer's share of map output and applies the reduce function
+func (e *executor) runReduceTask(index, worker int) (ExecTask, []KeyValue) {
+	task := ExecTask{
+		ID:      fmt.Sprintf("r%d", index),
+		Kind:    TaskReduce,
+		Worker:  worker,
+		StartMs: millisSince(e.start, time.Now()),
+	}
+
+	var output []KeyValue
+	currentKey := ""
+	var values []string
+	flush := func() {
+		if len(values) == 0 {
+			return
+		}
+		if value, keep := e.job.functions.Reduce(currentKey, values); keep {
+			output = append(output, KeyValue{Key: currentKey, Value: value})
+		}
+		values = nil
+	}
+	// Pairs arrive sorted, so each key's values are contiguous
+	consume := func(pair KeyValue) error {
+		task.InputPairs++
+		if len(values) > 0 && pair.Key != currentKey {
+			flush()
+		}
+		currentKey = pair.Key
+		values = append(values, pair.Value)
+		return nil
+	}
+
+	if e.config.Shuffle == ShuffleMemory {
+		var pairs []KeyValue
+		for _, chunk := range e.memory[index] {
+			pairs = append(pairs, chunk...)
+		}
+		sort.SliceStable(pairs, func(a, b int) bool { return pairs[a].Key < pairs[b].Key })
+		for _, pair := range pairs {
+			consume(pair)
+		}
+	} else {
+		passes, err := externalMerge(e.runs[index], e.dir, e.config.MergeFactor, &e.fileCount, consume)
+		if err != nil {
+			e.fail(err)
+		}
+		task.MergePasses = passes
+	}
+	flush()
+
+	task.OutputPairs = int64(len(output))
+	task.EndMs = millisSince(e.start, time.Now())
+	return task, output
+}
+
+// splitInput cuts documents into splits of at most splitBytes, preferring line and then word boundaries
+// Splits of a document that needed cutting are named by byte offset ("doc3@65536"), as Hadoop keys
+// records by offset, so per-input keys such as grep's line numbers stay unique
+func splitInput(documents []string, splitBytes int) []inputSplit {
+	var splits []inputSplit
+	for i, document := range documents {
+		inputID := fmt.Sprintf("doc%d", i+1)
+		if len(document) <= splitBytes {
+			splits = append(splits, inputSplit{inputID: inputID, text: document})
+			continue
+		}
+		for offset := 0; offset < len(document); {
+			end := offset + splitBytes
+			if end >= len(document) {
+				end = len(document)
+			} else if cut := strings.LastIndexByte(document[offset:end], '\n'); cut > 0 {
+				end = offset + cut + 1
+			} else if cut := strings.LastIndexAny(document[offset:end], " \t"); cut > 0 {
+				end = offset + cut + 1
+			}
+			splits = append(splits, inputSplit{inputID: fmt.Sprintf("%s@%d", inputID, offset), text: document[offset:end]})
+			offset = end
+		}
+	}
+	return splits
+}
+
+// millisSince returns the milliseconds between two instants
+func millisSince(from, to time.Time) float64 {
+	return float64(to.Sub(from).Microseconds()) / 1000
+}
+
+// GenerateCorpus produces about size bytes of Zipf-distributed pseudo-words in 256 KiB documents
+// Lines are occasionally tagged "error" or "warn" so grep has matches
+func GenerateCorpus(size int, seed int64) ([]string, error) {
+	if size <= 0 || size > MaxGenerateBytes {
+		return nil, fmt.Errorf("size must be between 1 and %d bytes", MaxGenerateBytes)
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	syllables := []string{"ka", "lo", "mi", "re", "tu", "sa", "no", "vi", "de", "po", "ra", "zu"}
+	vocabulary := make([]string, 5000)
+	for i := range vocabulary {
+		word := ""
+		for n := i; ; n /= len(syllables) {
+			word += syllables[n%len(syllables)]
+			if n < len(syllables) {
+				break
+			}
+		}
+		vocabulary[i] = word
+	}
+	zipf := rand.NewZipf(rng, 1.1, 1, uint64(len(vocabulary)-1))
+
+	const documentBytes = 256 << 10
+	var documents []string
+	var b strings.Builder
+	total := 0
+	for total < size {
+		line := 0
+		if rng.Intn(50) == 0 {
+			b.WriteString("error ")
+		} else if rng.Intn(20) == 0 {
+			b.WriteString("warn ")
+		}
+		for line < 80 {
+			word := vocabulary[zipf.Uint64()]
+			b.WriteString(word)
+			b.WriteByte(' ')
+			line += len(word) + 1
+		}
+		b.WriteByte('\n')
+		total += line + 1
+
+		if b.Len() >= documentBytes || total >= size {
+			documents = append(documents, b.String())
+			b.Reset()
+		}
+	}
+	return documents, nil
+}
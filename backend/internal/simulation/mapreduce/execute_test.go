@@ -0,0 +1,64 @@
+package mapreduce
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExecuteShufflesAgree(t *testing.T) {
+	corpus, err := GenerateCorpus(64<<10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		job  JobConfig
+	}{
+		{"wordcount", JobConfig{JobType: "wordcount", Documents: corpus, NumMappers: 1, NumReducers: 3, Partitioner: "hash"}},
+		{"wordcount combined", JobConfig{JobType: "wordcount", Documents: corpus, NumMappers: 1, NumReducers: 3, Combine: true, Partitioner: "hash"}},
+		{"wordcount range", JobConfig{JobType: "wordcount", Documents: corpus, NumMappers: 1, NumReducers: 4, Partitioner: "range"}},
+		{"grep", JobConfig{JobType: "grep", Documents: corpus, NumMappers: 1, NumReducers: 2, Partitioner: "hash"}},
+		{"invertedindex sample", JobConfig{JobType: "invertedindex", NumMappers: 1, NumReducers: 2, Partitioner: "first-letter"}},
+	}
+	for _, tt := range tests {
+		memory := DefaultExecConfig()
+		memory.Workers = 4
+		memory.SplitBytes = 4 << 10
+		memory.Shuffle = ShuffleMemory
+
+		// Small spills and a merge factor of 2 force several runs and merge passes
+		external := memory
+		external.Shuffle = ShuffleExternal
+		external.SpillBytes = 1024
+		external.MergeFactor = 2
+		external.TempDir = t.TempDir()
+
+		want, err := Execute(tt.job, memory)
+		if err != nil {
+			t.Fatalf("%s memory: %v", tt.name, err)
+		}
+		got, err := Execute(tt.job, external)
+		if err != nil {
+			t.Fatalf("%s external: %v", tt.name, err)
+		}
+
+		if len(want.Output) == 0 {
+			t.Errorf("%s: no output", tt.name)
+		}
+		if !reflect.DeepEqual(got.Output, want.Output) || got.Truncated != want.Truncated {
+			t.Errorf("%s: external output differs from memory output", tt.name)
+		}
+		// Combining once per spill can shuffle more pairs, but map and reduce output must match
+		if got.Stats.MapOutputPairs != want.Stats.MapOutputPairs || got.Stats.OutputPairs != want.Stats.OutputPairs {
+			t.Errorf("%s: external mapped %d pairs into %d, memory %d into %d", tt.name,
+				got.Stats.MapOutputPairs, got.Stats.OutputPairs, want.Stats.MapOutputPairs, want.Stats.OutputPairs)
+		}
+		if got.Stats.MergePasses < 2 {
+			t.Errorf("%s: external shuffle merged in %d passes, want several", tt.name, got.Stats.MergePasses)
+		}
+		if got.Stats.SpillFiles == 0 || want.Stats.SpillFiles != 0 {
+			t.Errorf("%s: spill files external %d, memory %d", tt.name, got.Stats.SpillFiles, want.Stats.SpillFiles)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package mapreduce
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// Run files hold key-sorted pairs, each written as a uvarint-prefixed key then value
+
+// runWriter appends pairs to a run file
+type runWriter struct {
+	f      *os.File
+	w      *bufio.Writer
+	size   int64
+	prefix [binary.MaxVarintLen64]byte
+}
+
+// createRun creates an empty run file
+func createRun(path string) (*runWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &runWriter{f: f, w: bufio.NewWriter(f)}, nil
+}
+
+// write appends one pair; pairs must arrive in key order
+func (rw *runWriter) write(pair KeyValue) error {
+	for _, field := range []string{pair.Key, pair.Value} {
+		n := binary.PutUvarint(rw.prefix[:], uint64(len(field)))
+		rw.w.Write(rw.prefix[:n])
+		if _, err := rw.w.WriteString(field); err != nil {
+			return err
+		}
+		rw.size += int64(n + len(field))
+	}
+	return nil
+}
+
+// close flushes and closes the file
+func (rw *runWriter) close() error {
+	err := rw.w.Flush()
+	if closeErr := rw.f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// writeRun writes sorted pairs to a new run file and returns its size in bytes
+func writeRun(path string, pairs []KeyValue) (int64, error) {
+	rw, err := createRun(path)
+	if err != nil {
+		return 0, err
+	}
+	for _, pair := range pairs {
+		if err := rw.write(pair); err != nil {
+			rw.close()
+			return 0, err
+		}
+	}
+	return rw.size, rw.close()
+}
+
+// runReader streams the pairs of one run file
+type runReader struct {
+	f       *os.File
+	r       *bufio.Reader
+	current KeyValue
+	order   int // Position among the merged runs, so equal keys keep their run order
+}
+
+// openRun opens a run file positioned on its first pair (nil if the run is empty)
+func openRun(path string, order int) (*runReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	rr := &runReader{f: f, r: bufio.NewReader(f), order: order}
+	ok, err := rr.next()
+	if err != nil || !ok {
+		f.Close()
+		return nil, err
+	}
+	return rr, nil
+}
+
+// next advances to the following pair, returning false at the end of the run
+func (rr *runReader) next() (bool, error) {
+	key, err := rr.readField()
+	if err == io.EOF {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	value, err := rr.readField()
+	if err != nil {
+		return false, fmt.Errorf("truncated run file %s: %v", rr.f.Name(), err)
+	}
+	rr.current = KeyValue{Key: key, Value: value}
+	return true, nil
+}
+
+// readField reads one length-prefixed string
+func (rr *runReader) readField() (string, error) {
+	n, err := binary.ReadUvarint(rr.r)
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(rr.r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// runHeap orders open runs by their current key
+type runHeap []*runReader
+
+func (h runHeap) Len() int { return len(h) }
+func (h runHeap) Less(i, j int) bool {
+	if h[i].current.Key != h[j].current.Key {
+		return h[i].current.Key < h[j].current.Key
+	}
+	return h[i].order < h[j].order
+}
+func (h runHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x interface{}) { *h = append(*h, x.(*runReader)) }
+func (h *runHeap) Pop() interface{} {
+	old := *h
+	rr := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return rr
+}
+
+// mergeSorted k-way merges run files, calling emit with pairs in key order
+func mergeSorted(paths []string, emit func(KeyValue) error) error {
+	h := make(runHeap, 0, len(paths))
+	defer func() {
+		for _, rr := range h {
+			rr.f.Close()
+		}
+	}()
+
+	for i, path := range paths {
+		rr, err := openRun(path, i)
+		if err != nil {
+			return err
+		}
+		if rr != nil {
+			h = append(h, rr)
+		}
+	}
+	heap.Init(&h)
+
+	for h.Len() > 0 {
+		rr := h[0]
+		if err := emit(rr.current); err != nil {
+			return err
+		}
+		ok, err := rr.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+			rr.f.Close()
+		}
+	}
+	return nil
+}
+
+// externalMerge merges any number of runs with at most fanIn files open at once
+// Intermediate passes merge groups of runs into larger runs in dir; the final
+// pass streams to emit. Returns the number of merge passes
+func externalMerge(paths []string, dir string, fanIn int, counter *int64, emit func(KeyValue) error) (int, error) {
+	passes := 0
+	for len(paths) > fanIn {
+		passes++
+		var merged []string
+		for start := 0; start < len(paths); start += fanIn {
+			end := start + fanIn
+			if end > len(paths) {
+				end = len(paths)
+			}
+			group := paths[start:end]
+			if len(group) == 1 {
+				merged = append(merged, group[0])
+				continue
+			}
+
+			path := filepath.Join(dir, fmt.Sprintf("merge-%d.run", atomic.AddInt64(counter, 1)))
+			if err := mergeToFile(group, path); err != nil {
+				return passes, err
+			}
+			for _, old := range group {
+				os.Remove(old)
+			}
+			merged = append(merged, path)
+		}
+		paths = merged
+	}
+
+	passes++
+	return passes, mergeSorted(paths, emit)
+}
+
+// mergeToFile merges runs into a single new run file
+func mergeToFile(paths []string, path string) error {
+	rw, err := createRun(path)
+	if err != nil {
+		return err
+	}
+	err = mergeSorted(paths, rw.write)
+	if closeErr := rw.close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
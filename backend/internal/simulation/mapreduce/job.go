@@ -2,16 +2,55 @@ package mapreduce
 
 import (
 	"fmt"
+	"math"
 	"sort"
-	"strings"
 	"sync"
 	"time"
 )
 
+// Limits on submitted jobs
+const (
+	MaxDocuments  = 1000
+	MaxInputBytes = 1 << 20
+	MaxWorkers    = 64
+)
+
+// JobConfig selects a registered job type and its input
+type JobConfig struct {
+	JobType     string            `json:"jobType"`
+	Documents   []string          `json:"documents"` // Empty uses the job type's sample input
+	NumMappers  int               `json:"numMappers"`
+	NumReducers int               `json:"numReducers"`
+	Params      map[string]string `json:"params,omitempty"`
+	Combine     bool              `json:"combine"`              // Pre-aggregate each map task's output with the job's combiner
+	Partitioner string            `json:"partitioner"`          // "hash", "range" or a registered custom partitioner
+	Boundaries  []string          `json:"boundaries,omitempty"` // Range split keys; sampled from map output when empty
+}
+
+// DefaultJobConfig is word count over its sample sentences with 2 mappers and 2 reducers
+func DefaultJobConfig() JobConfig {
+	return JobConfig{
+		JobType:     "wordcount",
+		NumMappers:  2,
+		NumReducers: 2,
+		Partitioner: "hash",
+	}
+}
+
 // Job represents a MapReduce job
 type Job struct {
 	mu           sync.RWMutex
 	jobID        string
+	jobType      string
+	jobName      string
+	params       map[string]string
+	functions    Functions
+	combine      bool
+	partitioner  string
+	boundaries   []string
+	partition    PartitionFunc
+	shuffleStats *ShuffleStats
+	submissions  int
 	status       JobStatus
 	inputData    []string
 	numMappers   int
@@ -25,17 +64,123 @@ type Job struct {
 }
 
 // NewJob creates a new MapReduce job
-func NewJob(jobID string, inputData []string, numMappers, numReducers int) *Job {
-	return &Job{
-		jobID:       jobID,
-		status:      StatusIdle,
-		inputData:   inputData,
-		numMappers:  numMappers,
-		numReducers: numReducers,
-		mapTasks:    []MapTask{},
-		shuffleData: []ShufflePartition{},
-		reduceTasks: []ReduceTask{},
-		finalOutput: []KeyValue{},
+func NewJob(config JobConfig) (*Job, error) {
+	j := &Job{}
+	if err := j.Configure(config); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// preparedJob is a job config resolved against the registries
+type preparedJob struct {
+	info       JobInfo
+	functions  Functions
+	params     map[string]string
+	documents  []string
+	partition  PartitionFunc
+	boundaries []string
+	config     JobConfig
+}
+
+// prepareJob validates a config and builds its functions and partitioner
+// Input size limits are left to the caller
+func prepareJob(config JobConfig) (preparedJob, error) {
+	if config.NumMappers < 1 || config.NumMappers > MaxWorkers {
+		return preparedJob{}, fmt.Errorf("numMappers must be between 1 and %d", MaxWorkers)
+	}
+	if config.NumReducers < 1 || config.NumReducers > MaxWorkers {
+		return preparedJob{}, fmt.Errorf("numReducers must be between 1 and %d", MaxWorkers)
+	}
+
+	info, functions, params, err := lookupJob(config.JobType, config.Params)
+	if err != nil {
+		return preparedJob{}, err
+	}
+
+	documents := config.Documents
+	if len(documents) == 0 {
+		documents = info.SampleInput
+	}
+
+	if config.Combine && functions.Combine == nil {
+		return preparedJob{}, fmt.Errorf("job type %q has no combiner", info.Key)
+	}
+	if config.Partitioner == "" {
+		config.Partitioner = "hash"
+	}
+	partition, boundaries, err := newPartition(config.Partitioner, config.Boundaries, functions, documents, config.NumReducers)
+	if err != nil {
+		return preparedJob{}, err
+	}
+
+	return preparedJob{
+		info:       info,
+		functions:  functions,
+		params:     params,
+		documents:  documents,
+		partition:  partition,
+		boundaries: boundaries,
+		config:     config,
+	}, nil
+}
+
+// Configure replaces the job with a new, idle one
+func (j *Job) Configure(config JobConfig) error {
+	prepared, err := prepareJob(config)
+	if err != nil {
+		return err
+	}
+
+	if len(prepared.documents) > MaxDocuments {
+		return fmt.Errorf("at most %d documents are allowed", MaxDocuments)
+	}
+	size := 0
+	for _, document := range prepared.documents {
+		size += len(document)
+	}
+	if size > MaxInputBytes {
+		return fmt.Errorf("input must be at most %d bytes", MaxInputBytes)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.submissions++
+	j.jobID = fmt.Sprintf("%s-%d", prepared.info.Key, j.submissions)
+	j.jobType = prepared.info.Key
+	j.jobName = prepared.info.Name
+	j.params = prepared.params
+	j.functions = prepared.functions
+	j.inputData = append([]string(nil), prepared.documents...)
+	j.numMappers = config.NumMappers
+	j.numReducers = config.NumReducers
+	j.combine = config.Combine
+	j.partitioner = prepared.config.Partitioner
+	j.boundaries = prepared.boundaries
+	j.partition = prepared.partition
+	j.resetLocked()
+	return nil
+}
+
+// Config returns the job's current configuration
+func (j *Job) Config() JobConfig {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	params := make(map[string]string, len(j.params))
+	for name, value := range j.params {
+		params[name] = value
+	}
+	return JobConfig{
+		JobType:     j.jobType,
+		Documents:   append([]string(nil), j.inputData...),
+		NumMappers:  j.numMappers,
+		NumReducers: j.numReducers,
+		Params:      params,
+		Combine:     j.combine,
+		Partitioner: j.partitioner,
+		Boundaries:  append([]string(nil), j.boundaries...),
 	}
 }
 
@@ -62,6 +207,7 @@ func (j *Job) createMapTasks() {
 		workerID := i % j.numMappers
 		task := MapTask{
 			WorkerID:    workerID,
+			InputID:     fmt.Sprintf("doc%d", i+1),
 			InputData:   data,
 			OutputPairs: []KeyValue{},
 			Status:      "pending",
@@ -79,23 +225,20 @@ func (j *Job) ExecuteMapPhase() {
 		return
 	}
 
-	// Execute map function on each task (word count example)
+	// Execute the job's map function on each task
 	for i := range j.mapTasks {
 		j.mapTasks[i].Status = "running"
 		j.mapTasks[i].StartTime = time.Now()
-		
-		// Map function: emit (word, 1) for each word
-		words := strings.Fields(j.mapTasks[i].InputData)
-		for _, word := range words {
-			word = strings.ToLower(strings.Trim(word, ".,!?;:"))
-			if word != "" {
-				j.mapTasks[i].OutputPairs = append(j.mapTasks[i].OutputPairs, KeyValue{
-					Key:   word,
-					Value: "1",
-				})
-			}
+
+		task := &j.mapTasks[i]
+		j.functions.Map(task.InputID, task.InputData, func(key, value string) {
+			task.OutputPairs = append(task.OutputPairs, KeyValue{Key: key, Value: value})
+		})
+		task.Emitted = len(task.OutputPairs)
+		if j.combine {
+			task.OutputPairs = combinePairs(task.OutputPairs, j.functions.Combine)
 		}
-		
+
 		j.mapTasks[i].Status = "completed"
 		j.mapTasks[i].EndTime = time.Now()
 	}
@@ -114,7 +257,9 @@ func (j *Job) ExecuteShufflePhase() {
 
 	// Collect all key-value pairs from map tasks
 	allPairs := make(map[string][]string)
+	emitted := 0
 	for _, task := range j.mapTasks {
+		emitted += task.Emitted
 		for _, pair := range task.OutputPairs {
 			allPairs[pair.Key] = append(allPairs[pair.Key], pair.Value)
 		}
@@ -129,8 +274,8 @@ func (j *Job) ExecuteShufflePhase() {
 
 	j.shuffleData = make([]ShufflePartition, 0)
 	for _, key := range keys {
-		// Hash key to determine reducer (simple modulo)
-		reducerID := hashKey(key) % j.numReducers
+		// The job's partitioner picks the reducer
+		reducerID := j.partition(key, j.numReducers)
 		partition := ShufflePartition{
 			ReducerID: reducerID,
 			Key:       key,
@@ -139,6 +284,9 @@ func (j *Job) ExecuteShufflePhase() {
 		j.shuffleData = append(j.shuffleData, partition)
 	}
 
+	stats := shuffleStats(j.partitioner, j.combine, j.boundaries, emitted, j.shuffleData, j.numReducers)
+	j.shuffleStats = &stats
+
 	j.status = StatusReducing
 }
 
@@ -162,12 +310,10 @@ func (j *Job) ExecuteReducePhase() {
 			StartTime: time.Now(),
 		}
 
-		// Reduce function: sum all values for word count
-		count := 0
-		for range task.Values {
-			count++ // Each value is "1"
-		}
-		task.Result = fmt.Sprintf("%d", count)
+		// Reduce function: fold the key's values with the job's reducer
+		result, keep := j.functions.Reduce(task.Key, task.Values)
+		task.Result = result
+		task.Dropped = !keep
 		task.Status = "completed"
 		task.EndTime = time.Now()
 
@@ -177,6 +323,9 @@ func (j *Job) ExecuteReducePhase() {
 	// Collect final output
 	j.finalOutput = make([]KeyValue, 0)
 	for _, task := range j.reduceTasks {
+		if task.Dropped {
+			continue
+		}
 		j.finalOutput = append(j.finalOutput, KeyValue{
 			Key:   task.Key,
 			Value: task.Result,
@@ -239,6 +388,12 @@ func (j *Job) GetState() JobState {
 
 	return JobState{
 		JobID:        j.jobID,
+		JobType:      j.jobType,
+		JobName:      j.jobName,
+		Params:       j.params,
+		Combine:      j.combine,
+		Partitioner:  j.partitioner,
+		ShuffleStats: j.shuffleStats,
 		Status:       j.status,
 		InputData:    j.inputData,
 		NumMappers:   j.numMappers,
@@ -258,25 +413,31 @@ func (j *Job) GetState() JobState {
 func (j *Job) Reset() {
 	j.mu.Lock()
 	defer j.mu.Unlock()
+	j.resetLocked()
+}
 
+// resetLocked clears all progress (must be called with lock held)
+func (j *Job) resetLocked() {
 	j.status = StatusIdle
 	j.mapTasks = []MapTask{}
 	j.shuffleData = []ShufflePartition{}
 	j.reduceTasks = []ReduceTask{}
 	j.finalOutput = []KeyValue{}
+	j.shuffleStats = nil
 	j.startTime = time.Time{}
 	j.endTime = time.Time{}
 }
 
 // hashKey returns a simple hash of the key for partitioning
+// It is never negative: a key whose hash wraps to the minimum int64 would stay negative when negated
 func hashKey(key string) int {
-	hash := 0
+	var hash uint64
 	for _, char := range key {
-		hash = hash*31 + int(char)
+		hash = hash*31 + uint64(char)
 	}
-	if hash < 0 {
+	if int64(hash) < 0 {
 		hash = -hash
 	}
-	return hash
+	return int(hash & math.MaxInt64)
 }
 
@@ -0,0 +1,312 @@
+package mapreduce
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The example jobs from the original MapReduce paper, plus a reduce-side join
+func init() {
+	Register(JobInfo{
+		Key:         "wordcount",
+		Name:        "Word Count",
+		Description: "Counts occurrences of each word: map emits (word, 1), reduce sums",
+		SampleInput: []string{
+			"hello world hello",
+			"world of mapreduce",
+			"hello mapreduce world",
+			"distributed computing rocks",
+		},
+	}, func(params map[string]string) (Functions, error) {
+		return Functions{
+			Map: func(inputID, input string, emit func(key, value string)) {
+				for _, word := range words(input) {
+					emit(word, "1")
+				}
+			},
+			Reduce:  sumReduce,
+			Combine: sumReduce,
+		}, nil
+	})
+
+	Register(JobInfo{
+		Key:         "invertedindex",
+		Name:        "Inverted Index",
+		Description: "Maps each word to the documents containing it: map emits (word, document ID), reduce sorts and deduplicates",
+		SampleInput: []string{
+			"the quick brown fox",
+			"the lazy dog",
+			"quick thinking saves the day",
+			"a brown dog and a lazy fox",
+		},
+	}, func(params map[string]string) (Functions, error) {
+		return Functions{
+			Map: func(inputID, input string, emit func(key, value string)) {
+				seen := make(map[string]bool)
+				for _, word := range words(input) {
+					if !seen[word] {
+						seen[word] = true
+						emit(word, inputID)
+					}
+				}
+			},
+			Reduce:  mergeLists,
+			Combine: mergeLists,
+		}, nil
+	})
+
+	Register(JobInfo{
+		Key:         "grep",
+		Name:        "Distributed Grep",
+		Description: "Emits every line matching a regular expression: map emits (document:line, text), reduce is the identity",
+		Params: []JobParam{
+			{Name: "pattern", Default: "error", Description: "Regular expression to match against each line"},
+		},
+		SampleInput: []string{
+			"info starting server\nerror disk full\ninfo retrying",
+			"warn slow response\nerror connection reset",
+			"info request served\ninfo request served",
+			"error timeout talking to db\nwarn cache miss storm",
+		},
+	}, func(params map[string]string) (Functions, error) {
+		pattern, err := regexp.Compile(params["pattern"])
+		if err != nil {
+			return Functions{}, fmt.Errorf("invalid pattern: %v", err)
+		}
+		return Functions{
+			Map: func(inputID, input string, emit func(key, value string)) {
+				for i, line := range strings.Split(input, "\n") {
+					if pattern.MatchString(line) {
+						emit(fmt.Sprintf("%s:%d", inputID, i+1), line)
+					}
+				}
+			},
+			Reduce: func(key string, values []string) (string, bool) {
+				return values[0], true
+			},
+		}, nil
+	})
+
+	Register(JobInfo{
+		Key:         "urlfrequency",
+		Name:        "URL Access Frequency",
+		Description: "Counts requests per URL in web server logs: map emits (URL, 1) for each log line, reduce sums",
+		SampleInput: []string{
+			"10.0.0.1 GET /index.html 200\n10.0.0.2 GET /about.html 200\n10.0.0.1 GET /index.html 304",
+			"10.0.0.3 GET /products.html 200\n10.0.0.3 POST /cart 201",
+			"10.0.0.4 GET /index.html 200\n10.0.0.2 GET /products.html 200",
+			"10.0.0.5 GET /index.html 200\n10.0.0.5 GET /missing.html 404",
+		},
+	}, func(params map[string]string) (Functions, error) {
+		return Functions{
+			Map: func(inputID, input string, emit func(key, value string)) {
+				for _, line := range strings.Split(input, "\n") {
+					for _, field := range strings.Fields(line) {
+						if strings.HasPrefix(field, "/") || strings.Contains(field, "://") {
+							emit(field, "1")
+							break
+						}
+					}
+				}
+			},
+			Reduce:  sumReduce,
+			Combine: sumReduce,
+		}, nil
+	})
+
+	Register(JobInfo{
+		Key:         "reverselinks",
+		Name:        "Reverse Web-Link Graph",
+		Description: "Lists the pages linking to each page: input lines are \"source: target target...\", map emits (target, source), reduce collects sources",
+		SampleInput: []string{
+			"index.html: about.html products.html",
+			"about.html: index.html team.html",
+			"products.html: index.html cart.html",
+			"team.html: about.html",
+		},
+	}, func(params map[string]string) (Functions, error) {
+		return Functions{
+			Map: func(inputID, input string, emit func(key, value string)) {
+				for _, line := range strings.Split(input, "\n") {
+					// Without a "source:" prefix the document itself is the source
+					source, targets := inputID, line
+					if i := strings.Index(line, ":"); i >= 0 {
+						source, targets = strings.TrimSpace(line[:i]), line[i+1:]
+					}
+					for _, target := range strings.Fields(targets) {
+						emit(target, source)
+					}
+				}
+			},
+			Reduce:  mergeLists,
+			Combine: mergeLists,
+		}, nil
+	})
+
+	Register(JobInfo{
+		Key:         "join",
+		Name:        "Relational Join",
+		Description: "Reduce-side inner join of two tables: input rows are \"table,key,columns...\", map tags each row with its table, reduce pairs left and right rows",
+		Params: []JobParam{
+			{Name: "left", Default: "users", Description: "Table on the left of the join"},
+			{Name: "right", Default: "orders", Description: "Table on the right of the join"},
+		},
+		SampleInput: []string{
+			"users,1,alice\nusers,2,bob\nusers,3,carol",
+			"orders,1,book\norders,1,lamp",
+			"orders,2,desk\norders,4,chair",
+			"users,4,dave",
+		},
+	}, func(params map[string]string) (Functions, error) {
+		left, right := params["left"], params["right"]
+		if left == "" || right == "" || left == right {
+			return Functions{}, fmt.Errorf("left and right must name two different tables")
+		}
+		return Functions{
+			Map: func(inputID, input string, emit func(key, value string)) {
+				for _, line := range strings.Split(input, "\n") {
+					fields := strings.SplitN(strings.TrimSpace(line), ",", 3)
+					if len(fields) < 2 || (fields[0] != left && fields[0] != right) {
+						continue
+					}
+					row := ""
+					if len(fields) == 3 {
+						row = fields[2]
+					}
+					emit(fields[1], fields[0]+"|"+row)
+				}
+			},
+			Reduce: func(key string, values []string) (string, bool) {
+				var leftRows, rightRows []string
+				for _, value := range values {
+					table, row, _ := strings.Cut(value, "|")
+					if table == left {
+						leftRows = append(leftRows, row)
+					} else {
+						rightRows = append(rightRows, row)
+					}
+				}
+				sort.Strings(leftRows)
+				sort.Strings(rightRows)
+
+				joined := make([]string, 0, len(leftRows)*len(rightRows))
+				for _, l := range leftRows {
+					for _, r := range rightRows {
+						joined = append(joined, l+","+r)
+					}
+				}
+				return strings.Join(joined, "; "), len(joined) > 0
+			},
+		}, nil
+	})
+
+	Register(JobInfo{
+		Key:         "topk",
+		Name:        "Top K",
+		Description: "Keeps the K records with the largest numeric values: input lines are \"key<TAB>value\" (another job's output), map emits (top, key=value), reduce keeps the K largest",
+		Params: []JobParam{
+			{Name: "k", Default: "3", Description: "Number of records to keep"},
+		},
+		SampleInput: []string{
+			"hello\t3\nworld\t3\nof\t1",
+			"mapreduce\t2\ncomputing\t1",
+			"distributed\t1\nrocks\t1",
+		},
+	}, func(params map[string]string) (Functions, error) {
+		k, err := strconv.Atoi(params["k"])
+		if err != nil || k < 1 {
+			return Functions{}, fmt.Errorf("k must be a positive integer")
+		}
+		top := func(key string, values []string) (string, bool) {
+			var entries []string
+			for _, value := range values {
+				entries = append(entries, strings.Split(value, ",")...)
+			}
+			sortByCount(entries)
+			if len(entries) > k {
+				entries = entries[:k]
+			}
+			return strings.Join(entries, ","), true
+		}
+		return Functions{
+			Map: func(inputID, input string, emit func(key, value string)) {
+				for _, line := range strings.Split(input, "\n") {
+					if key, value, ok := strings.Cut(line, "\t"); ok {
+						emit("top", key+"="+strings.TrimSpace(value))
+					}
+				}
+			},
+			// Keeping each map task's top K cannot lose a global top K entry
+			Reduce:  top,
+			Combine: top,
+		}, nil
+	})
+}
+
+// sortByCount orders "key=count" entries by count, largest first, then by key
+func sortByCount(entries []string) {
+	count := func(entry string) int {
+		n, _ := strconv.Atoi(entry[strings.LastIndex(entry, "=")+1:])
+		return n
+	}
+	sort.SliceStable(entries, func(a, b int) bool {
+		ca, cb := count(entries[a]), count(entries[b])
+		if ca != cb {
+			return ca > cb
+		}
+		return entries[a] < entries[b]
+	})
+}
+
+// words splits text into lowercase words without surrounding punctuation
+func words(text string) []string {
+	fields := strings.Fields(text)
+	result := make([]string, 0, len(fields))
+	for _, word := range fields {
+		word = strings.ToLower(strings.Trim(word, ".,!?;:\"'()"))
+		if word != "" {
+			result = append(result, word)
+		}
+	}
+	return result
+}
+
+// sumReduce adds up integer values
+func sumReduce(key string, values []string) (string, bool) {
+	total := 0
+	for _, value := range values {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			n = 1
+		}
+		total += n
+	}
+	return strconv.Itoa(total), true
+}
+
+// mergeLists unions comma-separated lists into one sorted, deduplicated list
+// It accepts its own output, so it also serves as a combiner
+func mergeLists(key string, values []string) (string, bool) {
+	var items []string
+	for _, value := range values {
+		items = append(items, strings.Split(value, ",")...)
+	}
+	return strings.Join(uniqueSorted(items), ","), true
+}
+
+// uniqueSorted returns the distinct values in sorted order
+func uniqueSorted(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
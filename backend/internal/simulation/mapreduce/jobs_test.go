@@ -0,0 +1,112 @@
+package mapreduce
+
+import (
+	"reflect"
+	"testing"
+)
+
+// runJob runs a job to completion in one goroutine and returns its final output
+func runJob(t *testing.T, config JobConfig) []KeyValue {
+	t.Helper()
+	job, err := NewJob(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.Start()
+	job.ExecuteMapPhase()
+	job.ExecuteShufflePhase()
+	job.ExecuteReducePhase()
+	return job.GetState().FinalOutput
+}
+
+func TestRegisteredJobsOnSampleInput(t *testing.T) {
+	want := map[string][]KeyValue{
+		"wordcount": {
+			{"computing", "1"}, {"distributed", "1"}, {"hello", "3"}, {"mapreduce", "2"},
+			{"of", "1"}, {"rocks", "1"}, {"world", "3"},
+		},
+		"invertedindex": {
+			{"a", "doc4"}, {"and", "doc4"}, {"brown", "doc1,doc4"}, {"day", "doc3"},
+			{"dog", "doc2,doc4"}, {"fox", "doc1,doc4"}, {"lazy", "doc2,doc4"}, {"quick", "doc1,doc3"},
+			{"saves", "doc3"}, {"the", "doc1,doc2,doc3"}, {"thinking", "doc3"},
+		},
+		"grep": {
+			{"doc1:2", "error disk full"}, {"doc2:2", "error connection reset"}, {"doc4:1", "error timeout talking to db"},
+		},
+		"urlfrequency": {
+			{"/about.html", "1"}, {"/cart", "1"}, {"/index.html", "4"}, {"/missing.html", "1"}, {"/products.html", "2"},
+		},
+		"reverselinks": {
+			{"about.html", "index.html,team.html"}, {"cart.html", "products.html"},
+			{"index.html", "about.html,products.html"}, {"products.html", "index.html"}, {"team.html", "about.html"},
+		},
+		// User 3 has no orders, so the inner join drops it
+		"join": {
+			{"1", "alice,book; alice,lamp"}, {"2", "bob,desk"}, {"4", "dave,chair"},
+		},
+		"topk": {
+			{"top", "hello=3,world=3,mapreduce=2"},
+		},
+	}
+
+	for _, info := range RegisteredJobs() {
+		expected, ok := want[info.Key]
+		if !ok {
+			t.Errorf("%s: no expected output", info.Key)
+			continue
+		}
+		_, functions, _, err := lookupJob(info.Key, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Combining and the number of tasks must not change the answer
+		for _, config := range []JobConfig{
+			{JobType: info.Key, NumMappers: 1, NumReducers: 1, Partitioner: "hash"},
+			{JobType: info.Key, NumMappers: 4, NumReducers: 3, Partitioner: "hash", Combine: functions.Combine != nil},
+			{JobType: info.Key, NumMappers: 2, NumReducers: 2, Partitioner: "range"},
+		} {
+			if got := runJob(t, config); !reflect.DeepEqual(got, expected) {
+				t.Errorf("%s with %d mappers, %d reducers, combine %v:\n got %v\nwant %v",
+					info.Key, config.NumMappers, config.NumReducers, config.Combine, got, expected)
+			}
+		}
+	}
+
+	// Parameters change what a job computes
+	grep := runJob(t, JobConfig{JobType: "grep", NumMappers: 1, NumReducers: 1, Params: map[string]string{"pattern": "^warn"}})
+	if want := []KeyValue{{"doc2:1", "warn slow response"}, {"doc4:2", "warn cache miss storm"}}; !reflect.DeepEqual(grep, want) {
+		t.Errorf("grep ^warn = %v, want %v", grep, want)
+	}
+	topk := runJob(t, JobConfig{JobType: "topk", NumMappers: 1, NumReducers: 1, Params: map[string]string{"k": "1"}})
+	if want := []KeyValue{{"top", "hello=3"}}; !reflect.DeepEqual(topk, want) {
+		t.Errorf("topk k=1 = %v, want %v", topk, want)
+	}
+}
+
+func TestLookupJobErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    string
+		params map[string]string
+	}{
+		{"unknown job", "sort", nil},
+		{"unknown parameter", "wordcount", map[string]string{"pattern": "x"}},
+		{"invalid grep pattern", "grep", map[string]string{"pattern": "("}},
+		{"join of a table with itself", "join", map[string]string{"left": "users", "right": "users"}},
+		{"join without a right table", "join", map[string]string{"right": ""}},
+		{"topk with k zero", "topk", map[string]string{"k": "0"}},
+	}
+	for _, tt := range tests {
+		if _, _, _, err := lookupJob(tt.key, tt.params); err == nil {
+			t.Errorf("%s: lookupJob(%q, %v) succeeded", tt.name, tt.key, tt.params)
+		}
+	}
+
+	_, _, params, err := lookupJob("join", map[string]string{"right": "payments"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"left": "users", "right": "payments"}; !reflect.DeepEqual(params, want) {
+		t.Errorf("resolved params = %v, want %v", params, want)
+	}
+}
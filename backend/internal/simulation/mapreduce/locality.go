@@ -0,0 +1,454 @@
+package mapreduce
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Locality is how close a map task ran to its input split
+type Locality string
+
+const (
+	NodeLocal Locality = "node-local" // A replica is on the task's node
+	RackLocal Locality = "rack-local" // A replica is elsewhere in the task's rack
+	Remote    Locality = "remote"     // Every replica is in another rack
+)
+
+// Map task schedulers compared by CompareLocality
+const (
+	SchedulerRoundRobin = "round-robin" // Task i goes to node i mod N up front, as Job.createMapTasks does
+	SchedulerFIFO       = "fifo"        // The next task goes to whichever slot frees first
+	SchedulerLocality   = "locality"    // A free slot takes a node-local task, else rack-local, else any
+)
+
+// Limits on the simulated cluster
+const (
+	MaxRacks        = 16
+	MaxNodesPerRack = 32
+	MaxSlotsPerNode = 8
+	MaxSplits       = 2000
+)
+
+// LocalityConfig is the cluster topology, input layout and network speeds
+// Transfers get their link's full bandwidth; contention between them is not modelled
+type LocalityConfig struct {
+	Racks           int     `json:"racks"`
+	NodesPerRack    int     `json:"nodesPerRack"`
+	SlotsPerNode    int     `json:"slotsPerNode"` // Tasks a node runs at once
+	Replication     int     `json:"replication"`  // Replicas of each split, placed as HDFS does
+	Splits          int     `json:"splits"`       // Input blocks; split i holds document i mod len(documents), 0 means one per document
+	SplitMB         float64 `json:"splitMB"`
+	MapComputeMs    int64   `json:"mapComputeMs"`
+	ReduceComputeMs int64   `json:"reduceComputeMs"`
+	DiskMBps        float64 `json:"diskMBps"`      // Reading from the local disk
+	RackMBps        float64 `json:"rackMBps"`      // Transfers through a rack's switch
+	CrossRackMBps   float64 `json:"crossRackMBps"` // Transfers through the oversubscribed core
+	Seed            int64   `json:"seed"`          // Replica placement
+}
+
+// DefaultLocalityConfig is 4 racks of 4 two-slot nodes reading 64 splits of 64 MB,
+// with a core oversubscribed 4:1 relative to the rack switches
+func DefaultLocalityConfig() LocalityConfig {
+	return LocalityConfig{
+		Racks:           4,
+		NodesPerRack:    4,
+		SlotsPerNode:    2,
+		Replication:     3,
+		Splits:          64,
+		SplitMB:         64,
+		MapComputeMs:    1000,
+		ReduceComputeMs: 1000,
+		DiskMBps:        200,
+		RackMBps:        100,
+		CrossRackMBps:   25,
+		Seed:            1,
+	}
+}
+
+// Validate checks the config
+func (c LocalityConfig) Validate() error {
+	if c.Racks < 1 || c.Racks > MaxRacks {
+		return fmt.Errorf("racks must be between 1 and %d", MaxRacks)
+	}
+	if c.NodesPerRack < 1 || c.NodesPerRack > MaxNodesPerRack {
+		return fmt.Errorf("nodesPerRack must be between 1 and %d", MaxNodesPerRack)
+	}
+	if c.SlotsPerNode < 1 || c.SlotsPerNode > MaxSlotsPerNode {
+		return fmt.Errorf("slotsPerNode must be between 1 and %d", MaxSlotsPerNode)
+	}
+	if c.Replication < 1 || c.Replication > c.Racks*c.NodesPerRack {
+		return fmt.Errorf("replication must be between 1 and the number of nodes")
+	}
+	if c.Splits < 0 || c.Splits > MaxSplits {
+		return fmt.Errorf("splits must be between 0 and %d", MaxSplits)
+	}
+	if c.SplitMB <= 0 || c.SplitMB > 1024 {
+		return fmt.Errorf("splitMB must be greater than 0 and at most 1024")
+	}
+	if c.MapComputeMs < 0 || c.ReduceComputeMs < 0 {
+		return fmt.Errorf("mapComputeMs and reduceComputeMs must not be negative")
+	}
+	if c.DiskMBps <= 0 || c.RackMBps <= 0 || c.CrossRackMBps <= 0 {
+		return fmt.Errorf("bandwidths must be positive")
+	}
+	return nil
+}
+
+// NodeInfo is a simulated machine
+type NodeInfo struct {
+	ID   int `json:"id"`
+	Rack int `json:"rack"`
+}
+
+// SplitPlacement lists the nodes holding a split's replicas
+type SplitPlacement struct {
+	Split    int    `json:"split"`
+	InputID  string `json:"inputId"`
+	Replicas []int  `json:"replicas"`
+}
+
+// MapPlacement is where and when a map task ran
+type MapPlacement struct {
+	Task     string   `json:"task"`
+	Node     int      `json:"node"`
+	Rack     int      `json:"rack"`
+	Locality Locality `json:"locality"`
+	ReadMs   int64    `json:"readMs"`
+	StartMs  int64    `json:"startMs"`
+	EndMs    int64    `json:"endMs"`
+}
+
+// ReducePlacement is where a reduce task ran and what its shuffle cost
+type ReducePlacement struct {
+	Task        string  `json:"task"`
+	Node        int     `json:"node"`
+	Rack        int     `json:"rack"`
+	ShuffleMB   float64 `json:"shuffleMB"`
+	CrossRackMB float64 `json:"crossRackMB"`
+	FetchMs     int64   `json:"fetchMs"`
+	StartMs     int64   `json:"startMs"`
+	EndMs       int64   `json:"endMs"`
+}
+
+// SchedulerRun is one scheduler's placement of a job and what it cost
+type SchedulerRun struct {
+	Scheduler           string            `json:"scheduler"`
+	NodeLocal           int               `json:"nodeLocal"`
+	RackLocal           int               `json:"rackLocal"`
+	Remote              int               `json:"remote"`
+	NodeLocalPct        float64           `json:"nodeLocalPct"`
+	RackLocalPct        float64           `json:"rackLocalPct"`
+	RemotePct           float64           `json:"remotePct"`
+	InputCrossRackMB    float64           `json:"inputCrossRackMB"` // Split data read across racks
+	ShuffleMB           float64           `json:"shuffleMB"`
+	CrossRackShuffleMB  float64           `json:"crossRackShuffleMB"`
+	CrossRackShufflePct float64           `json:"crossRackShufflePct"`
+	MapPhaseMs          int64             `json:"mapPhaseMs"`
+	CompletionMs        int64             `json:"completionMs"`
+	Maps                []MapPlacement    `json:"maps"`
+	Reduces             []ReducePlacement `json:"reduces"`
+}
+
+// LocalityReport compares map schedulers on the same cluster and input layout
+type LocalityReport struct {
+	Config      LocalityConfig   `json:"config"`
+	JobType     string           `json:"jobType"`
+	OutputRatio float64          `json:"outputRatio"` // Measured map output bytes per input byte, which sizes the shuffle
+	Nodes       []NodeInfo       `json:"nodes"`
+	Splits      []SplitPlacement `json:"splits"`
+	Runs        []SchedulerRun   `json:"runs"`
+}
+
+// CompareLocality places a job's input splits on a rack topology and schedules its
+// map tasks with each scheduler, accounting the network cost of reads and the shuffle
+func CompareLocality(config LocalityConfig, job JobConfig) (LocalityReport, error) {
+	if err := config.Validate(); err != nil {
+		return LocalityReport{}, err
+	}
+	prepared, err := prepareJob(job)
+	if err != nil {
+		return LocalityReport{}, err
+	}
+	reducers := prepared.config.NumReducers
+	nodes := config.Racks * config.NodesPerRack
+	if reducers > nodes*config.SlotsPerNode {
+		return LocalityReport{}, fmt.Errorf("numReducers exceeds the cluster's %d slots", nodes*config.SlotsPerNode)
+	}
+
+	splits := config.Splits
+	if splits == 0 {
+		splits = len(prepared.documents)
+	}
+
+	// Run the real map function on each document to size its shuffle partitions
+	documentMB := make([][]float64, len(prepared.documents))
+	inputBytes, outputBytes := 0, 0
+	for i, document := range prepared.documents {
+		var pairs []KeyValue
+		prepared.functions.Map(fmt.Sprintf("doc%d", i+1), document, func(key, value string) {
+			pairs = append(pairs, KeyValue{Key: key, Value: value})
+		})
+		if prepared.config.Combine {
+			pairs = combinePairs(pairs, prepared.functions.Combine)
+		}
+
+		partitionBytes := make([]int, reducers)
+		for _, pair := range pairs {
+			partitionBytes[prepared.partition(pair.Key, reducers)] += len(pair.Key) + len(pair.Value)
+		}
+		documentMB[i] = make([]float64, reducers)
+		for r, bytes := range partitionBytes {
+			if len(document) > 0 {
+				documentMB[i][r] = float64(bytes) / float64(len(document)) * config.SplitMB
+			}
+			outputBytes += bytes
+		}
+		inputBytes += len(document)
+	}
+
+	report := LocalityReport{
+		Config:  config,
+		JobType: prepared.info.Key,
+		Nodes:   make([]NodeInfo, nodes),
+		Splits:  placeReplicas(config, splits),
+	}
+	if inputBytes > 0 {
+		report.OutputRatio = float64(outputBytes) / float64(inputBytes)
+	}
+	for n := range report.Nodes {
+		report.Nodes[n] = NodeInfo{ID: n, Rack: n / config.NodesPerRack}
+	}
+	for i := range report.Splits {
+		report.Splits[i].InputID = fmt.Sprintf("doc%d", i%len(prepared.documents)+1)
+	}
+
+	for _, scheduler := range []string{SchedulerRoundRobin, SchedulerFIFO, SchedulerLocality} {
+		run := scheduleMaps(config, scheduler, report.Splits)
+		shuffle := make([][]float64, splits)
+		for i := range shuffle {
+			shuffle[i] = documentMB[i%len(prepared.documents)]
+		}
+		scheduleReduces(config, &run, shuffle, reducers)
+		report.Runs = append(report.Runs, run)
+	}
+	return report, nil
+}
+
+// placeReplicas puts each split's replicas where HDFS would: the first on a random node,
+// the second in another rack and the third beside the second; any more go anywhere unused
+func placeReplicas(config LocalityConfig, splits int) []SplitPlacement {
+	rng := rand.New(rand.NewSource(config.Seed))
+	nodes := config.Racks * config.NodesPerRack
+	rackOf := func(node int) int { return node / config.NodesPerRack }
+
+	placements := make([]SplitPlacement, splits)
+	for i := range placements {
+		used := make(map[int]bool)
+		var replicas []int
+		// pick chooses an unused node satisfying ok, or any unused node if none does
+		pick := func(ok func(node int) bool) {
+			var candidates, fallback []int
+			for node := 0; node < nodes; node++ {
+				if used[node] {
+					continue
+				}
+				fallback = append(fallback, node)
+				if ok(node) {
+					candidates = append(candidates, node)
+				}
+			}
+			if len(candidates) == 0 {
+				candidates = fallback
+			}
+			node := candidates[rng.Intn(len(candidates))]
+			used[node] = true
+			replicas = append(replicas, node)
+		}
+
+		for n := 0; n < config.Replication; n++ {
+			switch n {
+			case 0:
+				pick(func(int) bool { return true })
+			case 1:
+				pick(func(node int) bool { return rackOf(node) != rackOf(replicas[0]) })
+			case 2:
+				pick(func(node int) bool { return rackOf(node) == rackOf(replicas[1]) })
+			default:
+				pick(func(int) bool { return true })
+			}
+		}
+		placements[i] = SplitPlacement{Split: i, Replicas: replicas}
+	}
+	return placements
+}
+
+// locality returns how close node is to the nearest replica
+func (c LocalityConfig) locality(node int, replicas []int) Locality {
+	level := Remote
+	for _, replica := range replicas {
+		if replica == node {
+			return NodeLocal
+		}
+		if replica/c.NodesPerRack == node/c.NodesPerRack {
+			level = RackLocal
+		}
+	}
+	return level
+}
+
+// transferMs returns the time to move mb megabytes between two nodes
+func (c LocalityConfig) transferMs(mb float64, from, to int) int64 {
+	bandwidth := c.CrossRackMBps
+	if from == to {
+		bandwidth = c.DiskMBps
+	} else if from/c.NodesPerRack == to/c.NodesPerRack {
+		bandwidth = c.RackMBps
+	}
+	return int64(mb / bandwidth * 1000)
+}
+
+// readMs returns the time a map task takes to read its split from the nearest replica
+func (c LocalityConfig) readMs(level Locality) int64 {
+	switch level {
+	case NodeLocal:
+		return int64(c.SplitMB / c.DiskMBps * 1000)
+	case RackLocal:
+		return int64(c.SplitMB / c.RackMBps * 1000)
+	}
+	return int64(c.SplitMB / c.CrossRackMBps * 1000)
+}
+
+// scheduleMaps assigns every map task to a slot with the given scheduler
+func scheduleMaps(config LocalityConfig, scheduler string, splits []SplitPlacement) SchedulerRun {
+	nodes := config.Racks * config.NodesPerRack
+	free := make([]int64, nodes*config.SlotsPerNode) // When each slot is next free; slot s is on node s / SlotsPerNode
+	run := SchedulerRun{Scheduler: scheduler, Maps: make([]MapPlacement, len(splits))}
+
+	start := func(split, slot int) {
+		node := slot / config.SlotsPerNode
+		level := config.locality(node, splits[split].Replicas)
+		read := config.readMs(level)
+		run.Maps[split] = MapPlacement{
+			Task:     fmt.Sprintf("m%d", split),
+			Node:     node,
+			Rack:     node / config.NodesPerRack,
+			Locality: level,
+			ReadMs:   read,
+			StartMs:  free[slot],
+			EndMs:    free[slot] + read + config.MapComputeMs,
+		}
+		free[slot] = run.Maps[split].EndMs
+
+		switch level {
+		case NodeLocal:
+			run.NodeLocal++
+		case RackLocal:
+			run.RackLocal++
+		default:
+			run.Remote++
+			run.InputCrossRackMB += config.SplitMB
+		}
+	}
+
+	if scheduler == SchedulerRoundRobin {
+		// Assigned up front; each node works through its queue on its own slots
+		for split := range splits {
+			first := (split % nodes) * config.SlotsPerNode
+			slot := first
+			for s := first; s < first+config.SlotsPerNode; s++ {
+				if free[s] < free[slot] {
+					slot = s
+				}
+			}
+			start(split, slot)
+		}
+	} else {
+		pending := make([]bool, len(splits))
+		for i := range pending {
+			pending[i] = true
+		}
+		for range splits {
+			slot := 0
+			for s := range free {
+				if free[s] < free[slot] {
+					slot = s
+				}
+			}
+			start(pickSplit(config, scheduler, slot/config.SlotsPerNode, splits, pending), slot)
+		}
+	}
+
+	for _, m := range run.Maps {
+		if m.EndMs > run.MapPhaseMs {
+			run.MapPhaseMs = m.EndMs
+		}
+	}
+	if total := float64(len(splits)); total > 0 {
+		run.NodeLocalPct = float64(run.NodeLocal) / total * 100
+		run.RackLocalPct = float64(run.RackLocal) / total * 100
+		run.RemotePct = float64(run.Remote) / total * 100
+	}
+	return run
+}
+
+// pickSplit chooses the pending split a free slot on node runs next and marks it taken
+func pickSplit(config LocalityConfig, scheduler string, node int, splits []SplitPlacement, pending []bool) int {
+	best := -1
+	for i := range splits {
+		if !pending[i] {
+			continue
+		}
+		if best < 0 {
+			best = i
+			if scheduler == SchedulerFIFO {
+				break
+			}
+		}
+		level := config.locality(node, splits[i].Replicas)
+		if level == NodeLocal {
+			best = i
+			break
+		}
+		if level == RackLocal && config.locality(node, splits[best].Replicas) == Remote {
+			best = i
+		}
+	}
+	pending[best] = false
+	return best
+}
+
+// scheduleReduces places reducers across racks once every map has finished and
+// charges each for fetching its partition from every map task's node
+func scheduleReduces(config LocalityConfig, run *SchedulerRun, shuffle [][]float64, reducers int) {
+	run.Reduces = make([]ReducePlacement, reducers)
+	for r := range run.Reduces {
+		// Spread reducers rack by rack so no rack's uplink carries them all
+		node := (r%config.Racks)*config.NodesPerRack + (r/config.Racks)%config.NodesPerRack
+		reduce := ReducePlacement{
+			Task:    fmt.Sprintf("r%d", r),
+			Node:    node,
+			Rack:    node / config.NodesPerRack,
+			StartMs: run.MapPhaseMs,
+		}
+		for split, partitions := range shuffle {
+			mb := partitions[r]
+			from := run.Maps[split].Node
+			reduce.ShuffleMB += mb
+			if from/config.NodesPerRack != reduce.Rack {
+				reduce.CrossRackMB += mb
+			}
+			reduce.FetchMs += config.transferMs(mb, from, node)
+		}
+		reduce.EndMs = reduce.StartMs + reduce.FetchMs + config.ReduceComputeMs
+
+		run.ShuffleMB += reduce.ShuffleMB
+		run.CrossRackShuffleMB += reduce.CrossRackMB
+		if reduce.EndMs > run.CompletionMs {
+			run.CompletionMs = reduce.EndMs
+		}
+		run.Reduces[r] = reduce
+	}
+	if run.ShuffleMB > 0 {
+		run.CrossRackShufflePct = run.CrossRackShuffleMB / run.ShuffleMB * 100
+	}
+}
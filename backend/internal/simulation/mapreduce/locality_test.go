@@ -0,0 +1,92 @@
+package mapreduce
+
+import "testing"
+
+func TestPlaceReplicasFollowsHDFSRule(t *testing.T) {
+	config := DefaultLocalityConfig()
+	rackOf := func(node int) int { return node / config.NodesPerRack }
+
+	for _, replication := range []int{1, 2, 3, 4} {
+		config.Replication = replication
+		for _, placement := range placeReplicas(config, 200) {
+			replicas := placement.Replicas
+			if len(replicas) != replication {
+				t.Fatalf("split %d has %d replicas, want %d", placement.Split, len(replicas), replication)
+			}
+			seen := make(map[int]bool)
+			for _, node := range replicas {
+				if seen[node] {
+					t.Errorf("split %d stores two replicas on node %d", placement.Split, node)
+				}
+				seen[node] = true
+			}
+			if replication >= 2 && rackOf(replicas[1]) == rackOf(replicas[0]) {
+				t.Errorf("split %d: second replica %d shares rack with the first, %d", placement.Split, replicas[1], replicas[0])
+			}
+			if replication >= 3 && rackOf(replicas[2]) != rackOf(replicas[1]) {
+				t.Errorf("split %d: third replica %d is not in the second replica's rack", placement.Split, replicas[2])
+			}
+		}
+	}
+
+	// One rack cannot put the second replica elsewhere, so it falls back to any free node
+	config = DefaultLocalityConfig()
+	config.Racks = 1
+	for _, placement := range placeReplicas(config, 20) {
+		if len(placement.Replicas) != config.Replication {
+			t.Errorf("single rack: split %d has replicas %v", placement.Split, placement.Replicas)
+		}
+	}
+}
+
+func TestPickSplitPrefersLocalData(t *testing.T) {
+	config := DefaultLocalityConfig() // Node 0 is in rack 0 with nodes 1-3
+	splits := []SplitPlacement{
+		{Split: 0, Replicas: []int{8, 12}}, // Remote
+		{Split: 1, Replicas: []int{2, 9}},  // Rack-local
+		{Split: 2, Replicas: []int{5, 0}},  // Node-local
+	}
+
+	tests := []struct {
+		scheduler string
+		want      []int // Splits taken by node 0, in order
+	}{
+		{SchedulerLocality, []int{2, 1, 0}},
+		{SchedulerFIFO, []int{0, 1, 2}},
+	}
+	for _, tt := range tests {
+		pending := []bool{true, true, true}
+		for _, want := range tt.want {
+			if got := pickSplit(config, tt.scheduler, 0, splits, pending); got != want {
+				t.Errorf("%s scheduler picked split %d, want %d", tt.scheduler, got, want)
+			}
+		}
+		for i, left := range pending {
+			if left {
+				t.Errorf("%s scheduler left split %d pending", tt.scheduler, i)
+			}
+		}
+	}
+}
+
+func TestLocalitySchedulerBeatsRoundRobin(t *testing.T) {
+	report, err := CompareLocality(DefaultLocalityConfig(), DefaultJobConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs := make(map[string]SchedulerRun)
+	for _, run := range report.Runs {
+		runs[run.Scheduler] = run
+		if total := run.NodeLocal + run.RackLocal + run.Remote; total != len(report.Splits) {
+			t.Errorf("%s placed %d map tasks, want %d", run.Scheduler, total, len(report.Splits))
+		}
+	}
+
+	locality, roundRobin := runs[SchedulerLocality], runs[SchedulerRoundRobin]
+	if locality.NodeLocalPct <= roundRobin.NodeLocalPct {
+		t.Errorf("node-local: locality %.1f%%, round-robin %.1f%%", locality.NodeLocalPct, roundRobin.NodeLocalPct)
+	}
+	if locality.InputCrossRackMB >= roundRobin.InputCrossRackMB {
+		t.Errorf("cross-rack input: locality %.0f MB, round-robin %.0f MB", locality.InputCrossRackMB, roundRobin.InputCrossRackMB)
+	}
+}
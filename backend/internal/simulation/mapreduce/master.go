@@ -0,0 +1,1034 @@
+package mapreduce
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// TaskKind distinguishes map and reduce tasks
+type TaskKind string
+
+const (
+	TaskMap    TaskKind = "map"
+	TaskReduce TaskKind = "reduce"
+)
+
+// TaskState is the master's view of a task, as in the MapReduce paper
+type TaskState string
+
+const (
+	TaskIdle       TaskState = "idle"
+	TaskInProgress TaskState = "in-progress"
+	TaskCompleted  TaskState = "completed"
+)
+
+// MasterStatus is the state of the master's job
+type MasterStatus string
+
+const (
+	MasterIdle       MasterStatus = "idle"
+	MasterRunning    MasterStatus = "running"
+	MasterRecovering MasterStatus = "recovering" // Master crashed, restarting from its checkpoint
+	MasterCompleted  MasterStatus = "completed"
+	MasterAborted    MasterStatus = "aborted" // Master crashed without a checkpoint to restart from
+)
+
+// Limits on master simulations
+const (
+	MaxMasterWorkers = 64
+	MaxRunMs         = 600000 // Virtual time a single run may simulate, and the longest task or timer
+	MinWorkerSpeed   = 0.01   // Slowest straggler: 100x the configured task time
+	MaxWorkerSpeed   = 100.0
+	maxMasterEvents  = 200
+	maxAttempts      = 1000
+)
+
+// never is the time of an event that will not happen
+const never = math.MaxInt64
+
+// MasterConfig sets the cluster and failure-detection timing, all in virtual milliseconds
+type MasterConfig struct {
+	Workers          int   `json:"workers"`
+	HeartbeatMs      int64 `json:"heartbeatMs"`      // Interval between worker heartbeats and master health checks
+	FailureTimeoutMs int64 `json:"failureTimeoutMs"` // Silence after which the master declares a worker failed
+	MapTaskMs        int64 `json:"mapTaskMs"`
+	ReduceTaskMs     int64 `json:"reduceTaskMs"`
+	CheckpointMs     int64 `json:"checkpointMs"`     // Interval between master checkpoints; 0 means a master crash aborts the job
+	MasterRecoveryMs int64 `json:"masterRecoveryMs"` // Time for a new master to start from the checkpoint
+
+	// WorkerSpeeds scales each worker's task times (0.25 is a straggler taking 4x as long); missing entries are 1
+	WorkerSpeeds []float64 `json:"workerSpeeds,omitempty"`
+	// Speculative runs backup copies of in-progress tasks once a phase has no idle tasks left
+	Speculative bool `json:"speculative"`
+}
+
+// DefaultMasterConfig is 4 workers with 100 ms heartbeats and a 500 ms failure timeout
+func DefaultMasterConfig() MasterConfig {
+	return MasterConfig{
+		Workers:          4,
+		HeartbeatMs:      100,
+		FailureTimeoutMs: 500,
+		MapTaskMs:        1000,
+		ReduceTaskMs:     1000,
+		CheckpointMs:     2000,
+		MasterRecoveryMs: 1000,
+	}
+}
+
+// Validate checks the config
+func (c MasterConfig) Validate() error {
+	if c.Workers < 1 || c.Workers > MaxMasterWorkers {
+		return fmt.Errorf("workers must be between 1 and %d", MaxMasterWorkers)
+	}
+	for _, ms := range []int64{c.HeartbeatMs, c.MapTaskMs, c.ReduceTaskMs} {
+		if ms <= 0 || ms > MaxRunMs {
+			return fmt.Errorf("heartbeatMs, mapTaskMs and reduceTaskMs must be between 1 and %d", MaxRunMs)
+		}
+	}
+	if c.FailureTimeoutMs < c.HeartbeatMs || c.FailureTimeoutMs > MaxRunMs {
+		return fmt.Errorf("failureTimeoutMs must be between heartbeatMs and %d", MaxRunMs)
+	}
+	if c.CheckpointMs < 0 || c.MasterRecoveryMs < 0 || c.CheckpointMs > MaxRunMs || c.MasterRecoveryMs > MaxRunMs {
+		return fmt.Errorf("checkpointMs and masterRecoveryMs must be between 0 and %d", MaxRunMs)
+	}
+	if len(c.WorkerSpeeds) > c.Workers {
+		return fmt.Errorf("workerSpeeds has more entries than there are workers")
+	}
+	for _, speed := range c.WorkerSpeeds {
+		if !(speed >= MinWorkerSpeed && speed <= MaxWorkerSpeed) {
+			return fmt.Errorf("worker speeds must be between %g and %g", MinWorkerSpeed, MaxWorkerSpeed)
+		}
+	}
+	return nil
+}
+
+// speed returns a worker's speed multiplier
+func (c MasterConfig) speed(id int) float64 {
+	if id < len(c.WorkerSpeeds) {
+		return c.WorkerSpeeds[id]
+	}
+	return 1
+}
+
+// clone returns a copy of the config that shares no slices with it
+func (c MasterConfig) clone() MasterConfig {
+	c.WorkerSpeeds = append([]float64(nil), c.WorkerSpeeds...)
+	return c
+}
+
+// MasterTask is a task as tracked by the master
+type MasterTask struct {
+	ID       string    `json:"id"`
+	Kind     TaskKind  `json:"kind"`
+	Index    int       `json:"index"`
+	State    TaskState `json:"state"`
+	Worker   int       `json:"worker"` // Assigned worker, -1 when idle
+	Backup   int       `json:"backup"` // Worker running a speculative backup copy, -1 if none
+	Attempts int       `json:"attempts"`
+	InputID  string    `json:"inputId,omitempty"`
+	StartMs  int64     `json:"startMs"`
+	EndMs    int64     `json:"endMs"`
+	OutputOn int       `json:"outputOn"` // Worker whose local disk holds a map task's output, -1 if none
+}
+
+// TaskAttempt is one execution of a task on a worker
+type TaskAttempt struct {
+	Task    string `json:"task"`
+	Worker  int    `json:"worker"`
+	StartMs int64  `json:"startMs"`
+	EndMs   int64  `json:"endMs"`
+	Outcome string `json:"outcome"` // "running", "completed", "killed" (other copy won), "lost" (worker crashed), "unreported" (master down), "abandoned" (master restarted)
+	Backup  bool   `json:"backup,omitempty"`
+}
+
+// MasterEvent is an entry in the master's log
+type MasterEvent struct {
+	TimeMs  int64  `json:"timeMs"`
+	Kind    string `json:"kind"`
+	Worker  int    `json:"worker"`
+	Task    string `json:"task,omitempty"`
+	Message string `json:"message"`
+}
+
+// ScheduledCrash is a crash injected for a future time
+type ScheduledCrash struct {
+	AtMs   int64  `json:"atMs"`
+	Target string `json:"target"` // "worker" or "master"
+	Worker int    `json:"worker"`
+}
+
+// MasterStats counts the work the failure model caused
+type MasterStats struct {
+	MapAttempts       int   `json:"mapAttempts"`
+	ReduceAttempts    int   `json:"reduceAttempts"`
+	ReExecutedMaps    int   `json:"reExecutedMaps"`    // Completed map tasks re-run because their output was lost
+	ReassignedReduces int   `json:"reassignedReduces"` // In-progress reduce tasks handed to another worker
+	WorkerFailures    int   `json:"workerFailures"`
+	MasterFailures    int   `json:"masterFailures"`
+	Heartbeats        int   `json:"heartbeats"`
+	Checkpoints       int   `json:"checkpoints"`
+	BackupAttempts    int   `json:"backupAttempts"` // Speculative copies started
+	BackupWins        int   `json:"backupWins"`     // Tasks whose backup finished before the original
+	WorkMs            int64 `json:"workMs"`         // Worker time spent on ended attempts, including killed and lost ones
+}
+
+// WorkerView is a worker as shown to clients
+type WorkerView struct {
+	ID              int     `json:"id"`
+	Status          string  `json:"status"` // "idle", "busy", "crashed" (not yet detected) or "failed" (declared by the master)
+	Task            string  `json:"task,omitempty"`
+	Speed           float64 `json:"speed"`
+	LastHeartbeatMs int64   `json:"lastHeartbeatMs"`
+	TasksCompleted  int     `json:"tasksCompleted"`
+	Incarnation     int     `json:"incarnation"` // Restarts so far; a restart wipes the worker's local disk
+}
+
+// MasterState is the full state for visualization
+type MasterState struct {
+	Config         MasterConfig     `json:"config"`
+	JobType        string           `json:"jobType"`
+	Status         MasterStatus     `json:"status"`
+	Phase          string           `json:"phase"`
+	TimeMs         int64            `json:"timeMs"`
+	CompletionMs   int64            `json:"completionMs"`
+	LastCheckpoint int64            `json:"lastCheckpointMs"` // Time of the checkpoint a restarted master would resume from
+	Workers        []WorkerView     `json:"workers"`
+	Tasks          []MasterTask     `json:"tasks"`
+	Attempts       []TaskAttempt    `json:"attempts"`
+	Events         []MasterEvent    `json:"events"`
+	PendingCrashes []ScheduledCrash `json:"pendingCrashes"`
+	Stats          MasterStats      `json:"stats"`
+	FinalOutput    []KeyValue       `json:"finalOutput"`
+}
+
+// simWorker is a worker's ground truth, which the master only sees through heartbeats
+type simWorker struct {
+	alive         bool
+	failed        bool  // Master has declared the worker failed
+	task          int   // Index into tasks of the assigned task, -1 if none
+	attempt       int   // Index into attempts of the running attempt
+	startedAt     int64 // When the running attempt started, -1 if none
+	busyUntil     int64 // When the running task finishes
+	nextHeartbeat int64
+	lastHeartbeat int64 // Last heartbeat the master received
+	completed     int
+	incarnation   int
+}
+
+// masterCheckpoint is the master state a restarted master resumes from
+type masterCheckpoint struct {
+	timeMs        int64
+	tasks         []MasterTask
+	mapOutputs    [][][]KeyValue
+	outputEpochs  []int
+	reduceOutputs [][]KeyValue
+	failed        []bool
+}
+
+// Master schedules a job's tasks on simulated workers that can crash
+// The job's real map and reduce functions run, so the output shows that
+// re-execution is transparent: it matches a failure-free run
+type Master struct {
+	mu        sync.RWMutex
+	config    MasterConfig
+	job       JobConfig
+	functions Functions
+	partition PartitionFunc
+	documents []string
+
+	status       MasterStatus
+	clock        int64
+	completionMs int64
+	workers      []*simWorker
+	tasks        []MasterTask
+	numMaps      int
+
+	mapOutputs    [][][]KeyValue // Per map task, output partitioned by reducer
+	outputEpochs  []int          // Incarnation of the worker that wrote each map output
+	fetched       [][]KeyValue   // Per reduce task, input copied when it started
+	reduceOutputs [][]KeyValue   // Per reduce task, output in the global file system
+
+	attempts   []TaskAttempt
+	events     []MasterEvent
+	crashes    []ScheduledCrash
+	stats      MasterStats
+	nextCheck  int64
+	nextSave   int64
+	masterUpAt int64
+	checkpoint *masterCheckpoint
+}
+
+// NewMaster creates a master for the given job
+func NewMaster(config MasterConfig, job JobConfig) (*Master, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	m := &Master{config: config.clone()}
+	if err := m.Load(job); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Configure changes the cluster and restarts the current job from scratch
+func (m *Master) Configure(config MasterConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.config = config.clone()
+	m.resetLocked()
+	return nil
+}
+
+// Config returns the cluster configuration
+func (m *Master) Config() MasterConfig {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.config.clone()
+}
+
+// Load replaces the job, leaving the master idle
+func (m *Master) Load(job JobConfig) error {
+	scratch := &Job{}
+	if err := scratch.Configure(job); err != nil {
+		return err
+	}
+	config := scratch.Config()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.job = config
+	m.functions = scratch.functions
+	m.partition = scratch.partition
+	m.documents = config.Documents
+	m.resetLocked()
+	return nil
+}
+
+// Start begins scheduling tasks
+func (m *Master) Start() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.status != MasterIdle {
+		return fmt.Errorf("job already started; reset it first")
+	}
+	m.status = MasterRunning
+	m.logEvent("start", -1, "", fmt.Sprintf("%d map and %d reduce tasks on %d workers", m.numMaps, len(m.tasks)-m.numMaps, len(m.workers)))
+	m.schedule()
+	return nil
+}
+
+// Advance moves virtual time forward by ms
+func (m *Master) Advance(ms int64) error {
+	if ms <= 0 || ms > MaxRunMs {
+		return fmt.Errorf("ms must be between 1 and %d", MaxRunMs)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.advanceTo(m.clock + ms)
+	return nil
+}
+
+// Run advances until the job completes or aborts, or MaxRunMs elapses
+func (m *Master) Run() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.advanceTo(m.clock + MaxRunMs)
+}
+
+// CrashWorker kills a worker now (atMs <= current time) or schedules the crash
+func (m *Master) CrashWorker(id int, atMs int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if id < 0 || id >= len(m.workers) {
+		return fmt.Errorf("no worker %d", id)
+	}
+	if atMs > m.clock {
+		m.crashes = append(m.crashes, ScheduledCrash{AtMs: atMs, Target: "worker", Worker: id})
+		return nil
+	}
+	if !m.workers[id].alive {
+		return fmt.Errorf("worker %d is already down", id)
+	}
+	m.crashWorker(id)
+	return nil
+}
+
+// CrashMaster kills the master now (atMs <= current time) or schedules the crash
+func (m *Master) CrashMaster(atMs int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if atMs > m.clock {
+		m.crashes = append(m.crashes, ScheduledCrash{AtMs: atMs, Target: "master", Worker: -1})
+		return nil
+	}
+	if m.status != MasterRunning {
+		return fmt.Errorf("master is not running")
+	}
+	m.crashMaster()
+	return nil
+}
+
+// RecoverWorker restarts a crashed worker with an empty local disk
+func (m *Master) RecoverWorker(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if id < 0 || id >= len(m.workers) {
+		return fmt.Errorf("no worker %d", id)
+	}
+	w := m.workers[id]
+	if w.alive {
+		return fmt.Errorf("worker %d is running", id)
+	}
+	if m.status == MasterRecovering {
+		return fmt.Errorf("master is down; wait for it to recover")
+	}
+
+	// The restarted worker registers with a new incarnation, so the master
+	// learns its disk is gone even if it never noticed the crash
+	if !w.failed && m.status == MasterRunning {
+		m.failWorker(id, "re-registered after a restart")
+	}
+	w.alive = true
+	w.failed = false
+	w.task = -1
+	w.incarnation++
+	w.lastHeartbeat = m.clock
+	w.nextHeartbeat = m.clock + m.config.HeartbeatMs
+	m.logEvent("worker-recovered", id, "", fmt.Sprintf("worker %d rejoined with an empty disk", id))
+	m.schedule()
+	return nil
+}
+
+// Reset restarts the current job from scratch
+func (m *Master) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.resetLocked()
+}
+
+// resetLocked rebuilds workers and tasks (must be called with lock held)
+func (m *Master) resetLocked() {
+	m.status = MasterIdle
+	m.clock = 0
+	m.completionMs = 0
+	m.workers = make([]*simWorker, m.config.Workers)
+	for i := range m.workers {
+		m.workers[i] = &simWorker{alive: true, task: -1, startedAt: -1, nextHeartbeat: m.config.HeartbeatMs}
+	}
+
+	reducers := m.job.NumReducers
+	m.numMaps = len(m.documents)
+	m.tasks = make([]MasterTask, 0, m.numMaps+reducers)
+	for i := range m.documents {
+		m.tasks = append(m.tasks, MasterTask{
+			ID: fmt.Sprintf("m%d", i), Kind: TaskMap, Index: i, State: TaskIdle,
+			Worker: -1, Backup: -1, InputID: fmt.Sprintf("doc%d", i+1), OutputOn: -1,
+		})
+	}
+	for r := 0; r < reducers; r++ {
+		m.tasks = append(m.tasks, MasterTask{
+			ID: fmt.Sprintf("r%d", r), Kind: TaskReduce, Index: r, State: TaskIdle,
+			Worker: -1, Backup: -1, OutputOn: -1,
+		})
+	}
+
+	m.mapOutputs = make([][][]KeyValue, m.numMaps)
+	m.outputEpochs = make([]int, m.numMaps)
+	m.fetched = make([][]KeyValue, reducers)
+	m.reduceOutputs = make([][]KeyValue, reducers)
+	m.attempts = []TaskAttempt{}
+	m.events = []MasterEvent{}
+	m.crashes = []ScheduledCrash{}
+	m.stats = MasterStats{}
+	m.nextCheck = m.config.HeartbeatMs
+	m.nextSave = m.config.CheckpointMs
+	m.checkpoint = nil
+	m.saveCheckpoint()
+}
+
+// advanceTo processes every event up to target (must be called with lock held)
+func (m *Master) advanceTo(target int64) {
+	for {
+		m.schedule()
+		next := m.nextEvent()
+		if next > target {
+			break
+		}
+		m.clock = next
+		m.processDue()
+	}
+	if m.status == MasterRunning || m.status == MasterRecovering {
+		m.clock = target
+	}
+}
+
+// nextEvent returns the time of the earliest pending event (must be called with lock held)
+func (m *Master) nextEvent() int64 {
+	next := int64(never)
+	for _, crash := range m.crashes {
+		next = minTime(next, crash.AtMs)
+	}
+	if m.status != MasterRunning && m.status != MasterRecovering {
+		return next
+	}
+
+	if m.status == MasterRecovering {
+		next = minTime(next, m.masterUpAt)
+	} else {
+		next = minTime(next, m.nextCheck)
+		if m.config.CheckpointMs > 0 {
+			next = minTime(next, m.nextSave)
+		}
+	}
+	for _, w := range m.workers {
+		if !w.alive {
+			continue
+		}
+		next = minTime(next, w.nextHeartbeat)
+		if w.task >= 0 {
+			next = minTime(next, w.busyUntil)
+		}
+	}
+	return next
+}
+
+// processDue handles everything due at the current time (must be called with lock held)
+func (m *Master) processDue() {
+	now := m.clock
+
+	// Injected crashes
+	pending := m.crashes[:0]
+	var due []ScheduledCrash
+	for _, crash := range m.crashes {
+		if crash.AtMs <= now {
+			due = append(due, crash)
+		} else {
+			pending = append(pending, crash)
+		}
+	}
+	m.crashes = pending
+	for _, crash := range due {
+		if crash.Target == "master" {
+			if m.status == MasterRunning {
+				m.crashMaster()
+			}
+		} else if m.workers[crash.Worker].alive {
+			m.crashWorker(crash.Worker)
+		}
+	}
+
+	if m.status == MasterRecovering && m.masterUpAt <= now {
+		m.restoreCheckpoint()
+	}
+
+	// Finished tasks, then heartbeats
+	for id, w := range m.workers {
+		if w.alive && w.task >= 0 && w.busyUntil <= now {
+			m.finishTask(id)
+		}
+	}
+	for _, w := range m.workers {
+		if w.alive && w.nextHeartbeat <= now {
+			if m.status == MasterRunning {
+				w.lastHeartbeat = now
+				m.stats.Heartbeats++
+			}
+			w.nextHeartbeat = now + m.config.HeartbeatMs
+		}
+	}
+
+	if m.status != MasterRunning {
+		return
+	}
+
+	// Health check: declare silent workers failed
+	if m.nextCheck <= now {
+		for id, w := range m.workers {
+			if !w.failed && now-w.lastHeartbeat > m.config.FailureTimeoutMs {
+				m.failWorker(id, fmt.Sprintf("no heartbeat for %d ms", now-w.lastHeartbeat))
+			}
+		}
+		m.nextCheck = now + m.config.HeartbeatMs
+	}
+
+	if m.config.CheckpointMs > 0 && m.nextSave <= now {
+		m.saveCheckpoint()
+		m.nextSave = now + m.config.CheckpointMs
+	}
+}
+
+// schedule hands idle tasks to idle workers (must be called with lock held)
+// Reduce tasks wait until every map task has completed
+func (m *Master) schedule() {
+	if m.status != MasterRunning {
+		return
+	}
+	for id, w := range m.workers {
+		// The master cannot tell a crashed worker from a live one until it times out
+		if w.failed || w.task >= 0 {
+			continue
+		}
+		if task := m.nextIdleTask(); task >= 0 {
+			m.assign(task, id, false)
+		} else if task := m.nextBackupTask(id); task >= 0 {
+			m.assign(task, id, true)
+		} else {
+			return
+		}
+	}
+}
+
+// nextBackupTask picks the longest-running task of the current phase without a backup
+// Returns -1 unless speculation is on and the phase has no idle tasks left (must be called with lock held)
+func (m *Master) nextBackupTask(id int) int {
+	if !m.config.Speculative {
+		return -1
+	}
+
+	start, end := 0, m.numMaps
+	if m.mapsDone() {
+		if !m.outputsFetchable() {
+			return -1
+		}
+		start, end = m.numMaps, len(m.tasks)
+	}
+
+	best := -1
+	for i := start; i < end; i++ {
+		task := m.tasks[i]
+		if task.State == TaskIdle {
+			return -1
+		}
+		if task.State != TaskInProgress || task.Backup >= 0 || task.Worker == id {
+			continue
+		}
+		if best < 0 || task.StartMs < m.tasks[best].StartMs {
+			best = i
+		}
+	}
+	return best
+}
+
+// nextIdleTask returns the index of the next task to run, or -1 (must be called with lock held)
+func (m *Master) nextIdleTask() int {
+	mapsDone := true
+	for i := 0; i < m.numMaps; i++ {
+		if m.tasks[i].State == TaskIdle {
+			return i
+		}
+		if m.tasks[i].State != TaskCompleted {
+			mapsDone = false
+		}
+	}
+	if !mapsDone || !m.outputsFetchable() {
+		return -1
+	}
+	for i := m.numMaps; i < len(m.tasks); i++ {
+		if m.tasks[i].State == TaskIdle {
+			return i
+		}
+	}
+	return -1
+}
+
+// mapsDone reports whether every map task has completed (must be called with lock held)
+func (m *Master) mapsDone() bool {
+	for _, task := range m.tasks[:m.numMaps] {
+		if task.State != TaskCompleted {
+			return false
+		}
+	}
+	return true
+}
+
+// outputsFetchable reports whether a reduce task could copy every map output
+// A worker that has crashed or restarted serves nothing even before the master
+// declares it failed, so reduces wait for the lost maps to be re-executed
+// (must be called with lock held)
+func (m *Master) outputsFetchable() bool {
+	for i, task := range m.tasks[:m.numMaps] {
+		if task.OutputOn < 0 {
+			continue
+		}
+		w := m.workers[task.OutputOn]
+		if !w.alive || m.outputEpochs[i] != w.incarnation {
+			return false
+		}
+	}
+	return true
+}
+
+// assign starts a task, or a backup copy of one, on a worker (must be called with lock held)
+func (m *Master) assign(index, id int, backup bool) {
+	task := &m.tasks[index]
+	w := m.workers[id]
+
+	base := m.config.MapTaskMs
+	if task.Kind == TaskReduce {
+		base = m.config.ReduceTaskMs
+		m.stats.ReduceAttempts++
+
+		// Copy this reducer's partition from every map output now, so a later
+		// mapper failure does not affect a reduce task already under way
+		var input []KeyValue
+		for _, partitions := range m.mapOutputs[:m.numMaps] {
+			input = append(input, partitions[task.Index]...)
+		}
+		m.fetched[task.Index] = input
+	} else {
+		m.stats.MapAttempts++
+	}
+
+	task.Attempts++
+	if backup {
+		task.Backup = id
+		m.stats.BackupAttempts++
+		m.logEvent("backup", id, task.ID, fmt.Sprintf("speculative copy of the task running on worker %d", task.Worker))
+	} else {
+		task.State = TaskInProgress
+		task.Worker = id
+		task.StartMs = m.clock
+		task.EndMs = 0
+	}
+
+	duration := int64(float64(base) / m.config.speed(id))
+	if duration < 1 {
+		duration = 1
+	}
+	w.task = index
+	w.busyUntil = m.clock + duration
+	w.startedAt = m.clock
+	w.attempt = len(m.attempts)
+	m.attempts = append(m.attempts, TaskAttempt{Task: task.ID, Worker: id, StartMs: m.clock, Outcome: "running", Backup: backup})
+	if len(m.attempts) > maxAttempts {
+		drop := len(m.attempts) - maxAttempts
+		m.attempts = append([]TaskAttempt{}, m.attempts[drop:]...)
+		for _, other := range m.workers {
+			other.attempt -= drop
+		}
+	}
+}
+
+// finishTask completes a worker's running task (must be called with lock held)
+func (m *Master) finishTask(id int) {
+	w := m.workers[id]
+	index := w.task
+	task := &m.tasks[index]
+	w.task = -1
+	w.completed++
+
+	if m.status != MasterRunning {
+		m.endAttempt(w, "unreported")
+		m.logEvent("unreported", id, task.ID, "finished while the master was down; the result is lost")
+		return
+	}
+	m.endAttempt(w, "completed")
+
+	// The first copy to finish wins; the other one is killed
+	other := task.Backup
+	if id == task.Backup {
+		other = task.Worker
+		m.stats.BackupWins++
+	}
+	if other >= 0 && other != id {
+		loser := m.workers[other]
+		m.endAttempt(loser, "killed")
+		loser.task = -1
+	}
+
+	task.State = TaskCompleted
+	task.Worker = -1
+	task.Backup = -1
+	task.EndMs = m.clock
+
+	if task.Kind == TaskMap {
+		m.mapOutputs[task.Index] = m.runMap(task.Index)
+		m.outputEpochs[task.Index] = w.incarnation
+		task.OutputOn = id
+		return
+	}
+
+	m.reduceOutputs[task.Index] = m.runReduce(m.fetched[task.Index])
+	for _, t := range m.tasks[m.numMaps:] {
+		if t.State != TaskCompleted {
+			return
+		}
+	}
+	m.status = MasterCompleted
+	m.completionMs = m.clock
+	m.logEvent("completed", -1, "", fmt.Sprintf("job finished at %d ms", m.clock))
+}
+
+// runMap applies the map function (and combiner) to one document and partitions its output by reducer
+func (m *Master) runMap(index int) [][]KeyValue {
+	var pairs []KeyValue
+	m.functions.Map(fmt.Sprintf("doc%d", index+1), m.documents[index], func(key, value string) {
+		pairs = append(pairs, KeyValue{Key: key, Value: value})
+	})
+	if m.job.Combine {
+		pairs = combinePairs(pairs, m.functions.Combine)
+	}
+
+	partitions := make([][]KeyValue, m.job.NumReducers)
+	for _, pair := range pairs {
+		r := m.partition(pair.Key, m.job.NumReducers)
+		partitions[r] = append(partitions[r], pair)
+	}
+	return partitions
+}
+
+// runReduce groups a reducer's input by key and applies the reduce function
+func (m *Master) runReduce(input []KeyValue) []KeyValue {
+	grouped := make(map[string][]string)
+	for _, pair := range input {
+		grouped[pair.Key] = append(grouped[pair.Key], pair.Value)
+	}
+	keys := make([]string, 0, len(grouped))
+	for key := range grouped {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	output := make([]KeyValue, 0, len(keys))
+	for _, key := range keys {
+		if value, keep := m.functions.Reduce(key, grouped[key]); keep {
+			output = append(output, KeyValue{Key: key, Value: value})
+		}
+	}
+	return output
+}
+
+// crashWorker stops a worker; the master only finds out by missing heartbeats (must be called with lock held)
+func (m *Master) crashWorker(id int) {
+	w := m.workers[id]
+	w.alive = false
+	if w.task >= 0 {
+		m.endAttempt(w, "lost")
+	}
+	m.logEvent("worker-crash", id, "", fmt.Sprintf("worker %d crashed", id))
+}
+
+// failWorker is the master reacting to a failed worker (must be called with lock held)
+// In-progress tasks go back to idle. Completed map tasks are re-run because their
+// output lived on the worker's local disk; completed reduce output is already in
+// the global file system and is kept
+func (m *Master) failWorker(id int, reason string) {
+	w := m.workers[id]
+	w.failed = true
+	m.stats.WorkerFailures++
+	m.logEvent("worker-failed", id, "", fmt.Sprintf("worker %d declared failed: %s", id, reason))
+
+	reducesPending := false
+	for _, t := range m.tasks[m.numMaps:] {
+		if t.State != TaskCompleted {
+			reducesPending = true
+		}
+	}
+
+	for i := range m.tasks {
+		task := &m.tasks[i]
+		switch {
+		case task.State == TaskInProgress && task.Backup == id:
+			task.Backup = -1
+		case task.State == TaskInProgress && task.Worker == id && task.Backup >= 0:
+			task.Worker = task.Backup
+			task.Backup = -1
+			m.logEvent("reassign", id, task.ID, fmt.Sprintf("backup on worker %d carries on", task.Worker))
+		case task.State == TaskInProgress && task.Worker == id:
+			if task.Kind == TaskReduce {
+				m.stats.ReassignedReduces++
+			}
+			task.State = TaskIdle
+			task.Worker = -1
+			m.logEvent("reassign", id, task.ID, "in-progress task returned to idle")
+		case task.Kind == TaskMap && task.State == TaskCompleted && task.OutputOn == id && reducesPending:
+			task.State = TaskIdle
+			task.OutputOn = -1
+			m.mapOutputs[task.Index] = nil
+			m.stats.ReExecutedMaps++
+			m.logEvent("re-execute", id, task.ID, "completed map output lost with the worker's disk")
+		}
+	}
+	if w.task >= 0 {
+		m.endAttempt(w, "lost")
+		w.task = -1
+	}
+}
+
+// crashMaster stops the master (must be called with lock held)
+// Without checkpoints the job aborts, as in the original implementation
+func (m *Master) crashMaster() {
+	m.stats.MasterFailures++
+	if m.config.CheckpointMs == 0 {
+		m.status = MasterAborted
+		m.logEvent("master-crash", -1, "", "master crashed without checkpoints; job aborted")
+		return
+	}
+	m.status = MasterRecovering
+	m.masterUpAt = m.clock + m.config.MasterRecoveryMs
+	m.logEvent("master-crash", -1, "", fmt.Sprintf("master crashed; restarting from the %d ms checkpoint", m.checkpoint.timeMs))
+}
+
+// saveCheckpoint records the master's task state (must be called with lock held)
+func (m *Master) saveCheckpoint() {
+	failed := make([]bool, len(m.workers))
+	for i, w := range m.workers {
+		failed[i] = w.failed
+	}
+	m.checkpoint = &masterCheckpoint{
+		timeMs:        m.clock,
+		tasks:         append([]MasterTask(nil), m.tasks...),
+		mapOutputs:    append([][][]KeyValue(nil), m.mapOutputs...),
+		outputEpochs:  append([]int(nil), m.outputEpochs...),
+		reduceOutputs: append([][]KeyValue(nil), m.reduceOutputs...),
+		failed:        failed,
+	}
+	if m.clock > 0 {
+		m.stats.Checkpoints++
+	}
+}
+
+// restoreCheckpoint starts a new master from the last checkpoint (must be called with lock held)
+// Tasks that were in progress are re-run, and map outputs written by a worker
+// that has since restarted are treated as lost
+func (m *Master) restoreCheckpoint() {
+	cp := m.checkpoint
+	m.tasks = append([]MasterTask(nil), cp.tasks...)
+	m.mapOutputs = append([][][]KeyValue(nil), cp.mapOutputs...)
+	m.outputEpochs = append([]int(nil), cp.outputEpochs...)
+	m.reduceOutputs = append([][]KeyValue(nil), cp.reduceOutputs...)
+
+	for id, w := range m.workers {
+		if w.task >= 0 {
+			m.endAttempt(w, "abandoned")
+			w.task = -1
+		}
+		w.failed = cp.failed[id] && !w.alive
+		w.lastHeartbeat = m.clock
+	}
+	for i := range m.tasks {
+		task := &m.tasks[i]
+		if task.State == TaskInProgress {
+			task.State = TaskIdle
+			task.Worker = -1
+			task.Backup = -1
+		}
+		if task.Kind == TaskMap && task.State == TaskCompleted && m.outputEpochs[i] != m.workers[task.OutputOn].incarnation {
+			task.State = TaskIdle
+			task.OutputOn = -1
+			m.mapOutputs[i] = nil
+			m.stats.ReExecutedMaps++
+		}
+	}
+
+	m.status = MasterRunning
+	m.nextCheck = m.clock + m.config.HeartbeatMs
+	m.nextSave = m.clock + m.config.CheckpointMs
+	m.logEvent("master-recovered", -1, "", fmt.Sprintf("new master resumed from the %d ms checkpoint", cp.timeMs))
+}
+
+// endAttempt closes a worker's running attempt (must be called with lock held)
+// Its worker time is counted here, since the attempt may have been trimmed from the log
+func (m *Master) endAttempt(w *simWorker, outcome string) {
+	if w.startedAt >= 0 {
+		m.stats.WorkMs += m.clock - w.startedAt
+		w.startedAt = -1
+	}
+	if w.attempt >= 0 && w.attempt < len(m.attempts) && m.attempts[w.attempt].Outcome == "running" {
+		m.attempts[w.attempt].EndMs = m.clock
+		m.attempts[w.attempt].Outcome = outcome
+	}
+}
+
+// logEvent appends to the master's log (must be called with lock held)
+func (m *Master) logEvent(kind string, worker int, task, message string) {
+	m.events = append(m.events, MasterEvent{TimeMs: m.clock, Kind: kind, Worker: worker, Task: task, Message: message})
+	if len(m.events) > maxMasterEvents {
+		m.events = append([]MasterEvent{}, m.events[len(m.events)-maxMasterEvents:]...)
+	}
+}
+
+// GetState returns the master's state for visualization
+func (m *Master) GetState() MasterState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	workers := make([]WorkerView, len(m.workers))
+	for id, w := range m.workers {
+		view := WorkerView{
+			ID:              id,
+			Status:          "idle",
+			Speed:           m.config.speed(id),
+			LastHeartbeatMs: w.lastHeartbeat,
+			TasksCompleted:  w.completed,
+			Incarnation:     w.incarnation,
+		}
+		switch {
+		case !w.alive && w.failed:
+			view.Status = "failed"
+		case !w.alive:
+			view.Status = "crashed"
+		case w.task >= 0:
+			view.Status = "busy"
+		}
+		if w.task >= 0 {
+			view.Task = m.tasks[w.task].ID
+		}
+		workers[id] = view
+	}
+
+	phase := "map"
+	if m.mapsDone() {
+		phase = "reduce"
+	}
+	if m.status == MasterCompleted {
+		phase = "done"
+	}
+
+	output := []KeyValue{}
+	for _, partition := range m.reduceOutputs {
+		output = append(output, partition...)
+	}
+	sort.Slice(output, func(a, b int) bool { return output[a].Key < output[b].Key })
+
+	lastCheckpoint := int64(0)
+	if m.checkpoint != nil {
+		lastCheckpoint = m.checkpoint.timeMs
+	}
+
+	return MasterState{
+		Config:         m.config.clone(),
+		JobType:        m.job.JobType,
+		Status:         m.status,
+		Phase:          phase,
+		TimeMs:         m.clock,
+		CompletionMs:   m.completionMs,
+		LastCheckpoint: lastCheckpoint,
+		Workers:        workers,
+		Tasks:          append([]MasterTask{}, m.tasks...),
+		Attempts:       append([]TaskAttempt{}, m.attempts...),
+		Events:         append([]MasterEvent{}, m.events...),
+		PendingCrashes: append([]ScheduledCrash{}, m.crashes...),
+		Stats:          m.stats,
+		FinalOutput:    output,
+	}
+}
+
+// minTime returns the earlier of two times
+func minTime(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,189 @@
+package mapreduce
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMasterConfigIsCopied(t *testing.T) {
+	config := DefaultMasterConfig()
+	config.WorkerSpeeds = []float64{1, 0.5}
+	m, err := NewMaster(config, DefaultJobConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config.WorkerSpeeds[0] = 0.01
+	got := m.Config()
+	got.WorkerSpeeds[1] = 0.01
+	m.GetState().Config.WorkerSpeeds[1] = 0.01
+
+	if speeds := m.Config().WorkerSpeeds; speeds[0] != 1 || speeds[1] != 0.5 {
+		t.Errorf("worker speeds = %v, want [1 0.5]", speeds)
+	}
+}
+
+func TestMasterWorkMs(t *testing.T) {
+	config := DefaultMasterConfig()
+	config.WorkerSpeeds = []float64{1, 1, 1, 0.25}
+	config.Speculative = true
+	m, err := NewMaster(config, DefaultJobConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	m.Run()
+
+	state := m.GetState()
+	var want int64
+	for _, attempt := range state.Attempts {
+		want += attempt.EndMs - attempt.StartMs
+	}
+	if state.Stats.WorkMs != want || want == 0 {
+		t.Errorf("WorkMs = %d, attempts add up to %d", state.Stats.WorkMs, want)
+	}
+}
+
+func TestReduceWaitsForCrashedMapOutput(t *testing.T) {
+	config := DefaultMasterConfig()
+	config.WorkerSpeeds = []float64{1, 0.5}
+	m, err := NewMaster(config, DefaultJobConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	// Worker 0 finishes its map at 1000 ms and crashes before worker 1's map ends
+	if err := m.CrashWorker(0, 1900); err != nil {
+		t.Fatal(err)
+	}
+	m.Run()
+
+	state := m.GetState()
+	if state.Status != MasterCompleted {
+		t.Fatalf("status = %s, want completed", state.Status)
+	}
+	var declared int64 = -1
+	for _, event := range state.Events {
+		if event.Kind == "worker-failed" && event.Worker == 0 {
+			declared = event.TimeMs
+		}
+	}
+	if declared < 0 {
+		t.Fatal("worker 0 was never declared failed")
+	}
+	for _, attempt := range state.Attempts {
+		if strings.HasPrefix(attempt.Task, "r") && attempt.StartMs >= 1900 && attempt.StartMs < declared {
+			t.Errorf("%s started at %d ms, while worker 0 was down holding map output (declared failed at %d ms)", attempt.Task, attempt.StartMs, declared)
+		}
+	}
+}
+
+func TestMasterRecoveryOutput(t *testing.T) {
+	run := func(config MasterConfig, crash func(m *Master) error) MasterState {
+		m, err := NewMaster(config, DefaultJobConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Start(); err != nil {
+			t.Fatal(err)
+		}
+		if err := crash(m); err != nil {
+			t.Fatal(err)
+		}
+		m.Run()
+		return m.GetState()
+	}
+	want := run(DefaultMasterConfig(), func(*Master) error { return nil }).FinalOutput
+	if len(want) == 0 {
+		t.Fatal("failure-free run produced no output")
+	}
+
+	// The failure-free job maps until 1000 ms and reduces on workers 0 and 1 until 2000 ms
+	tests := []struct {
+		name           string
+		checkpointMs   int64
+		crash          func(m *Master) error
+		workerFailures int
+		masterFailures int
+	}{
+		{"worker during map", 2000, func(m *Master) error { return m.CrashWorker(0, 500) }, 1, 0},
+		{"idle worker holding map output", 2000, func(m *Master) error { return m.CrashWorker(2, 1200) }, 1, 0},
+		{"worker during reduce", 2000, func(m *Master) error { return m.CrashWorker(1, 1500) }, 1, 0},
+		{"master before any checkpoint", 2000, func(m *Master) error { return m.CrashMaster(1500) }, 0, 1},
+		{"master after a checkpoint", 500, func(m *Master) error { return m.CrashMaster(1700) }, 0, 1},
+		{"worker then master", 500, func(m *Master) error {
+			if err := m.CrashWorker(2, 800); err != nil {
+				return err
+			}
+			return m.CrashMaster(1200)
+		}, 1, 1},
+	}
+	for _, tt := range tests {
+		config := DefaultMasterConfig()
+		config.CheckpointMs = tt.checkpointMs
+		state := run(config, tt.crash)
+		if state.Status != MasterCompleted {
+			t.Errorf("%s: status = %s, want completed", tt.name, state.Status)
+			continue
+		}
+		if state.Stats.WorkerFailures != tt.workerFailures || state.Stats.MasterFailures != tt.masterFailures {
+			t.Errorf("%s: %d worker and %d master failures, want %d and %d", tt.name,
+				state.Stats.WorkerFailures, state.Stats.MasterFailures, tt.workerFailures, tt.masterFailures)
+		}
+		if !reflect.DeepEqual(state.FinalOutput, want) {
+			t.Errorf("%s: output differs from the failure-free run", tt.name)
+		}
+	}
+
+	// Without checkpoints a master crash aborts the job
+	config := DefaultMasterConfig()
+	config.CheckpointMs = 0
+	if state := run(config, func(m *Master) error { return m.CrashMaster(1500) }); state.Status != MasterAborted {
+		t.Errorf("status without checkpoints = %s, want aborted", state.Status)
+	}
+}
+
+func TestMasterConfigBounds(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *MasterConfig)
+	}{
+		{"tiny worker speed", func(c *MasterConfig) { c.WorkerSpeeds = []float64{1e-17} }},
+		{"NaN worker speed", func(c *MasterConfig) { c.WorkerSpeeds = []float64{math.NaN()} }},
+		{"huge map task", func(c *MasterConfig) { c.MapTaskMs = math.MaxInt64 / 2 }},
+		{"huge reduce task", func(c *MasterConfig) { c.ReduceTaskMs = MaxRunMs + 1 }},
+		{"huge heartbeat", func(c *MasterConfig) { c.HeartbeatMs, c.FailureTimeoutMs = MaxRunMs+1, MaxRunMs+1 }},
+		{"huge failure timeout", func(c *MasterConfig) { c.FailureTimeoutMs = MaxRunMs + 1 }},
+		{"huge checkpoint interval", func(c *MasterConfig) { c.CheckpointMs = MaxRunMs + 1 }},
+		{"huge master recovery", func(c *MasterConfig) { c.MasterRecoveryMs = MaxRunMs + 1 }},
+	}
+	for _, tt := range tests {
+		config := DefaultMasterConfig()
+		tt.modify(&config)
+		if err := config.Validate(); err == nil {
+			t.Errorf("%s: config accepted", tt.name)
+		}
+	}
+
+	// The slowest allowed straggler on the longest task is still running when a run ends
+	config := DefaultMasterConfig()
+	config.WorkerSpeeds = []float64{MinWorkerSpeed}
+	config.MapTaskMs = MaxRunMs
+	m, err := NewMaster(config, DefaultJobConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	m.Run()
+	if worker := m.GetState().Workers[0]; worker.Status != "busy" {
+		t.Errorf("straggler is %s after %d ms, want busy", worker.Status, MaxRunMs)
+	}
+}
@@ -0,0 +1,308 @@
+package mapreduce
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// PartitionFunc assigns an intermediate key to one of reducers partitions
+type PartitionFunc func(key string, reducers int) int
+
+// PartitionerInfo describes a partitioner
+type PartitionerInfo struct {
+	Key         string `json:"key"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// SkewThreshold is the reducer imbalance (heaviest load over mean load) flagged as skew
+const SkewThreshold = 1.5
+
+// maxSampleDocuments bounds the documents mapped to sample range boundaries
+const maxSampleDocuments = 100
+
+// Built-in partitioners; custom ones are added with RegisterPartitioner
+var builtinPartitioners = []PartitionerInfo{
+	{Key: "hash", Name: "Hash", Description: "hash(key) mod R; spreads distinct keys evenly but cannot split a hot key"},
+	{Key: "range", Name: "Range", Description: "Sorted key ranges split at boundaries, sampled from map output when none are given (as in TeraSort)"},
+}
+
+var (
+	partitionerMu    sync.RWMutex
+	partitioners     = map[string]PartitionerInfo{}
+	customPartitions = map[string]PartitionFunc{}
+	partitionerOrder []string // registration order of custom partitioners
+)
+
+func init() {
+	RegisterPartitioner(PartitionerInfo{
+		Key:         "first-letter",
+		Name:        "First Letter",
+		Description: "Custom function splitting the alphabet into R equal letter ranges; natural text skews it badly",
+	}, func(key string, reducers int) int {
+		if key == "" {
+			return 0
+		}
+		c := key[0]
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		if c < 'a' || c > 'z' {
+			return 0
+		}
+		return int(c-'a') * reducers / 26
+	})
+}
+
+// RegisterPartitioner makes a custom partition function available under info.Key
+func RegisterPartitioner(info PartitionerInfo, fn PartitionFunc) {
+	partitionerMu.Lock()
+	defer partitionerMu.Unlock()
+
+	if _, exists := partitioners[info.Key]; exists || info.Key == "hash" || info.Key == "range" {
+		panic("mapreduce: partitioner registered twice: " + info.Key)
+	}
+	partitioners[info.Key] = info
+	customPartitions[info.Key] = fn
+	partitionerOrder = append(partitionerOrder, info.Key)
+}
+
+// RegisteredPartitioners lists the built-in partitioners followed by custom ones
+func RegisteredPartitioners() []PartitionerInfo {
+	partitionerMu.RLock()
+	defer partitionerMu.RUnlock()
+
+	infos := append([]PartitionerInfo{}, builtinPartitioners...)
+	for _, key := range partitionerOrder {
+		infos = append(infos, partitioners[key])
+	}
+	return infos
+}
+
+// newPartition builds the partition function for a job
+// The range partitioner samples its boundaries from the map output of the documents when none are given
+func newPartition(name string, boundaries []string, functions Functions, documents []string, reducers int) (PartitionFunc, []string, error) {
+	switch name {
+	case "", "hash":
+		return func(key string, reducers int) int {
+			return hashKey(key) % reducers
+		}, nil, nil
+	case "range":
+		if len(boundaries) == 0 {
+			boundaries = sampleBoundaries(functions, documents, reducers)
+		}
+		boundaries = append([]string(nil), boundaries...)
+		sort.Strings(boundaries)
+		return func(key string, reducers int) int {
+			// Keys below the first boundary go to reducer 0, and so on
+			r := sort.Search(len(boundaries), func(i int) bool { return key < boundaries[i] })
+			if r >= reducers {
+				r = reducers - 1
+			}
+			return r
+		}, boundaries, nil
+	}
+
+	partitionerMu.RLock()
+	fn, exists := customPartitions[name]
+	partitionerMu.RUnlock()
+	if !exists {
+		return nil, nil, fmt.Errorf("unknown partitioner %q", name)
+	}
+	return func(key string, reducers int) int {
+		// Guard against custom functions returning out-of-range partitions
+		r := fn(key, reducers) % reducers
+		if r < 0 {
+			r += reducers
+		}
+		return r
+	}, nil, nil
+}
+
+// sampleBoundaries picks R-1 split keys so each range receives about the same number of pairs
+func sampleBoundaries(functions Functions, documents []string, reducers int) []string {
+	counts := make(map[string]int)
+	total := 0
+	for i, document := range documents {
+		if i == maxSampleDocuments {
+			break
+		}
+		functions.Map(fmt.Sprintf("doc%d", i+1), document, func(key, value string) {
+			counts[key]++
+			total++
+		})
+	}
+
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var boundaries []string
+	seen := 0
+	for i, key := range keys {
+		// Start a new range at this key once the next equal-share mark is passed,
+		// or already before it when that lands nearer the mark (so a hot key gets
+		// a range to itself instead of swelling the previous one)
+		next := seen + counts[key]
+		mark := (len(boundaries) + 1) * total
+		if i > 0 && len(boundaries) < reducers-1 &&
+			(seen*reducers >= mark || (next*reducers > mark && mark-seen*reducers < next*reducers-mark)) {
+			boundaries = append(boundaries, key)
+		}
+		seen = next
+	}
+	return boundaries
+}
+
+// combinePairs groups one map task's output by key and pre-aggregates it with the combiner
+func combinePairs(pairs []KeyValue, combine ReduceFunc) []KeyValue {
+	grouped := make(map[string][]string)
+	for _, pair := range pairs {
+		grouped[pair.Key] = append(grouped[pair.Key], pair.Value)
+	}
+	keys := make([]string, 0, len(grouped))
+	for key := range grouped {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	combined := make([]KeyValue, 0, len(keys))
+	for _, key := range keys {
+		if value, keep := combine(key, grouped[key]); keep {
+			combined = append(combined, KeyValue{Key: key, Value: value})
+		}
+	}
+	return combined
+}
+
+// ReducerLoad is the shuffle input of one reducer
+type ReducerLoad struct {
+	Reducer      int     `json:"reducer"`
+	Keys         int     `json:"keys"`
+	Pairs        int     `json:"pairs"`
+	Bytes        int     `json:"bytes"`
+	Share        float64 `json:"share"` // Fraction of all shuffled pairs
+	HottestKey   string  `json:"hottestKey"`
+	HottestPairs int     `json:"hottestPairs"`
+}
+
+// HotKey is a key whose pairs alone exceed a reducer's fair share
+// No partitioner can balance it; only a combiner (or splitting the key) helps
+type HotKey struct {
+	Key     string  `json:"key"`
+	Pairs   int     `json:"pairs"`
+	Share   float64 `json:"share"`
+	Reducer int     `json:"reducer"`
+}
+
+// ShuffleStats describes how intermediate data was spread over reducers
+type ShuffleStats struct {
+	Partitioner    string        `json:"partitioner"`
+	Combine        bool          `json:"combine"`
+	Boundaries     []string      `json:"boundaries,omitempty"`
+	MapOutputPairs int           `json:"mapOutputPairs"` // Pairs emitted by map functions
+	ShuffledPairs  int           `json:"shuffledPairs"`  // Pairs sent to reducers after combining
+	ShuffledBytes  int           `json:"shuffledBytes"`
+	Reducers       []ReducerLoad `json:"reducers"`
+	Imbalance      float64       `json:"imbalance"` // Heaviest reducer's pairs over the mean
+	Skewed         bool          `json:"skewed"`    // Imbalance is at least SkewThreshold
+	HotKeys        []HotKey      `json:"hotKeys"`
+}
+
+// shuffleStats computes reducer loads from the shuffle's partitions
+func shuffleStats(partitioner string, combine bool, boundaries []string, mapOutputPairs int, partitions []ShufflePartition, reducers int) ShuffleStats {
+	stats := ShuffleStats{
+		Partitioner:    partitioner,
+		Combine:        combine,
+		Boundaries:     boundaries,
+		MapOutputPairs: mapOutputPairs,
+		Reducers:       make([]ReducerLoad, reducers),
+		HotKeys:        []HotKey{},
+	}
+	for r := range stats.Reducers {
+		stats.Reducers[r].Reducer = r
+	}
+
+	for _, partition := range partitions {
+		load := &stats.Reducers[partition.ReducerID]
+		pairs := len(partition.Values)
+		load.Keys++
+		load.Pairs += pairs
+		for _, value := range partition.Values {
+			load.Bytes += len(partition.Key) + len(value)
+		}
+		if pairs > load.HottestPairs {
+			load.HottestKey = partition.Key
+			load.HottestPairs = pairs
+		}
+		stats.ShuffledPairs += pairs
+	}
+
+	heaviest := 0
+	for r := range stats.Reducers {
+		load := &stats.Reducers[r]
+		stats.ShuffledBytes += load.Bytes
+		if stats.ShuffledPairs > 0 {
+			load.Share = float64(load.Pairs) / float64(stats.ShuffledPairs)
+		}
+		if load.Pairs > heaviest {
+			heaviest = load.Pairs
+		}
+	}
+	if stats.ShuffledPairs > 0 {
+		mean := float64(stats.ShuffledPairs) / float64(reducers)
+		stats.Imbalance = float64(heaviest) / mean
+		stats.Skewed = stats.Imbalance >= SkewThreshold
+
+		for _, partition := range partitions {
+			if float64(len(partition.Values)) > mean {
+				stats.HotKeys = append(stats.HotKeys, HotKey{
+					Key:     partition.Key,
+					Pairs:   len(partition.Values),
+					Share:   float64(len(partition.Values)) / float64(stats.ShuffledPairs),
+					Reducer: partition.ReducerID,
+				})
+			}
+		}
+		sort.Slice(stats.HotKeys, func(a, b int) bool { return stats.HotKeys[a].Pairs > stats.HotKeys[b].Pairs })
+	}
+	return stats
+}
+
+// AnalyzeShuffle runs a job's map and shuffle phases under every partitioner,
+// with and without its combiner (when it has one), and returns the reducer loads of each
+func AnalyzeShuffle(config JobConfig) ([]ShuffleStats, error) {
+	_, functions, _, err := lookupJob(config.JobType, config.Params)
+	if err != nil {
+		return nil, err
+	}
+	combines := []bool{false}
+	if functions.Combine != nil {
+		combines = append(combines, true)
+	}
+
+	var results []ShuffleStats
+	for _, info := range RegisteredPartitioners() {
+		for _, combine := range combines {
+			variant := config
+			variant.Partitioner = info.Key
+			variant.Combine = combine
+			if info.Key != "range" {
+				variant.Boundaries = nil
+			}
+
+			job, err := NewJob(variant)
+			if err != nil {
+				return nil, err
+			}
+			job.Start()
+			job.ExecuteMapPhase()
+			job.ExecuteShufflePhase()
+			results = append(results, *job.GetState().ShuffleStats)
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,98 @@
+package mapreduce
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+// overflowKey hashes to the minimum int64, which stays negative when negated
+const overflowKey = "feyzcnl|cv~wg"
+
+func TestHashPartitionInRange(t *testing.T) {
+	partition, _, err := newPartition("hash", nil, Functions{}, nil, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{overflowKey, "", "a", "hello", "the quick brown fox"} {
+		for reducers := 1; reducers <= 7; reducers++ {
+			if r := partition(key, reducers); r < 0 || r >= reducers {
+				t.Errorf("hash partition of %q over %d reducers = %d", key, reducers, r)
+			}
+		}
+	}
+}
+
+func TestShuffleOverflowKey(t *testing.T) {
+	job, err := NewJob(JobConfig{JobType: "wordcount", Documents: []string{overflowKey}, NumMappers: 1, NumReducers: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.Start()
+	job.ExecuteMapPhase()
+	job.ExecuteShufflePhase()
+	job.ExecuteReducePhase()
+
+	output := job.GetState().FinalOutput
+	if len(output) != 1 || output[0].Key != overflowKey || output[0].Value != "1" {
+		t.Errorf("output = %v, want [{%s 1}]", output, overflowKey)
+	}
+}
+
+func TestPartitioners(t *testing.T) {
+	_, functions, _, err := lookupJob("wordcount", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	documents := []string{"apple banana cherry", "delta echo foxtrot", "golf hotel india", "juliet kilo lima"}
+
+	tests := []struct {
+		name       string
+		boundaries []string
+		key        string
+		reducers   int
+		want       int
+	}{
+		{"hash", nil, "", 1, 0},
+		{"range", []string{"m", "f"}, "apple", 3, 0},
+		{"range", []string{"m", "f"}, "f", 3, 1},
+		{"range", []string{"m", "f"}, "kilo", 3, 1},
+		{"range", []string{"m", "f"}, "zulu", 3, 2},
+		{"range", []string{"c", "f", "m"}, "zulu", 2, 1}, // More boundaries than reducers clamp to the last
+		{"first-letter", nil, "apple", 2, 0},
+		{"first-letter", nil, "Zulu", 2, 1},
+		{"first-letter", nil, "42", 2, 0},
+		{"first-letter", nil, "", 2, 0},
+	}
+	for _, tt := range tests {
+		partition, _, err := newPartition(tt.name, tt.boundaries, functions, documents, tt.reducers)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := partition(tt.key, tt.reducers); got != tt.want {
+			t.Errorf("%s %v: partition(%q, %d) = %d, want %d", tt.name, tt.boundaries, tt.key, tt.reducers, got, tt.want)
+		}
+	}
+
+	// Sampled range boundaries are sorted and keep every key in range
+	for reducers := 1; reducers <= 4; reducers++ {
+		partition, boundaries, err := newPartition("range", nil, functions, documents, reducers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sort.StringsAreSorted(boundaries) || len(boundaries) > reducers-1 {
+			t.Errorf("%d reducers: sampled boundaries %v", reducers, boundaries)
+		}
+		for _, document := range documents {
+			for _, key := range strings.Fields(document) {
+				if r := partition(key, reducers); r < 0 || r >= reducers {
+					t.Errorf("range partition of %q over %d reducers = %d", key, reducers, r)
+				}
+			}
+		}
+	}
+
+	if _, _, err := newPartition("no-such-partitioner", nil, functions, documents, 2); err == nil {
+		t.Error("unknown partitioner was accepted")
+	}
+}
@@ -0,0 +1,748 @@
+package mapreduce
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// StageOp is the operation a pipeline stage applies
+type StageOp string
+
+const (
+	OpSource    StageOp = "source"    // Emits its documents as (docN, text) records
+	OpMapReduce StageOp = "mapreduce" // Runs a registered job over its input
+	OpMap       StageOp = "map"       // Transforms each record into zero or more records
+	OpFilter    StageOp = "filter"    // Keeps matching records
+	OpGroupBy   StageOp = "groupBy"   // Aggregates the values of each key
+	OpJoin      StageOp = "join"      // Inner-joins two inputs on their keys
+	OpTopK      StageOp = "topK"      // Keeps the records with the largest numeric values
+)
+
+// PipelineStatus is the progress of a pipeline
+type PipelineStatus string
+
+const (
+	PipelineIdle      PipelineStatus = "idle"
+	PipelineRunning   PipelineStatus = "running"
+	PipelineCompleted PipelineStatus = "completed"
+	PipelineFailed    PipelineStatus = "failed"
+)
+
+// Limits on pipelines
+const (
+	MaxStages       = 20
+	MaxStageRecords = 1000000  // Records a map, join or groupBy stage may output before it fails
+	MaxStageBytes   = 64 << 20 // Key and value bytes a map, join or groupBy stage may output before it fails
+	maxStageOutput  = 200      // Records of each stage returned in the state
+)
+
+// StageOpInfo describes a stage operation
+type StageOpInfo struct {
+	Op          StageOp    `json:"op"`
+	Description string     `json:"description"`
+	Inputs      int        `json:"inputs"`
+	Params      []JobParam `json:"params"`
+}
+
+var stageOps = []StageOpInfo{
+	{Op: OpSource, Description: "Reads documents; each becomes a (docN, text) record", Params: []JobParam{}},
+	{Op: OpMapReduce, Inputs: 1, Description: "Runs a registered MapReduce job; a source's documents are its input as-is, other records become \"key<TAB>value\" lines split over the mappers", Params: []JobParam{}},
+	{Op: OpMap, Inputs: 1, Description: "Transforms each record", Params: []JobParam{
+		{Name: "fn", Default: "words", Description: "words: (word, 1) per word of the value; lines: (key, line) per line; swap: (value, key); csv: keyed by a comma-separated column of the value"},
+		{Name: "column", Default: "0", Description: "Column used as the key by csv"},
+	}},
+	{Op: OpFilter, Inputs: 1, Description: "Keeps records whose field matches a pattern and is at least min", Params: []JobParam{
+		{Name: "field", Default: "value", Description: "key or value"},
+		{Name: "pattern", Default: "", Description: "Regular expression the field must match"},
+		{Name: "min", Default: "", Description: "Smallest numeric field kept; empty skips the check"},
+	}},
+	{Op: OpGroupBy, Inputs: 1, Description: "Groups records by key and aggregates their values", Params: []JobParam{
+		{Name: "aggregate", Default: "count", Description: "count, sum, min, max or collect (sorted distinct values)"},
+	}},
+	{Op: OpJoin, Inputs: 2, Description: "Inner join on key; values become left|right", Params: []JobParam{}},
+	{Op: OpTopK, Inputs: 1, Description: "Sorts by numeric value, largest first, and keeps k records", Params: []JobParam{
+		{Name: "k", Default: "10", Description: "Number of records to keep"},
+	}},
+}
+
+// StageOps lists the operations a pipeline stage can apply
+func StageOps() []StageOpInfo {
+	return stageOps
+}
+
+// StageConfig is one node of a pipeline DAG
+type StageConfig struct {
+	ID        string            `json:"id"`
+	Op        StageOp           `json:"op"`
+	Inputs    []string          `json:"inputs,omitempty"` // IDs of the stages feeding this one
+	Params    map[string]string `json:"params,omitempty"`
+	Documents []string          `json:"documents,omitempty"` // Source stages
+	Job       *JobConfig        `json:"job,omitempty"`       // MapReduce stages; documents come from the input
+}
+
+// PipelineConfig is a DAG of stages
+type PipelineConfig struct {
+	Name   string        `json:"name"`
+	Stages []StageConfig `json:"stages"`
+}
+
+// PipelinePresets returns example pipelines: a MapReduce chain and a dataflow DAG with a join
+func PipelinePresets() []PipelineConfig {
+	wordcount, _, _, _ := lookupJob("wordcount", nil)
+	join, _, _, _ := lookupJob("join", nil)
+	return []PipelineConfig{
+		{
+			Name: "wordcount-topk",
+			Stages: []StageConfig{
+				{ID: "input", Op: OpSource, Documents: wordcount.SampleInput},
+				{ID: "counts", Op: OpMapReduce, Inputs: []string{"input"}, Job: &JobConfig{JobType: "wordcount", NumMappers: 2, NumReducers: 2, Combine: true}},
+				{ID: "top", Op: OpMapReduce, Inputs: []string{"counts"}, Job: &JobConfig{JobType: "topk", NumMappers: 2, NumReducers: 1, Params: map[string]string{"k": "3"}}},
+			},
+		},
+		{
+			Name: "orders-per-user",
+			Stages: []StageConfig{
+				{ID: "tables", Op: OpSource, Documents: join.SampleInput},
+				{ID: "rows", Op: OpMap, Inputs: []string{"tables"}, Params: map[string]string{"fn": "lines"}},
+				{ID: "keyed", Op: OpMap, Inputs: []string{"rows"}, Params: map[string]string{"fn": "csv", "column": "1"}},
+				{ID: "users", Op: OpFilter, Inputs: []string{"keyed"}, Params: map[string]string{"pattern": "^users,"}},
+				{ID: "orders", Op: OpFilter, Inputs: []string{"keyed"}, Params: map[string]string{"pattern": "^orders,"}},
+				{ID: "joined", Op: OpJoin, Inputs: []string{"users", "orders"}},
+				{ID: "perUser", Op: OpGroupBy, Inputs: []string{"joined"}, Params: map[string]string{"aggregate": "count"}},
+			},
+		},
+	}
+}
+
+// DefaultPipelineConfig chains word count into a top-3 job
+func DefaultPipelineConfig() PipelineConfig {
+	return PipelinePresets()[0]
+}
+
+// StageState is the progress and intermediate data of one stage
+type StageState struct {
+	ID            string            `json:"id"`
+	Op            StageOp           `json:"op"`
+	Inputs        []string          `json:"inputs"`
+	Params        map[string]string `json:"params,omitempty"`
+	Status        string            `json:"status"` // "pending", "completed", "failed"
+	Error         string            `json:"error,omitempty"`
+	Sink          bool              `json:"sink"` // No stage reads its output
+	InputRecords  int               `json:"inputRecords"`
+	OutputRecords int               `json:"outputRecords"`
+	Output        []KeyValue        `json:"output"` // First records of the stage's output
+	Truncated     bool              `json:"truncated"`
+	Job           *JobState         `json:"job,omitempty"` // MapReduce stages: map tasks, shuffle and reduce tasks
+}
+
+// PipelineState represents the complete state of a pipeline
+type PipelineState struct {
+	Name         string         `json:"name"`
+	Status       PipelineStatus `json:"status"`
+	Order        []string       `json:"order"` // Stages in execution order
+	CurrentStage string         `json:"currentStage"`
+	Progress     int            `json:"progress"` // 0-100
+	Stages       []StageState   `json:"stages"`
+}
+
+// errTooManyRecords fails a stage whose output would exceed MaxStageRecords
+var errTooManyRecords = fmt.Errorf("stage output exceeds %d records", MaxStageRecords)
+
+// errTooManyBytes fails a stage whose output would exceed MaxStageBytes
+var errTooManyBytes = fmt.Errorf("stage output exceeds %d bytes", MaxStageBytes)
+
+// recordBytes is the size a record counts toward MaxStageBytes
+func recordBytes(record KeyValue) int {
+	return len(record.Key) + len(record.Value)
+}
+
+// stageFunc computes a stage's output from its inputs' outputs
+type stageFunc func(inputs [][]KeyValue) ([]KeyValue, error)
+
+// Pipeline runs a DAG of stages one at a time, keeping every intermediate dataset
+type Pipeline struct {
+	mu      sync.RWMutex
+	stepMu  sync.Mutex // Serializes running stages, which happens without mu
+	config  PipelineConfig
+	funcs   []stageFunc // nil for MapReduce stages
+	order   []int
+	next    int // Position in order of the next stage to run
+	status  PipelineStatus
+	outputs [][]KeyValue
+	jobs    []*JobState
+	errors  []string
+
+	generation int // Bumped on every reset, so a stage finishing afterwards is discarded
+}
+
+// NewPipeline creates a pipeline
+func NewPipeline(config PipelineConfig) (*Pipeline, error) {
+	p := &Pipeline{}
+	if err := p.Configure(config); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Configure validates a DAG and replaces the pipeline with it, idle
+func (p *Pipeline) Configure(config PipelineConfig) error {
+	if len(config.Stages) == 0 || len(config.Stages) > MaxStages {
+		return fmt.Errorf("a pipeline needs between 1 and %d stages", MaxStages)
+	}
+
+	index := make(map[string]int, len(config.Stages))
+	funcs := make([]stageFunc, len(config.Stages))
+	for i, stage := range config.Stages {
+		if stage.ID == "" {
+			return fmt.Errorf("stage %d has no id", i+1)
+		}
+		if _, exists := index[stage.ID]; exists {
+			return fmt.Errorf("duplicate stage id %q", stage.ID)
+		}
+		index[stage.ID] = i
+
+		fn, err := compileStage(stage)
+		if err != nil {
+			return fmt.Errorf("stage %q: %v", stage.ID, err)
+		}
+		funcs[i] = fn
+	}
+
+	order, err := topologicalOrder(config.Stages, index)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+	p.funcs = funcs
+	p.order = order
+	p.resetLocked()
+	return nil
+}
+
+// Config returns the pipeline's current configuration
+func (p *Pipeline) Config() PipelineConfig {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.config
+}
+
+// compileStage checks a stage's inputs and parameters and builds its function
+func compileStage(stage StageConfig) (stageFunc, error) {
+	var info *StageOpInfo
+	for i := range stageOps {
+		if stageOps[i].Op == stage.Op {
+			info = &stageOps[i]
+		}
+	}
+	if info == nil {
+		return nil, fmt.Errorf("unknown op %q", stage.Op)
+	}
+	if len(stage.Inputs) != info.Inputs {
+		return nil, fmt.Errorf("%s takes %d inputs, got %d", stage.Op, info.Inputs, len(stage.Inputs))
+	}
+
+	params := make(map[string]string, len(info.Params))
+	for _, param := range info.Params {
+		params[param.Name] = param.Default
+	}
+	for name, value := range stage.Params {
+		if _, known := params[name]; !known {
+			return nil, fmt.Errorf("%s has no parameter %q", stage.Op, name)
+		}
+		params[name] = value
+	}
+
+	switch stage.Op {
+	case OpSource:
+		if len(stage.Documents) == 0 || len(stage.Documents) > MaxDocuments {
+			return nil, fmt.Errorf("a source needs between 1 and %d documents", MaxDocuments)
+		}
+		size := 0
+		for _, document := range stage.Documents {
+			size += len(document)
+		}
+		if size > MaxInputBytes {
+			return nil, fmt.Errorf("input exceeds %d bytes", MaxInputBytes)
+		}
+		records := make([]KeyValue, len(stage.Documents))
+		for i, document := range stage.Documents {
+			records[i] = KeyValue{Key: fmt.Sprintf("doc%d", i+1), Value: document}
+		}
+		return func([][]KeyValue) ([]KeyValue, error) { return records, nil }, nil
+
+	case OpMapReduce:
+		if stage.Job == nil {
+			return nil, fmt.Errorf("a mapreduce stage needs a job")
+		}
+		// Check the job type, parameters and partitioner on placeholder input
+		job := mapReduceStageJob(*stage.Job, []string{""})
+		if _, err := prepareJob(job); err != nil {
+			return nil, err
+		}
+		return nil, nil
+
+	case OpMap:
+		return compileMap(params["fn"], params["column"])
+
+	case OpFilter:
+		return compileFilter(params["field"], params["pattern"], params["min"])
+
+	case OpGroupBy:
+		return compileGroupBy(params["aggregate"])
+
+	case OpJoin:
+		return func(inputs [][]KeyValue) ([]KeyValue, error) {
+			right := make(map[string][]string)
+			rightBytes := make(map[string]int) // Value bytes of each key's right records
+			for _, record := range inputs[1] {
+				right[record.Key] = append(right[record.Key], record.Value)
+				rightBytes[record.Key] += len(record.Value)
+			}
+			joined := []KeyValue{}
+			size := 0
+			for _, record := range inputs[0] {
+				matches := len(right[record.Key])
+				if len(joined)+matches > MaxStageRecords {
+					return nil, errTooManyRecords
+				}
+				// Each match repeats the left record and a separator before its right value
+				size += matches*(recordBytes(record)+1) + rightBytes[record.Key]
+				if size > MaxStageBytes {
+					return nil, errTooManyBytes
+				}
+				for _, value := range right[record.Key] {
+					joined = append(joined, KeyValue{Key: record.Key, Value: record.Value + "|" + value})
+				}
+			}
+			sort.SliceStable(joined, func(a, b int) bool { return joined[a].Key < joined[b].Key })
+			return joined, nil
+		}, nil
+
+	case OpTopK:
+		k, err := strconv.Atoi(params["k"])
+		if err != nil || k < 1 {
+			return nil, fmt.Errorf("k must be a positive integer")
+		}
+		return func(inputs [][]KeyValue) ([]KeyValue, error) {
+			records := append([]KeyValue{}, inputs[0]...)
+			sort.SliceStable(records, func(a, b int) bool {
+				va, _ := strconv.ParseFloat(records[a].Value, 64)
+				vb, _ := strconv.ParseFloat(records[b].Value, 64)
+				if va != vb {
+					return va > vb
+				}
+				return records[a].Key < records[b].Key
+			})
+			if len(records) > k {
+				records = records[:k]
+			}
+			return records, nil
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown op %q", stage.Op)
+}
+
+// compileMap builds a map stage
+func compileMap(fn, column string) (stageFunc, error) {
+	col, err := strconv.Atoi(column)
+	if err != nil || col < 0 {
+		return nil, fmt.Errorf("column must be a non-negative integer")
+	}
+
+	var transform func(record KeyValue, emit func(key, value string))
+	switch fn {
+	case "words":
+		transform = func(record KeyValue, emit func(key, value string)) {
+			for _, word := range words(record.Value) {
+				emit(word, "1")
+			}
+		}
+	case "lines":
+		transform = func(record KeyValue, emit func(key, value string)) {
+			for _, line := range strings.Split(record.Value, "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					emit(record.Key, line)
+				}
+			}
+		}
+	case "swap":
+		transform = func(record KeyValue, emit func(key, value string)) {
+			emit(record.Value, record.Key)
+		}
+	case "csv":
+		transform = func(record KeyValue, emit func(key, value string)) {
+			fields := strings.Split(record.Value, ",")
+			if col < len(fields) {
+				emit(strings.TrimSpace(fields[col]), record.Value)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown map fn %q", fn)
+	}
+
+	return func(inputs [][]KeyValue) ([]KeyValue, error) {
+		output := []KeyValue{}
+		size := 0
+		for _, record := range inputs[0] {
+			transform(record, func(key, value string) {
+				if len(output) <= MaxStageRecords && size <= MaxStageBytes {
+					output = append(output, KeyValue{Key: key, Value: value})
+					size += len(key) + len(value)
+				}
+			})
+			if len(output) > MaxStageRecords {
+				return nil, errTooManyRecords
+			}
+			if size > MaxStageBytes {
+				return nil, errTooManyBytes
+			}
+		}
+		return output, nil
+	}, nil
+}
+
+// compileFilter builds a filter stage
+func compileFilter(field, pattern, min string) (stageFunc, error) {
+	if field != "key" && field != "value" {
+		return nil, fmt.Errorf("field must be key or value")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %v", err)
+	}
+	checkMin := min != ""
+	threshold, err := strconv.ParseFloat(min, 64)
+	if checkMin && err != nil {
+		return nil, fmt.Errorf("min must be a number")
+	}
+
+	return func(inputs [][]KeyValue) ([]KeyValue, error) {
+		output := []KeyValue{}
+		for _, record := range inputs[0] {
+			text := record.Value
+			if field == "key" {
+				text = record.Key
+			}
+			if !re.MatchString(text) {
+				continue
+			}
+			if checkMin {
+				n, err := strconv.ParseFloat(text, 64)
+				if err != nil || n < threshold {
+					continue
+				}
+			}
+			output = append(output, record)
+		}
+		return output, nil
+	}, nil
+}
+
+// compileGroupBy builds a groupBy stage
+func compileGroupBy(aggregate string) (stageFunc, error) {
+	var fold func(values []string) string
+	switch aggregate {
+	case "count":
+		fold = func(values []string) string { return strconv.Itoa(len(values)) }
+	case "sum", "min", "max":
+		fold = func(values []string) string {
+			result := 0.0
+			for i, value := range values {
+				n, _ := strconv.ParseFloat(value, 64)
+				switch {
+				case i == 0:
+					result = n
+				case aggregate == "sum":
+					result += n
+				case aggregate == "min" && n < result, aggregate == "max" && n > result:
+					result = n
+				}
+			}
+			return strconv.FormatFloat(result, 'f', -1, 64)
+		}
+	case "collect":
+		fold = func(values []string) string { return strings.Join(uniqueSorted(values), ",") }
+	default:
+		return nil, fmt.Errorf("unknown aggregate %q", aggregate)
+	}
+
+	return func(inputs [][]KeyValue) ([]KeyValue, error) {
+		if len(inputs[0]) > MaxStageRecords {
+			return nil, errTooManyRecords
+		}
+		groups := make(map[string][]string)
+		for _, record := range inputs[0] {
+			groups[record.Key] = append(groups[record.Key], record.Value)
+		}
+		keys := make([]string, 0, len(groups))
+		for key := range groups {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		output := make([]KeyValue, len(keys))
+		size := 0
+		for i, key := range keys {
+			output[i] = KeyValue{Key: key, Value: fold(groups[key])}
+			if size += recordBytes(output[i]); size > MaxStageBytes {
+				return nil, errTooManyBytes
+			}
+		}
+		return output, nil
+	}, nil
+}
+
+// topologicalOrder orders stages so each runs after its inputs, keeping config order where free
+func topologicalOrder(stages []StageConfig, index map[string]int) ([]int, error) {
+	pending := make([]int, len(stages))
+	consumers := make([][]int, len(stages))
+	for i, stage := range stages {
+		for _, input := range stage.Inputs {
+			from, exists := index[input]
+			if !exists {
+				return nil, fmt.Errorf("stage %q reads unknown stage %q", stage.ID, input)
+			}
+			pending[i]++
+			consumers[from] = append(consumers[from], i)
+		}
+	}
+
+	var order []int
+	done := make([]bool, len(stages))
+	for len(order) < len(stages) {
+		ready := -1
+		for i := range stages {
+			if !done[i] && pending[i] == 0 {
+				ready = i
+				break
+			}
+		}
+		if ready < 0 {
+			return nil, fmt.Errorf("stages form a cycle")
+		}
+		done[ready] = true
+		order = append(order, ready)
+		for _, consumer := range consumers[ready] {
+			pending[consumer]--
+		}
+	}
+	return order, nil
+}
+
+// mapReduceStageJob fills in a stage's job config with its documents and default worker counts
+func mapReduceStageJob(job JobConfig, documents []string) JobConfig {
+	job.Documents = documents
+	if job.NumMappers == 0 {
+		job.NumMappers = 2
+	}
+	if job.NumReducers == 0 {
+		job.NumReducers = 2
+	}
+	return job
+}
+
+// recordDocuments formats records as "key<TAB>value" lines, as Hadoop's text output does,
+// spread over one document per mapper
+func recordDocuments(records []KeyValue, mappers int) []string {
+	if len(records) < mappers {
+		mappers = len(records)
+	}
+	if mappers == 0 {
+		return []string{""}
+	}
+	lines := make([][]string, mappers)
+	for i, record := range records {
+		// Contiguous chunks keep each document in key order
+		chunk := i * mappers / len(records)
+		lines[chunk] = append(lines[chunk], record.Key+"\t"+record.Value)
+	}
+	documents := make([]string, mappers)
+	for i := range lines {
+		documents[i] = strings.Join(lines[i], "\n")
+	}
+	return documents
+}
+
+// Step runs the next stage
+func (p *Pipeline) Step() {
+	p.stepMu.Lock()
+	defer p.stepMu.Unlock()
+	p.step()
+}
+
+// Run runs every remaining stage
+func (p *Pipeline) Run() {
+	p.stepMu.Lock()
+	defer p.stepMu.Unlock()
+	for p.step() {
+	}
+}
+
+// step runs the next stage in order, reporting whether more remain (must be called with stepMu held)
+// The stage itself runs without mu, so the state stays readable; a Reset or Configure
+// meanwhile discards its result
+func (p *Pipeline) step() bool {
+	p.mu.Lock()
+	if p.status == PipelineCompleted || p.status == PipelineFailed {
+		p.mu.Unlock()
+		return false
+	}
+	p.status = PipelineRunning
+
+	generation := p.generation
+	i := p.order[p.next]
+	stage := p.config.Stages[i]
+	fn := p.funcs[i]
+	inputs := make([][]KeyValue, len(stage.Inputs))
+	for n, input := range stage.Inputs {
+		inputs[n] = p.outputs[p.indexOf(input)]
+	}
+	fromSource := stage.Op == OpMapReduce && p.config.Stages[p.indexOf(stage.Inputs[0])].Op == OpSource
+	p.mu.Unlock()
+
+	var output []KeyValue
+	var job *JobState
+	var err error
+	if stage.Op == OpMapReduce {
+		output, job, err = runStageJob(stage, inputs[0], fromSource)
+	} else {
+		output, err = fn(inputs)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.generation != generation {
+		return false
+	}
+	if err != nil {
+		p.errors[i] = err.Error()
+		p.status = PipelineFailed
+		return false
+	}
+	p.outputs[i] = output
+	p.jobs[i] = job
+
+	p.next++
+	if p.next == len(p.order) {
+		p.status = PipelineCompleted
+	}
+	return p.status == PipelineRunning
+}
+
+// runStageJob runs a MapReduce stage to completion and returns its output and job state
+func runStageJob(stage StageConfig, input []KeyValue, fromSource bool) ([]KeyValue, *JobState, error) {
+	config := mapReduceStageJob(*stage.Job, nil)
+	if fromSource {
+		for _, record := range input {
+			config.Documents = append(config.Documents, record.Value)
+		}
+	} else {
+		config.Documents = recordDocuments(input, config.NumMappers)
+	}
+
+	job, err := NewJob(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	job.Start()
+	job.ExecuteMapPhase()
+	job.ExecuteShufflePhase()
+	job.ExecuteReducePhase()
+
+	state := job.GetState()
+	return state.FinalOutput, &state, nil
+}
+
+// indexOf returns the position of a stage in the config (must be called with lock held)
+func (p *Pipeline) indexOf(id string) int {
+	for i, stage := range p.config.Stages {
+		if stage.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetState returns the current state of the pipeline
+func (p *Pipeline) GetState() PipelineState {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	consumed := make(map[string]bool)
+	for _, stage := range p.config.Stages {
+		for _, input := range stage.Inputs {
+			consumed[input] = true
+		}
+	}
+
+	state := PipelineState{
+		Name:     p.config.Name,
+		Status:   p.status,
+		Order:    make([]string, len(p.order)),
+		Progress: p.next * 100 / len(p.order),
+		Stages:   make([]StageState, len(p.config.Stages)),
+	}
+	for n, i := range p.order {
+		state.Order[n] = p.config.Stages[i].ID
+	}
+	if p.next < len(p.order) {
+		state.CurrentStage = p.config.Stages[p.order[p.next]].ID
+	}
+
+	for i, stage := range p.config.Stages {
+		s := StageState{
+			ID:     stage.ID,
+			Op:     stage.Op,
+			Inputs: append([]string{}, stage.Inputs...),
+			Params: stage.Params,
+			Status: "pending",
+			Error:  p.errors[i],
+			Sink:   !consumed[stage.ID],
+			Output: []KeyValue{},
+			Job:    p.jobs[i],
+		}
+		if stage.Op == OpMapReduce {
+			s.Params = stage.Job.Params
+		}
+		if s.Error != "" {
+			s.Status = "failed"
+		} else if p.outputs[i] != nil {
+			s.Status = "completed"
+			for _, input := range stage.Inputs {
+				s.InputRecords += len(p.outputs[p.indexOf(input)])
+			}
+			s.OutputRecords = len(p.outputs[i])
+			s.Output = p.outputs[i]
+			if len(s.Output) > maxStageOutput {
+				s.Output = s.Output[:maxStageOutput]
+				s.Truncated = true
+			}
+		}
+		state.Stages[i] = s
+	}
+	return state
+}
+
+// Reset clears every stage's output
+func (p *Pipeline) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.resetLocked()
+}
+
+// resetLocked clears all progress (must be called with lock held)
+func (p *Pipeline) resetLocked() {
+	p.generation++
+	p.status = PipelineIdle
+	p.next = 0
+	p.outputs = make([][]KeyValue, len(p.config.Stages))
+	p.jobs = make([]*JobState, len(p.config.Stages))
+	p.errors = make([]string, len(p.config.Stages))
+}
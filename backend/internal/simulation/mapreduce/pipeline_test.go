@@ -0,0 +1,85 @@
+package mapreduce
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPipelinePresets(t *testing.T) {
+	want := map[string][]KeyValue{
+		"wordcount-topk":  {{Key: "top", Value: "hello=3,world=3,mapreduce=2"}},
+		"orders-per-user": {{Key: "1", Value: "2"}, {Key: "2", Value: "1"}, {Key: "4", Value: "1"}},
+	}
+	for _, preset := range PipelinePresets() {
+		p, err := NewPipeline(preset)
+		if err != nil {
+			t.Fatalf("%s: %v", preset.Name, err)
+		}
+		p.Run()
+
+		state := p.GetState()
+		if state.Status != PipelineCompleted {
+			t.Fatalf("%s: status %s", preset.Name, state.Status)
+		}
+		sink := state.Stages[len(state.Stages)-1]
+		if !reflect.DeepEqual(sink.Output, want[preset.Name]) {
+			t.Errorf("%s: output %v, want %v", preset.Name, sink.Output, want[preset.Name])
+		}
+	}
+}
+
+func TestPipelineStageRecordLimit(t *testing.T) {
+	// A self-join of 1100 equal keys would produce 1,210,000 records
+	p, err := NewPipeline(PipelineConfig{Stages: []StageConfig{
+		{ID: "input", Op: OpSource, Documents: []string{strings.Repeat("a ", 1100)}},
+		{ID: "w", Op: OpMap, Inputs: []string{"input"}, Params: map[string]string{"fn": "words"}},
+		{ID: "self", Op: OpJoin, Inputs: []string{"w", "w"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Run()
+
+	state := p.GetState()
+	if state.Status != PipelineFailed {
+		t.Fatalf("status %s, want failed", state.Status)
+	}
+	if join := state.Stages[2]; join.Status != "failed" || join.Error != errTooManyRecords.Error() {
+		t.Errorf("join stage %s with error %q", join.Status, join.Error)
+	}
+}
+
+func TestPipelineStageByteLimit(t *testing.T) {
+	// A self-join of 1000 rows sharing one key stays under the record limit
+	// but would hold about 200 MB of values
+	row := "k," + strings.Repeat("x", 100) + "\n"
+	p, err := NewPipeline(PipelineConfig{Stages: []StageConfig{
+		{ID: "input", Op: OpSource, Documents: []string{strings.Repeat(row, 1000)}},
+		{ID: "rows", Op: OpMap, Inputs: []string{"input"}, Params: map[string]string{"fn": "lines"}},
+		{ID: "keyed", Op: OpMap, Inputs: []string{"rows"}, Params: map[string]string{"fn": "csv", "column": "0"}},
+		{ID: "self", Op: OpJoin, Inputs: []string{"keyed", "keyed"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Run()
+
+	state := p.GetState()
+	if state.Status != PipelineFailed {
+		t.Fatalf("status %s, want failed", state.Status)
+	}
+	if join := state.Stages[3]; join.Status != "failed" || join.Error != errTooManyBytes.Error() {
+		t.Errorf("join stage %s with error %q", join.Status, join.Error)
+	}
+}
+
+func TestPipelineRejectsCycles(t *testing.T) {
+	_, err := NewPipeline(PipelineConfig{Stages: []StageConfig{
+		{ID: "a", Op: OpFilter, Inputs: []string{"b"}},
+		{ID: "b", Op: OpFilter, Inputs: []string{"a"}},
+	}})
+	if err == nil {
+		t.Error("cyclic pipeline accepted")
+	}
+}
@@ -0,0 +1,106 @@
+package mapreduce
+
+import (
+	"fmt"
+	"sync"
+)
+
+// MapFunc turns one input document into intermediate key-value pairs
+// inputID names the document (used by jobs such as the inverted index)
+type MapFunc func(inputID, input string, emit func(key, value string))
+
+// ReduceFunc folds every value emitted for a key into one output value
+// Returning false drops the key from the final output (e.g. an inner join without a match)
+type ReduceFunc func(key string, values []string) (string, bool)
+
+// Functions are the user code a MapReduce job runs
+// Combine is optional: a reducer that is safe to run on partial map output
+// because the reduce operation is associative and commutative
+type Functions struct {
+	Map     MapFunc
+	Reduce  ReduceFunc
+	Combine ReduceFunc
+}
+
+// Factory builds a job's functions from its parameters
+type Factory func(params map[string]string) (Functions, error)
+
+// JobParam describes a parameter a job type accepts
+type JobParam struct {
+	Name        string `json:"name"`
+	Default     string `json:"default"`
+	Description string `json:"description"`
+}
+
+// JobInfo describes a registered job type
+type JobInfo struct {
+	Key         string     `json:"key"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Params      []JobParam `json:"params"`
+	SampleInput []string   `json:"sampleInput"` // Used when a job is submitted without documents
+}
+
+// registration is a registry entry
+type registration struct {
+	info    JobInfo
+	factory Factory
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]registration{}
+	order      []string // registration order, used for listing
+)
+
+// Register makes a job type available under info.Key
+func Register(info JobInfo, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, exists := registry[info.Key]; exists {
+		panic("mapreduce: job registered twice: " + info.Key)
+	}
+	registry[info.Key] = registration{info: info, factory: factory}
+	order = append(order, info.Key)
+}
+
+// lookupJob returns a registered job type with its parameters resolved against the defaults
+func lookupJob(key string, params map[string]string) (JobInfo, Functions, map[string]string, error) {
+	registryMu.RLock()
+	entry, exists := registry[key]
+	registryMu.RUnlock()
+
+	if !exists {
+		return JobInfo{}, Functions{}, nil, fmt.Errorf("unknown job type %q", key)
+	}
+
+	resolved := make(map[string]string, len(entry.info.Params))
+	for _, param := range entry.info.Params {
+		resolved[param.Name] = param.Default
+	}
+	for name, value := range params {
+		if _, known := resolved[name]; !known {
+			return JobInfo{}, Functions{}, nil, fmt.Errorf("job type %q has no parameter %q", key, name)
+		}
+		resolved[name] = value
+	}
+
+	functions, err := entry.factory(resolved)
+	if err != nil {
+		return JobInfo{}, Functions{}, nil, err
+	}
+	return entry.info, functions, resolved, nil
+}
+
+// RegisteredJobs lists every registered job type in registration order
+func RegisteredJobs() []JobInfo {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	infos := make([]JobInfo, 0, len(order))
+	for _, key := range order {
+		infos = append(infos, registry[key].info)
+	}
+	return infos
+}
@@ -0,0 +1,64 @@
+package mapreduce
+
+import "fmt"
+
+// SpeculationRun is one failure-free run of a job
+type SpeculationRun struct {
+	Speculative    bool          `json:"speculative"`
+	CompletionMs   int64         `json:"completionMs"`
+	WorkMs         int64         `json:"workMs"` // Worker time spent, including killed backup copies
+	BackupAttempts int           `json:"backupAttempts"`
+	BackupWins     int           `json:"backupWins"`
+	Attempts       []TaskAttempt `json:"attempts"`
+}
+
+// SpeculationReport compares a job's completion time with and without backup tasks
+type SpeculationReport struct {
+	Config      MasterConfig   `json:"config"`
+	Without     SpeculationRun `json:"without"`
+	With        SpeculationRun `json:"with"`
+	Speedup     float64        `json:"speedup"`     // Completion time without backups divided by with
+	ExtraWorkMs int64          `json:"extraWorkMs"` // Additional worker time the backups cost; negative when killing stragglers saved more
+}
+
+// CompareSpeculation runs a job to completion twice on the same cluster,
+// once without and once with speculative execution
+func CompareSpeculation(config MasterConfig, job JobConfig) (SpeculationReport, error) {
+	report := SpeculationReport{Config: config}
+	for _, speculative := range []bool{false, true} {
+		config.Speculative = speculative
+		m, err := NewMaster(config, job)
+		if err != nil {
+			return SpeculationReport{}, err
+		}
+		if err := m.Start(); err != nil {
+			return SpeculationReport{}, err
+		}
+		m.Run()
+
+		state := m.GetState()
+		if state.Status != MasterCompleted {
+			return SpeculationReport{}, fmt.Errorf("job did not complete within %d ms", MaxRunMs)
+		}
+		run := SpeculationRun{
+			Speculative:    speculative,
+			CompletionMs:   state.CompletionMs,
+			BackupAttempts: state.Stats.BackupAttempts,
+			BackupWins:     state.Stats.BackupWins,
+			WorkMs:         state.Stats.WorkMs,
+			Attempts:       state.Attempts,
+		}
+
+		if speculative {
+			report.With = run
+		} else {
+			report.Without = run
+		}
+	}
+
+	if report.With.CompletionMs > 0 {
+		report.Speedup = float64(report.Without.CompletionMs) / float64(report.With.CompletionMs)
+	}
+	report.ExtraWorkMs = report.With.WorkMs - report.Without.WorkMs
+	return report, nil
+}
@@ -22,8 +22,10 @@ type KeyValue struct {
 // MapTask represents a map task on a worker
 type MapTask struct {
 	WorkerID    int         `json:"workerId"`
+	InputID     string      `json:"inputId"`
 	InputData   string      `json:"inputData"`
 	OutputPairs []KeyValue  `json:"outputPairs"`
+	Emitted     int         `json:"emitted"` // Pairs the map function emitted, before any combining
 	Status      string      `json:"status"` // "pending", "running", "completed"
 	StartTime   time.Time   `json:"startTime"`
 	EndTime     time.Time   `json:"endTime"`
@@ -42,6 +44,7 @@ type ReduceTask struct {
 	Key        string    `json:"key"`
 	Values     []string  `json:"values"`
 	Result     string    `json:"result"`
+	Dropped    bool      `json:"dropped,omitempty"` // Reducer produced no output for the key
 	Status     string    `json:"status"` // "pending", "running", "completed"
 	StartTime  time.Time `json:"startTime"`
 	EndTime    time.Time `json:"endTime"`
@@ -50,6 +53,12 @@ type ReduceTask struct {
 // JobState represents the complete state of a MapReduce job
 type JobState struct {
 	JobID            string              `json:"jobId"`
+	JobType          string              `json:"jobType"`
+	JobName          string              `json:"jobName"`
+	Params           map[string]string   `json:"params,omitempty"`
+	Combine          bool                `json:"combine"`
+	Partitioner      string              `json:"partitioner"`
+	ShuffleStats     *ShuffleStats       `json:"shuffleStats,omitempty"` // Reducer loads and skew, once shuffled
 	Status           JobStatus           `json:"status"`
 	InputData        []string            `json:"inputData"`
 	NumMappers       int                 `json:"numMappers"`
@@ -0,0 +1,274 @@
+package rate_limiting
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ConcurrencyStrategy selects how the adaptive limit reacts to latency
+type ConcurrencyStrategy string
+
+const (
+	StrategyAIMD  ConcurrencyStrategy = "aimd"  // additive increase, multiplicative decrease on timeouts
+	StrategyVegas ConcurrencyStrategy = "vegas" // TCP-Vegas: grow/shrink by the estimated queue size
+)
+
+// BackendModel is the simulated service behind the limiter
+// Latency stays at BaseLatency until more than Capacity requests are in
+// flight, then grows linearly with the overload (requests queue up)
+type BackendModel struct {
+	BaseLatency time.Duration `json:"baseLatency"`
+	Capacity    int           `json:"capacity"` // Requests the backend serves concurrently without queueing
+	Timeout     time.Duration `json:"timeout"`  // Latency above this counts as a failure
+}
+
+// latency returns the simulated latency with inflight requests outstanding
+func (b BackendModel) latency(inflight int) time.Duration {
+	load := float64(inflight) / float64(b.Capacity)
+	if load < 1 {
+		load = 1
+	}
+	return time.Duration(float64(b.BaseLatency) * load)
+}
+
+// maxLimitSamples is how many recent limit changes the state shows
+const maxLimitSamples = 100
+
+// LimitSample records the adaptive limit after a request completes
+type LimitSample struct {
+	Timestamp time.Time `json:"timestamp"`
+	Limit     float64   `json:"limit"`
+	Inflight  int       `json:"inflight"`
+	LatencyMs float64   `json:"latencyMs"`
+	Dropped   bool      `json:"dropped"` // Timed out at the backend
+}
+
+// inflightRequest is an admitted request waiting for its simulated completion
+type inflightRequest struct {
+	start time.Time
+	done  time.Time
+}
+
+// AdaptiveConcurrencyLimiter caps in-flight requests instead of request rate
+// (like Netflix concurrency-limits). Requests complete after a latency given
+// by the BackendModel, and the limit adapts to those latencies, so it sheds
+// load when the backend slows down even though the request rate is unchanged
+type AdaptiveConcurrencyLimiter struct {
+	mu       sync.RWMutex
+	strategy ConcurrencyStrategy
+	backend  BackendModel
+
+	initialLimit float64
+	minLimit     float64
+	maxLimit     float64
+	limit        float64
+	inflight     []inflightRequest // sorted by completion time
+
+	backoffRatio float64       // AIMD: multiply the limit by this on a drop
+	minRTT       time.Duration // Vegas: best latency seen (no-load estimate)
+	alpha        float64       // Vegas: grow while the estimated queue is below alpha
+	beta         float64       // Vegas: shrink once the estimated queue exceeds beta
+
+	completed      int
+	dropped        int
+	limitHistory   []LimitSample
+	requestHistory []RequestLog
+	historyCap     int // Decisions kept in requestHistory; 0 keeps them all
+	clock          Clock
+}
+
+// NewAdaptiveConcurrencyLimiter creates an adaptive limiter starting at initialLimit
+func NewAdaptiveConcurrencyLimiter(strategy ConcurrencyStrategy, initialLimit int, backend BackendModel) *AdaptiveConcurrencyLimiter {
+	if backend.Capacity < 1 {
+		backend.Capacity = 1
+	}
+	return &AdaptiveConcurrencyLimiter{
+		strategy:       strategy,
+		backend:        backend,
+		initialLimit:   float64(initialLimit),
+		minLimit:       1,
+		maxLimit:       float64(initialLimit) * 10,
+		limit:          float64(initialLimit),
+		inflight:       []inflightRequest{},
+		backoffRatio:   0.9,
+		alpha:          3,
+		beta:           6,
+		limitHistory:   []LimitSample{},
+		requestHistory: []RequestLog{},
+		clock:          RealClock(),
+	}
+}
+
+func (a *AdaptiveConcurrencyLimiter) AllowRequest() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.clock.Now()
+
+	// Completions that happened before this request adjust the limit first
+	a.completeUntil(now)
+
+	allowed := len(a.inflight) < int(a.limit)
+
+	if allowed {
+		done := now.Add(a.backend.latency(len(a.inflight) + 1))
+		i := sort.Search(len(a.inflight), func(i int) bool { return a.inflight[i].done.After(done) })
+		a.inflight = append(a.inflight, inflightRequest{})
+		copy(a.inflight[i+1:], a.inflight[i:])
+		a.inflight[i] = inflightRequest{start: now, done: done}
+	}
+
+	// Record request
+	a.requestHistory = recordRequest(a.requestHistory, a.historyCap, now, allowed)
+
+	return allowed
+}
+
+// completeUntil finishes every request done by now and feeds its latency
+// to the limit algorithm (must be called with lock held)
+func (a *AdaptiveConcurrencyLimiter) completeUntil(now time.Time) {
+	for len(a.inflight) > 0 && !a.inflight[0].done.After(now) {
+		req := a.inflight[0]
+		a.inflight = a.inflight[1:]
+
+		rtt := req.done.Sub(req.start)
+		dropped := a.backend.Timeout > 0 && rtt > a.backend.Timeout
+		inflight := len(a.inflight) + 1
+
+		switch a.strategy {
+		case StrategyVegas:
+			a.updateVegas(rtt, dropped)
+		default:
+			a.updateAIMD(inflight, dropped)
+		}
+
+		a.completed++
+		if dropped {
+			a.dropped++
+		}
+		a.limitHistory = append(a.limitHistory, LimitSample{
+			Timestamp: req.done,
+			Limit:     a.limit,
+			Inflight:  inflight,
+			LatencyMs: float64(rtt) / float64(time.Millisecond),
+			Dropped:   dropped,
+		})
+		if len(a.limitHistory) > 2*maxLimitSamples {
+			a.limitHistory = append([]LimitSample{}, a.limitHistory[len(a.limitHistory)-maxLimitSamples:]...)
+		}
+	}
+}
+
+// updateAIMD grows the limit by one per successful request while the limit
+// is being used, and backs off multiplicatively on a drop
+func (a *AdaptiveConcurrencyLimiter) updateAIMD(inflight int, dropped bool) {
+	if dropped {
+		a.limit = math.Max(a.minLimit, a.limit*a.backoffRatio)
+		return
+	}
+	if float64(inflight)*2 >= a.limit {
+		a.limit = math.Min(a.maxLimit, a.limit+1)
+	}
+}
+
+// updateVegas estimates how many requests are queued from the ratio of
+// no-load latency to observed latency and steers that queue between alpha and beta
+func (a *AdaptiveConcurrencyLimiter) updateVegas(rtt time.Duration, dropped bool) {
+	if a.minRTT == 0 || rtt < a.minRTT {
+		a.minRTT = rtt
+	}
+	if dropped {
+		a.limit = math.Max(a.minLimit, a.limit*a.backoffRatio)
+		return
+	}
+
+	queue := a.limit * (1 - float64(a.minRTT)/float64(rtt))
+	switch {
+	case queue < a.alpha:
+		a.limit = math.Min(a.maxLimit, a.limit+1)
+	case queue > a.beta:
+		a.limit = math.Max(a.minLimit, a.limit-1)
+	}
+}
+
+// SetBackend changes the simulated backend, e.g. to show the limit shrinking
+// when the service degrades
+func (a *AdaptiveConcurrencyLimiter) SetBackend(backend BackendModel) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if backend.Capacity < 1 {
+		backend.Capacity = 1
+	}
+	a.backend = backend
+}
+
+func (a *AdaptiveConcurrencyLimiter) GetState() interface{} {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	// Show at most the last maxLimitSamples limit changes
+	history := a.limitHistory
+	if len(history) > maxLimitSamples {
+		history = history[len(history)-maxLimitSamples:]
+	}
+
+	return map[string]interface{}{
+		"algorithm":      a.GetName(),
+		"strategy":       a.strategy,
+		"limit":          a.limit,
+		"minLimit":       a.minLimit,
+		"maxLimit":       a.maxLimit,
+		"inflight":       len(a.inflight),
+		"backend":        a.backend,
+		"minRttMs":       float64(a.minRTT) / float64(time.Millisecond),
+		"completed":      a.completed,
+		"dropped":        a.dropped,
+		"limitHistory":   history,
+		"stateBytes":     a.stateBytes(),
+		"requestHistory": a.requestHistory,
+	}
+}
+
+func (a *AdaptiveConcurrencyLimiter) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.limit = a.initialLimit
+	a.inflight = []inflightRequest{}
+	a.minRTT = 0
+	a.completed = 0
+	a.dropped = 0
+	a.limitHistory = []LimitSample{}
+	a.requestHistory = []RequestLog{}
+}
+
+func (a *AdaptiveConcurrencyLimiter) GetName() string {
+	if a.strategy == StrategyVegas {
+		return "Adaptive Concurrency (Vegas)"
+	}
+	return "Adaptive Concurrency (AIMD)"
+}
+
+// SetClock swaps the time source and resets the limiter onto it
+func (a *AdaptiveConcurrencyLimiter) SetClock(clock Clock) {
+	a.mu.Lock()
+	a.clock = clock
+	a.mu.Unlock()
+	a.Reset()
+}
+
+// StateBytes counts the limit, its bounds, the RTT estimate and one
+// start/done pair per in-flight request
+func (a *AdaptiveConcurrencyLimiter) StateBytes() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.stateBytes()
+}
+
+// stateBytes is StateBytes without locking (must be called with lock held)
+func (a *AdaptiveConcurrencyLimiter) stateBytes() int {
+	return 4*wordBytes + sliceHeaderBytes + 2*timeBytes*len(a.inflight)
+}
@@ -0,0 +1,35 @@
+package rate_limiting
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConcurrencyQuotaMatchesAdmission(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	backend := BackendModel{BaseLatency: 100 * time.Millisecond, Capacity: 4, Timeout: time.Second}
+	for _, strategy := range []ConcurrencyStrategy{StrategyAIMD, StrategyVegas} {
+		t.Run(string(strategy), func(t *testing.T) {
+			clock := NewVirtualClock(start)
+			a := NewAdaptiveConcurrencyLimiter(strategy, 4, backend)
+			a.SetClock(clock)
+
+			// Fill the limit, then check the quota once every request has completed
+			for i := 0; i < 4; i++ {
+				a.AllowRequest()
+			}
+			if remaining := a.Quota().Remaining; remaining != 0 {
+				t.Errorf("remaining = %d with the limit in flight, want 0", remaining)
+			}
+
+			for step := 0; step < 40; step++ {
+				clock.Advance(25 * time.Millisecond)
+				remaining := a.Quota().Remaining
+				allowed := a.AllowRequest()
+				if allowed != (remaining > 0) {
+					t.Fatalf("step %d: quota reported %d remaining but AllowRequest returned %v", step, remaining, allowed)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,337 @@
+package rate_limiting
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DistributedStrategy names how gateway nodes share one limit
+type DistributedStrategy string
+
+const (
+	// Every request is decided by a token bucket in the central store
+	StrategyCentralTokenBucket DistributedStrategy = "central-token-bucket"
+	// Every request is decided by a sliding window counter in the central store
+	StrategyCentralSlidingWindow DistributedStrategy = "central-sliding-window"
+	// Nodes decide locally and periodically exchange their counts
+	StrategyLocalSync DistributedStrategy = "local-sync"
+	// Nodes lease batches of tokens from a central token bucket
+	StrategyTokenLease DistributedStrategy = "token-lease"
+)
+
+// DistributedStrategies lists the strategies in report order
+var DistributedStrategies = []DistributedStrategy{
+	StrategyCentralTokenBucket,
+	StrategyCentralSlidingWindow,
+	StrategyLocalSync,
+	StrategyTokenLease,
+}
+
+// Limits on the simulated cluster
+const (
+	MaxGatewayNodes  = 64
+	MaxSyncPublishes = 1000000 // local-sync: sync rounds times nodes over the timeline
+)
+
+// DistributedConfig describes the gateway cluster
+type DistributedConfig struct {
+	Nodes          int     `json:"nodes"`
+	StoreLatencyMs float64 `json:"storeLatencyMs"` // Round trip to the central store
+	SyncIntervalMs int64   `json:"syncIntervalMs"` // local-sync: how often nodes exchange counts
+	LeaseSize      int     `json:"leaseSize"`      // token-lease: tokens fetched per store call
+}
+
+// CounterStore is an in-process stand-in for a shared store such as Redis
+// Each operation is one simulated round trip; the limiter state it holds is
+// the same TokenBucket / SlidingWindowCounter logic the single-node demo uses
+type CounterStore struct {
+	mu     sync.Mutex
+	calls  int
+	bucket *TokenBucket
+	window *SlidingWindowCounter
+	counts map[int]float64 // Last count published by each node
+}
+
+// NewCounterStore creates a store holding a token bucket and a sliding window
+// counter for the spec, both driven by the given clock
+func NewCounterStore(spec LimiterSpec, clock Clock) *CounterStore {
+	window := time.Duration(spec.WindowSeconds * float64(time.Second))
+	store := &CounterStore{
+		bucket: NewTokenBucket(spec.Limit, float64(spec.Limit)/spec.WindowSeconds),
+		window: NewSlidingWindowCounter(spec.Limit, window),
+		counts: make(map[int]float64),
+	}
+	store.bucket.SetClock(clock)
+	store.window.SetClock(clock)
+	return store
+}
+
+// roundTrip accounts for one call to the store
+func (s *CounterStore) roundTrip() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+}
+
+// AllowTokenBucket atomically checks the shared token bucket
+func (s *CounterStore) AllowTokenBucket() bool {
+	s.roundTrip()
+	return s.bucket.AllowRequest()
+}
+
+// AllowSlidingWindow atomically checks the shared sliding window counter
+func (s *CounterStore) AllowSlidingWindow() bool {
+	s.roundTrip()
+	return s.window.AllowRequest()
+}
+
+// LeaseTokens takes up to n tokens from the shared bucket
+func (s *CounterStore) LeaseTokens(n int) int {
+	s.roundTrip()
+	return s.bucket.take(n)
+}
+
+// Publish stores a node's current count and returns the sum of every other node's count
+func (s *CounterStore) Publish(nodeID int, count float64) float64 {
+	s.roundTrip()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.counts[nodeID] = count
+	remote := 0.0
+	for id, c := range s.counts {
+		if id != nodeID {
+			remote += c
+		}
+	}
+	return remote
+}
+
+// Calls returns the number of round trips made to the store
+func (s *CounterStore) Calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+// gatewayNode is one API gateway instance
+type gatewayNode struct {
+	id       int
+	local    *SlidingWindowCounter // local-sync: requests seen by this node
+	remote   float64               // local-sync: other nodes' counts at the last sync
+	leased   int                   // token-lease: unused leased tokens
+	accepted int
+	rejected int
+}
+
+// NodeStats summarizes one gateway node for a strategy
+type NodeStats struct {
+	NodeID       int `json:"nodeId"`
+	Accepted     int `json:"accepted"`
+	Rejected     int `json:"rejected"`
+	LeasedUnused int `json:"leasedUnused,omitempty"`
+}
+
+// StrategyReport is the outcome of one distribution strategy
+type StrategyReport struct {
+	Strategy          DistributedStrategy `json:"strategy"`
+	Accepted          int                 `json:"accepted"`
+	Rejected          int                 `json:"rejected"`
+	ExactAccepted     int                 `json:"exactAccepted"` // What a single central limiter of the same algorithm admits
+	OverLimit         int                 `json:"overLimit"`     // Admissions that put more than limit requests in the trailing window
+	OverAdmitted      int                 `json:"overAdmitted"`  // OverLimit beyond what the exact limiter allows (caused by sync lag)
+	UnderAdmitted     int                 `json:"underAdmitted"` // Fewer admissions than the exact limiter (e.g. stranded leases)
+	MaxInWindow       int                 `json:"maxInWindow"`   // Most requests admitted in any window-length span
+	StoreCalls        int                 `json:"storeCalls"`
+	StoreCallsPerReq  float64             `json:"storeCallsPerRequest"`
+	AvgAddedLatencyMs float64             `json:"avgAddedLatencyMs"` // Store round trips on the request path, per request
+	Nodes             []NodeStats         `json:"nodes"`
+}
+
+// DistributedReport compares every strategy on the same timeline
+type DistributedReport struct {
+	Spec          LimiterSpec       `json:"spec"`
+	Config        DistributedConfig `json:"config"`
+	TotalRequests int               `json:"totalRequests"`
+	Strategies    []StrategyReport  `json:"strategies"`
+}
+
+// SimulateDistributed replays the timeline through a cluster of gateway nodes
+// for every strategy (requests are spread across nodes round-robin)
+func SimulateDistributed(spec LimiterSpec, config DistributedConfig, requests []ScheduledRequest) (DistributedReport, error) {
+	if spec.Limit <= 0 || spec.WindowSeconds <= 0 {
+		return DistributedReport{}, fmt.Errorf("limit and windowSeconds must be positive")
+	}
+	if config.Nodes <= 0 || config.Nodes > MaxGatewayNodes {
+		return DistributedReport{}, fmt.Errorf("nodes must be between 1 and %d", MaxGatewayNodes)
+	}
+	if err := validateTimeline(requests); err != nil {
+		return DistributedReport{}, err
+	}
+	if config.SyncIntervalMs <= 0 {
+		config.SyncIntervalMs = 1000
+	}
+	if config.LeaseSize <= 0 {
+		config.LeaseSize = max(1, spec.Limit/(config.Nodes*4))
+	}
+
+	timeline := append([]ScheduledRequest(nil), requests...)
+	sort.SliceStable(timeline, func(i, j int) bool { return timeline[i].OffsetMs < timeline[j].OffsetMs })
+
+	// local-sync publishes every node's count once per interval of the timeline's span
+	if len(timeline) > 0 {
+		rounds := timeline[len(timeline)-1].OffsetMs / config.SyncIntervalMs
+		if rounds*int64(config.Nodes) > MaxSyncPublishes {
+			return DistributedReport{}, fmt.Errorf("syncIntervalMs is too short for the timeline: %d rounds of %d nodes exceed %d publishes", rounds, config.Nodes, MaxSyncPublishes)
+		}
+	}
+
+	report := DistributedReport{
+		Spec:          spec,
+		Config:        config,
+		TotalRequests: len(timeline),
+		Strategies:    make([]StrategyReport, 0, len(DistributedStrategies)),
+	}
+
+	decisions := make(map[DistributedStrategy][]bool)
+	for _, strategy := range DistributedStrategies {
+		result, allowed := runStrategy(strategy, spec, config, timeline)
+		decisions[strategy] = allowed
+		report.Strategies = append(report.Strategies, result)
+	}
+
+	// Central strategies are exact; compare the others to their central twin
+	windowMs := int64(spec.WindowSeconds * 1000)
+	overLimit := make(map[DistributedStrategy]int)
+	for _, strategy := range DistributedStrategies {
+		overLimit[strategy] = countOverLimit(timeline, decisions[strategy], windowMs, spec.Limit)
+	}
+	for i := range report.Strategies {
+		result := &report.Strategies[i]
+		twin := StrategyCentralTokenBucket
+		if result.Strategy == StrategyCentralSlidingWindow || result.Strategy == StrategyLocalSync {
+			twin = StrategyCentralSlidingWindow
+		}
+		for r := range timeline {
+			if decisions[twin][r] {
+				result.ExactAccepted++
+			}
+		}
+		result.OverLimit = overLimit[result.Strategy]
+		result.OverAdmitted = max(0, overLimit[result.Strategy]-overLimit[twin])
+		result.UnderAdmitted = max(0, result.ExactAccepted-result.Accepted)
+		result.MaxInWindow = maxAdmittedWithin(timeline, decisions[result.Strategy], windowMs)
+	}
+
+	return report, nil
+}
+
+// runStrategy simulates one strategy on its own virtual clock
+func runStrategy(strategy DistributedStrategy, spec LimiterSpec, config DistributedConfig, timeline []ScheduledRequest) (StrategyReport, []bool) {
+	start := time.Unix(0, 0).UTC()
+	clock := NewVirtualClock(start)
+	store := NewCounterStore(spec, clock)
+
+	window := time.Duration(spec.WindowSeconds * float64(time.Second))
+	nodes := make([]*gatewayNode, config.Nodes)
+	for i := range nodes {
+		nodes[i] = &gatewayNode{id: i + 1}
+		if strategy == StrategyLocalSync {
+			nodes[i].local = NewSlidingWindowCounter(spec.Limit, window)
+			nodes[i].local.SetClock(clock)
+		}
+	}
+
+	syncInterval := time.Duration(config.SyncIntervalMs) * time.Millisecond
+	nextSync := start.Add(syncInterval)
+	pathCalls := 0 // store calls made while a request waits (sync runs in the background)
+
+	allowed := make([]bool, len(timeline))
+	for r, req := range timeline {
+		at := start.Add(time.Duration(req.OffsetMs) * time.Millisecond)
+
+		// Background sync rounds that happened before this request
+		if strategy == StrategyLocalSync {
+			for !nextSync.After(at) {
+				clock.Set(nextSync)
+				for _, node := range nodes {
+					node.remote = store.Publish(node.id, node.local.estimatedCount())
+				}
+				nextSync = nextSync.Add(syncInterval)
+			}
+		}
+		clock.Set(at)
+
+		node := nodes[r%len(nodes)]
+		switch strategy {
+		case StrategyCentralTokenBucket:
+			allowed[r] = store.AllowTokenBucket()
+			pathCalls++
+		case StrategyCentralSlidingWindow:
+			allowed[r] = store.AllowSlidingWindow()
+			pathCalls++
+		case StrategyLocalSync:
+			allowed[r] = node.local.allowWithRemote(node.remote)
+		case StrategyTokenLease:
+			if node.leased == 0 {
+				node.leased = store.LeaseTokens(config.LeaseSize)
+				pathCalls++
+			}
+			if node.leased > 0 {
+				node.leased--
+				allowed[r] = true
+			}
+		}
+
+		if allowed[r] {
+			node.accepted++
+		} else {
+			node.rejected++
+		}
+	}
+
+	result := StrategyReport{
+		Strategy:   strategy,
+		StoreCalls: store.Calls(),
+		Nodes:      make([]NodeStats, 0, len(nodes)),
+	}
+	for _, node := range nodes {
+		result.Accepted += node.accepted
+		result.Rejected += node.rejected
+		result.Nodes = append(result.Nodes, NodeStats{
+			NodeID:       node.id,
+			Accepted:     node.accepted,
+			Rejected:     node.rejected,
+			LeasedUnused: node.leased,
+		})
+	}
+	if len(timeline) > 0 {
+		result.StoreCallsPerReq = float64(result.StoreCalls) / float64(len(timeline))
+		result.AvgAddedLatencyMs = float64(pathCalls) * config.StoreLatencyMs / float64(len(timeline))
+	}
+
+	return result, allowed
+}
+
+// countOverLimit counts admissions that left more than limit admitted
+// requests inside the trailing window
+func countOverLimit(timeline []ScheduledRequest, allowed []bool, windowMs int64, limit int) int {
+	admitted := []int64{}
+	over, left := 0, 0
+	for r, req := range timeline {
+		if !allowed[r] {
+			continue
+		}
+		admitted = append(admitted, req.OffsetMs)
+		for req.OffsetMs-admitted[left] >= windowMs {
+			left++
+		}
+		if len(admitted)-left > limit {
+			over++
+		}
+	}
+	return over
+}
@@ -0,0 +1,29 @@
+package rate_limiting
+
+import "testing"
+
+func TestSimulateDistributedLimits(t *testing.T) {
+	spec := LimiterSpec{Limit: 10, WindowSeconds: 1}
+	timeline := []ScheduledRequest{{OffsetMs: 0}, {OffsetMs: 500}, {OffsetMs: 3600000}}
+
+	tests := []struct {
+		name   string
+		config DistributedConfig
+		ok     bool
+	}{
+		{name: "defaults", config: DistributedConfig{Nodes: 4}, ok: true},
+		{name: "too many nodes", config: DistributedConfig{Nodes: 1e9}},
+		{name: "sync every millisecond for an hour", config: DistributedConfig{Nodes: 4, SyncIntervalMs: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := SimulateDistributed(spec, tt.config, timeline)
+			if (err == nil) != tt.ok {
+				t.Fatalf("SimulateDistributed() error = %v, want ok %v", err, tt.ok)
+			}
+			if tt.ok && report.TotalRequests != len(timeline) {
+				t.Errorf("simulated %d requests, want %d", report.TotalRequests, len(timeline))
+			}
+		})
+	}
+}
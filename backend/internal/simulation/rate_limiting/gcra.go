@@ -0,0 +1,141 @@
+package rate_limiting
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// GCRA implements the Generic Cell Rate Algorithm (as used by redis-cell)
+// It stores a single "theoretical arrival time" (TAT) per client: each
+// request pushes the TAT one emission interval into the future, and a
+// request is rejected if that would put the TAT further ahead than the
+// burst tolerance allows. Same behavior as a token bucket, one timestamp of state
+type GCRA struct {
+	mu               sync.RWMutex
+	limit            int // Requests per period
+	period           time.Duration
+	burst            int           // Requests that may arrive back to back
+	emissionInterval time.Duration // period / limit: ideal spacing between requests
+	tolerance        time.Duration // How far ahead of now the TAT may run
+	tat              time.Time     // Theoretical arrival time of the next request
+	requestHistory   []RequestLog
+	historyCap       int // Decisions kept in requestHistory; 0 keeps them all
+	clock            Clock
+}
+
+// NewGCRA creates a GCRA limiter allowing limit requests per period with the given burst
+func NewGCRA(limit int, period time.Duration, burst int) (*GCRA, error) {
+	if limit <= 0 || period <= 0 {
+		return nil, fmt.Errorf("limit and period must be positive")
+	}
+	emissionInterval := period / time.Duration(limit)
+	if emissionInterval <= 0 {
+		return nil, fmt.Errorf("period must be at least limit nanoseconds")
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &GCRA{
+		limit:            limit,
+		period:           period,
+		burst:            burst,
+		emissionInterval: emissionInterval,
+		tolerance:        emissionInterval * time.Duration(burst-1),
+		tat:              time.Now(),
+		requestHistory:   []RequestLog{},
+		clock:            RealClock(),
+	}, nil
+}
+
+func (g *GCRA) AllowRequest() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.clock.Now()
+
+	// A TAT in the past means the client has been idle: start from now
+	tat := g.tat
+	if tat.Before(now) {
+		tat = now
+	}
+
+	// Allowed if the new TAT stays within the burst tolerance of now
+	newTAT := tat.Add(g.emissionInterval)
+	allowed := newTAT.Sub(now) <= g.tolerance+g.emissionInterval
+
+	if allowed {
+		g.tat = newTAT
+	}
+
+	// Record request
+	g.requestHistory = recordRequest(g.requestHistory, g.historyCap, now, allowed)
+
+	return allowed
+}
+
+// remaining returns how many requests could be admitted right now (must be called with lock held)
+func (g *GCRA) remaining(now time.Time) int {
+	ahead := g.tat.Sub(now)
+	if ahead < 0 {
+		ahead = 0
+	}
+	slack := g.tolerance + g.emissionInterval - ahead
+	return int(math.Max(0, math.Floor(float64(slack)/float64(g.emissionInterval))))
+}
+
+// retryAfter returns how long until the next request would be admitted (must be called with lock held)
+func (g *GCRA) retryAfter(now time.Time) time.Duration {
+	wait := g.tat.Sub(now) - g.tolerance
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+func (g *GCRA) GetState() interface{} {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	now := g.clock.Now()
+
+	return map[string]interface{}{
+		"algorithm":          "GCRA",
+		"limit":              g.limit,
+		"period":             g.period.Seconds(),
+		"burst":              g.burst,
+		"emissionIntervalMs": g.emissionInterval.Milliseconds(),
+		"toleranceMs":        g.tolerance.Milliseconds(),
+		"tat":                g.tat,
+		"remaining":          g.remaining(now),
+		"retryAfterMs":       g.retryAfter(now).Milliseconds(),
+		"stateBytes":         g.StateBytes(),
+		"requestHistory":     g.requestHistory,
+	}
+}
+
+func (g *GCRA) Reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.tat = g.clock.Now()
+	g.requestHistory = []RequestLog{}
+}
+
+func (g *GCRA) GetName() string {
+	return "GCRA"
+}
+
+// SetClock swaps the time source and resets the limiter onto it
+func (g *GCRA) SetClock(clock Clock) {
+	g.mu.Lock()
+	g.clock = clock
+	g.mu.Unlock()
+	g.Reset()
+}
+
+// StateBytes counts emission interval, tolerance and the TAT (redis-cell stores only the TAT)
+func (g *GCRA) StateBytes() int {
+	return 2*wordBytes + timeBytes
+}
@@ -0,0 +1,40 @@
+package rate_limiting
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewGCRAValidates(t *testing.T) {
+	tests := []struct {
+		name   string
+		limit  int
+		period time.Duration
+		ok     bool
+	}{
+		{name: "valid", limit: 10, period: time.Minute, ok: true},
+		{name: "zero limit", limit: 0, period: time.Minute},
+		{name: "negative limit", limit: -1, period: time.Minute},
+		{name: "zero period", limit: 10, period: 0},
+		{name: "period shorter than limit nanoseconds", limit: 10, period: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gcra, err := NewGCRA(tt.limit, tt.period, tt.limit)
+			if (err == nil) != tt.ok {
+				t.Fatalf("NewGCRA(%d, %v) error = %v, want ok %v", tt.limit, tt.period, err, tt.ok)
+			}
+			if tt.ok && !gcra.AllowRequest() {
+				t.Error("first request refused")
+			}
+		})
+	}
+
+	if _, err := NewLimiter("gcra", LimiterSpec{Limit: 0, WindowSeconds: 1}, RealClock()); err == nil {
+		t.Error("NewLimiter accepted a gcra spec with limit 0")
+	}
+	// A window too short for the limit reaches GCRA's check through the comparison report
+	if _, err := CompareAlgorithms(LimiterSpec{Limit: 5, WindowSeconds: 1e-10}, []ScheduledRequest{{OffsetMs: 0}}); err == nil {
+		t.Error("CompareAlgorithms accepted a window shorter than one nanosecond per request")
+	}
+}
@@ -0,0 +1,472 @@
+package rate_limiting
+
+import (
+	"container/list"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TierLevel is one level of a hierarchical limit
+type TierLevel string
+
+const (
+	TierGlobal   TierLevel = "global"   // One limit shared by every request
+	TierTenant   TierLevel = "tenant"   // One limit per tenant (customer account)
+	TierUser     TierLevel = "user"     // One limit per user within a tenant
+	TierEndpoint TierLevel = "endpoint" // One limit per endpoint within a tenant
+)
+
+// Bounds on the hierarchy's memory
+const (
+	MaxTierEntries   = 10000 // Tier keys tracked at once; the least recently used is dropped beyond this
+	maxTierDecisions = 50    // Recent decisions kept for GetState
+)
+
+// TierLevels lists the levels in evaluation order, outermost first
+var TierLevels = []TierLevel{TierGlobal, TierTenant, TierUser, TierEndpoint}
+
+// QuotaPeriod is a calendar period for usage quotas (UTC)
+type QuotaPeriod string
+
+const (
+	PeriodDaily   QuotaPeriod = "daily"
+	PeriodMonthly QuotaPeriod = "monthly"
+)
+
+// TierRule configures one level: a short-term rate limit using any of the
+// rate limiting algorithms, plus optional daily and monthly usage quotas
+type TierRule struct {
+	Level         TierLevel `json:"level"`
+	Algorithm     string    `json:"algorithm"`     // Key from LimiterAlgorithms (default tokenBucket)
+	Limit         int       `json:"limit"`         // Requests per window; 0 = no rate limit at this level
+	WindowSeconds float64   `json:"windowSeconds"` // Window length (refill period for buckets)
+	DailyQuota    int       `json:"dailyQuota"`    // Requests per UTC day; 0 = unlimited
+	MonthlyQuota  int       `json:"monthlyQuota"`  // Requests per UTC month; 0 = unlimited
+}
+
+// HierarchyConfig holds the default rules and the plans tenants may be on
+// A tenant's plan replaces the default rule for every level the plan defines,
+// except global, which always applies to everyone
+type HierarchyConfig struct {
+	Rules   []TierRule            `json:"rules"`
+	Plans   map[string][]TierRule `json:"plans"`   // Plan name -> rules
+	Tenants map[string]string     `json:"tenants"` // Tenant -> plan name
+}
+
+// HierarchicalRequest identifies who is calling what
+type HierarchicalRequest struct {
+	Tenant   string `json:"tenant"`
+	User     string `json:"user"`
+	Endpoint string `json:"endpoint"`
+}
+
+// TierRejection names a tier that would not admit a request and which of its limits was hit
+type TierRejection struct {
+	Level TierLevel `json:"level"`
+	Key   string    `json:"key"`
+	Limit string    `json:"limit"` // "rate", "daily" or "monthly"
+}
+
+// TierDecision records how one request was treated by the hierarchy
+type TierDecision struct {
+	Timestamp  time.Time           `json:"timestamp"`
+	Request    HierarchicalRequest `json:"request"`
+	Plan       string              `json:"plan"`
+	Allowed    bool                `json:"allowed"`
+	RejectedBy TierLevel           `json:"rejectedBy,omitempty"` // Outermost tier that rejected
+	Rejections []TierRejection     `json:"rejections,omitempty"` // Every tier that was over a limit
+}
+
+// quotaCounter counts requests in the current calendar period
+type quotaCounter struct {
+	period      QuotaPeriod
+	limit       int
+	used        int
+	periodStart time.Time
+}
+
+// periodStartFor returns the start of the period containing t (UTC)
+func periodStartFor(period QuotaPeriod, t time.Time) time.Time {
+	t = t.UTC()
+	if period == PeriodMonthly {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// roll starts a new period if now has left the current one
+func (q *quotaCounter) roll(now time.Time) {
+	if start := periodStartFor(q.period, now); !start.Equal(q.periodStart) {
+		q.periodStart = start
+		q.used = 0
+	}
+}
+
+// resetsAt returns when the current period ends
+func (q *quotaCounter) resetsAt() time.Time {
+	if q.period == PeriodMonthly {
+		return q.periodStart.AddDate(0, 1, 0)
+	}
+	return q.periodStart.AddDate(0, 0, 1)
+}
+
+// tierEntry is the live state of one tier key (e.g. tenant "acme")
+type tierEntry struct {
+	level    TierLevel
+	key      string
+	rule     TierRule
+	limiter  RateLimiter // nil when the rule has no rate limit
+	element  *list.Element
+	quotas   []*quotaCounter
+	accepted int
+	rejected int
+}
+
+// QuotaUsage is the visualization state of one quota counter
+type QuotaUsage struct {
+	Period   QuotaPeriod `json:"period"`
+	Limit    int         `json:"limit"`
+	Used     int         `json:"used"`
+	ResetsAt time.Time   `json:"resetsAt"`
+}
+
+// TierUsage is the visualization state of one tier key
+type TierUsage struct {
+	Level     TierLevel    `json:"level"`
+	Key       string       `json:"key"`
+	Algorithm string       `json:"algorithm,omitempty"`
+	Rate      *Quota       `json:"rate,omitempty"`
+	Quotas    []QuotaUsage `json:"quotas"`
+	Accepted  int          `json:"accepted"` // Requests this tier saw that were admitted
+	Rejected  int          `json:"rejected"` // Requests this tier rejected
+}
+
+// HierarchicalState is the visualization state of a hierarchical limiter
+type HierarchicalState struct {
+	Config         HierarchyConfig   `json:"config"`
+	Accepted       int               `json:"accepted"`
+	Rejected       int               `json:"rejected"`
+	RejectedByTier map[TierLevel]int `json:"rejectedByTier"`
+	Tiers          []TierUsage       `json:"tiers"`
+	Decisions      []TierDecision    `json:"decisions"` // Most recent 50, newest last
+}
+
+// HierarchicalLimiter evaluates global, tenant, user and endpoint limits
+// together: a request is admitted only if every tier has room, and only then
+// is it charged against every tier, so a rejection never burns another tier's allowance
+type HierarchicalLimiter struct {
+	mu             sync.Mutex
+	config         HierarchyConfig
+	entries        map[string]*tierEntry
+	recency        *list.List // front = most recently used tier key
+	decisions      []TierDecision
+	accepted       int
+	rejected       int
+	rejectedByTier map[TierLevel]int
+	clock          Clock
+}
+
+// DefaultHierarchyConfig is a small SaaS setup: a global cap, free and pro
+// plans with different tenant and user limits, and a per-endpoint limit
+func DefaultHierarchyConfig() HierarchyConfig {
+	return HierarchyConfig{
+		Rules: []TierRule{
+			{Level: TierGlobal, Algorithm: "tokenBucket", Limit: 50, WindowSeconds: 1},
+			{Level: TierTenant, Algorithm: "tokenBucket", Limit: 10, WindowSeconds: 1, DailyQuota: 1000, MonthlyQuota: 20000},
+			{Level: TierUser, Algorithm: "slidingWindow", Limit: 5, WindowSeconds: 1, DailyQuota: 200},
+			{Level: TierEndpoint, Algorithm: "fixedWindow", Limit: 8, WindowSeconds: 1},
+		},
+		Plans: map[string][]TierRule{
+			"pro": {
+				{Level: TierTenant, Algorithm: "tokenBucket", Limit: 40, WindowSeconds: 1, DailyQuota: 50000, MonthlyQuota: 1000000},
+				{Level: TierUser, Algorithm: "slidingWindow", Limit: 20, WindowSeconds: 1, DailyQuota: 10000},
+			},
+		},
+		Tenants: map[string]string{"acme": "pro"},
+	}
+}
+
+// NewHierarchicalLimiter creates a hierarchical limiter from the config
+func NewHierarchicalLimiter(config HierarchyConfig) (*HierarchicalLimiter, error) {
+	h := &HierarchicalLimiter{clock: RealClock()}
+	if err := h.Configure(config); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// validateRules checks every rule and fills in the default algorithm
+func validateRules(rules []TierRule) error {
+	seen := make(map[TierLevel]bool)
+	for i := range rules {
+		rule := &rules[i]
+		known := false
+		for _, level := range TierLevels {
+			known = known || rule.Level == level
+		}
+		if !known {
+			return fmt.Errorf("unknown tier level %q", rule.Level)
+		}
+		if seen[rule.Level] {
+			return fmt.Errorf("duplicate rule for tier %q", rule.Level)
+		}
+		seen[rule.Level] = true
+
+		if rule.Limit < 0 || rule.DailyQuota < 0 || rule.MonthlyQuota < 0 {
+			return fmt.Errorf("tier %q: limits must not be negative", rule.Level)
+		}
+		if rule.Limit > 0 {
+			if rule.WindowSeconds <= 0 {
+				return fmt.Errorf("tier %q: windowSeconds must be positive", rule.Level)
+			}
+			if rule.Algorithm == "" {
+				rule.Algorithm = "tokenBucket"
+			}
+			if _, err := NewLimiter(rule.Algorithm, LimiterSpec{Limit: rule.Limit, WindowSeconds: rule.WindowSeconds}, RealClock()); err != nil {
+				return fmt.Errorf("tier %q: %v", rule.Level, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Configure replaces the rules and plans, clearing all usage
+func (h *HierarchicalLimiter) Configure(config HierarchyConfig) error {
+	if err := validateRules(config.Rules); err != nil {
+		return err
+	}
+	for name, rules := range config.Plans {
+		if err := validateRules(rules); err != nil {
+			return fmt.Errorf("plan %q: %v", name, err)
+		}
+	}
+	for tenant, plan := range config.Tenants {
+		if _, exists := config.Plans[plan]; !exists {
+			return fmt.Errorf("tenant %q is on unknown plan %q", tenant, plan)
+		}
+	}
+
+	h.mu.Lock()
+	h.config = config
+	h.mu.Unlock()
+	h.Reset()
+	return nil
+}
+
+// ruleFor resolves the rule for a level given the tenant's plan (must be called with lock held)
+func (h *HierarchicalLimiter) ruleFor(level TierLevel, plan string) (TierRule, bool) {
+	if level != TierGlobal {
+		for _, rule := range h.config.Plans[plan] {
+			if rule.Level == level {
+				return rule, true
+			}
+		}
+	}
+	for _, rule := range h.config.Rules {
+		if rule.Level == level {
+			return rule, true
+		}
+	}
+	return TierRule{}, false
+}
+
+// tierKey returns the key a request is counted under at a level
+// Users and endpoints are scoped to their tenant
+func tierKey(level TierLevel, req HierarchicalRequest) string {
+	switch level {
+	case TierTenant:
+		return "tenant:" + req.Tenant
+	case TierUser:
+		return "user:" + req.Tenant + "/" + req.User
+	case TierEndpoint:
+		return "endpoint:" + req.Tenant + "/" + req.Endpoint
+	default:
+		return "global"
+	}
+}
+
+// entryFor returns the state for a tier key, creating it on first use (must be called with lock held)
+func (h *HierarchicalLimiter) entryFor(level TierLevel, key string, rule TierRule) *tierEntry {
+	if entry, exists := h.entries[key]; exists {
+		h.recency.MoveToFront(entry.element)
+		return entry
+	}
+
+	if len(h.entries) >= MaxTierEntries {
+		oldest := h.recency.Remove(h.recency.Back()).(*tierEntry)
+		delete(h.entries, oldest.key)
+	}
+
+	entry := &tierEntry{level: level, key: key, rule: rule}
+	entry.element = h.recency.PushFront(entry)
+	if rule.Limit > 0 {
+		entry.limiter, _ = NewLimiter(rule.Algorithm, LimiterSpec{Limit: rule.Limit, WindowSeconds: rule.WindowSeconds}, h.clock)
+	}
+	if rule.DailyQuota > 0 {
+		entry.quotas = append(entry.quotas, &quotaCounter{period: PeriodDaily, limit: rule.DailyQuota})
+	}
+	if rule.MonthlyQuota > 0 {
+		entry.quotas = append(entry.quotas, &quotaCounter{period: PeriodMonthly, limit: rule.MonthlyQuota})
+	}
+	h.entries[key] = entry
+	return entry
+}
+
+// AllowRequest checks the request against every tier and records the decision
+func (h *HierarchicalLimiter) AllowRequest(req HierarchicalRequest) TierDecision {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.clock.Now()
+	plan := h.config.Tenants[req.Tenant]
+
+	tiers := []*tierEntry{}
+	for _, level := range TierLevels {
+		if rule, exists := h.ruleFor(level, plan); exists {
+			tiers = append(tiers, h.entryFor(level, tierKey(level, req), rule))
+		}
+	}
+
+	// Check every tier without consuming anything
+	rejections := []TierRejection{}
+	for _, tier := range tiers {
+		if tier.limiter != nil && tier.limiter.Quota().Remaining < 1 {
+			rejections = append(rejections, TierRejection{Level: tier.level, Key: tier.key, Limit: "rate"})
+		}
+		for _, quota := range tier.quotas {
+			quota.roll(now)
+			if quota.used >= quota.limit {
+				rejections = append(rejections, TierRejection{Level: tier.level, Key: tier.key, Limit: string(quota.period)})
+			}
+		}
+	}
+
+	// Charge every tier; a limiter that still refuses (e.g. an adaptive
+	// limit shrinking on this call) rejects the request after all, and the
+	// tiers already charged get their allowance back
+	if len(rejections) == 0 {
+		for i, tier := range tiers {
+			if tier.limiter != nil && !tier.limiter.AllowRequest() {
+				rejections = append(rejections, TierRejection{Level: tier.level, Key: tier.key, Limit: "rate"})
+				for _, charged := range tiers[:i] {
+					if charged.limiter != nil {
+						refund(charged.limiter)
+					}
+				}
+				break
+			}
+		}
+	}
+
+	decision := TierDecision{
+		Timestamp:  now,
+		Request:    req,
+		Plan:       plan,
+		Allowed:    len(rejections) == 0,
+		Rejections: rejections,
+	}
+
+	if decision.Allowed {
+		h.accepted++
+		for _, tier := range tiers {
+			tier.accepted++
+			for _, quota := range tier.quotas {
+				quota.used++
+			}
+		}
+	} else {
+		h.rejected++
+		decision.RejectedBy = rejections[0].Level
+		h.rejectedByTier[decision.RejectedBy]++
+		for _, rejection := range rejections {
+			h.entries[rejection.Key].rejected++
+		}
+	}
+
+	h.decisions = append(h.decisions, decision)
+	if len(h.decisions) > maxTierDecisions {
+		h.decisions = append([]TierDecision{}, h.decisions[len(h.decisions)-maxTierDecisions:]...)
+	}
+	return decision
+}
+
+// GetState returns usage for every tier key and the most recent decisions
+func (h *HierarchicalLimiter) GetState() HierarchicalState {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.clock.Now()
+	rank := make(map[TierLevel]int)
+	for i, level := range TierLevels {
+		rank[level] = i
+	}
+
+	tiers := make([]TierUsage, 0, len(h.entries))
+	for _, entry := range h.entries {
+		usage := TierUsage{
+			Level:    entry.level,
+			Key:      entry.key,
+			Quotas:   []QuotaUsage{},
+			Accepted: entry.accepted,
+			Rejected: entry.rejected,
+		}
+		if entry.limiter != nil {
+			quota := entry.limiter.Quota()
+			usage.Algorithm = entry.limiter.GetName()
+			usage.Rate = &quota
+		}
+		for _, quota := range entry.quotas {
+			quota.roll(now)
+			usage.Quotas = append(usage.Quotas, QuotaUsage{
+				Period:   quota.period,
+				Limit:    quota.limit,
+				Used:     quota.used,
+				ResetsAt: quota.resetsAt(),
+			})
+		}
+		tiers = append(tiers, usage)
+	}
+	sort.Slice(tiers, func(i, j int) bool {
+		if tiers[i].Level != tiers[j].Level {
+			return rank[tiers[i].Level] < rank[tiers[j].Level]
+		}
+		return tiers[i].Key < tiers[j].Key
+	})
+
+	rejectedByTier := make(map[TierLevel]int, len(h.rejectedByTier))
+	for level, count := range h.rejectedByTier {
+		rejectedByTier[level] = count
+	}
+
+	return HierarchicalState{
+		Config:         h.config,
+		Accepted:       h.accepted,
+		Rejected:       h.rejected,
+		RejectedByTier: rejectedByTier,
+		Tiers:          tiers,
+		Decisions:      append([]TierDecision(nil), h.decisions...),
+	}
+}
+
+// Reset clears every tier's usage and the decision history, keeping the config
+func (h *HierarchicalLimiter) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.entries = make(map[string]*tierEntry)
+	h.recency = list.New()
+	h.decisions = []TierDecision{}
+	h.accepted = 0
+	h.rejected = 0
+	h.rejectedByTier = make(map[TierLevel]int)
+}
+
+// SetClock swaps the time source and resets the limiter onto it
+func (h *HierarchicalLimiter) SetClock(clock Clock) {
+	h.mu.Lock()
+	h.clock = clock
+	h.mu.Unlock()
+	h.Reset()
+}
@@ -0,0 +1,97 @@
+package rate_limiting
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// refusingLimiter reports spare quota but refuses every request, like a
+// limiter whose limit shrinks during the call
+type refusingLimiter struct {
+	RateLimiter
+}
+
+func (refusingLimiter) AllowRequest() bool { return false }
+
+func TestRefundRestoresAllowance(t *testing.T) {
+	spec := LimiterSpec{Limit: 3, WindowSeconds: 10}
+	for _, algorithm := range LimiterAlgorithms {
+		t.Run(algorithm, func(t *testing.T) {
+			clock := NewVirtualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+			limiter, err := NewLimiter(algorithm, spec, clock)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !limiter.AllowRequest() {
+				t.Fatal("first request refused")
+			}
+			refund(limiter)
+
+			allowed := 0
+			for i := 0; i < 2*spec.Limit; i++ {
+				if limiter.AllowRequest() {
+					allowed++
+				}
+			}
+			fresh, _ := NewLimiter(algorithm, spec, clock)
+			want := 0
+			for i := 0; i < 2*spec.Limit; i++ {
+				if fresh.AllowRequest() {
+					want++
+				}
+			}
+			if allowed != want {
+				t.Errorf("allowed %d requests after a refund, a fresh limiter allows %d", allowed, want)
+			}
+		})
+	}
+}
+
+func TestHierarchyRefundsOuterTiers(t *testing.T) {
+	h, err := NewHierarchicalLimiter(HierarchyConfig{Rules: []TierRule{
+		{Level: TierGlobal, Limit: 10, WindowSeconds: 60},
+		{Level: TierTenant, Limit: 10, WindowSeconds: 60},
+		{Level: TierUser, Limit: 10, WindowSeconds: 60},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.SetClock(NewVirtualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
+
+	req := HierarchicalRequest{Tenant: "acme", User: "alice"}
+	h.AllowRequest(req)
+	user := h.entries[tierKey(TierUser, req)]
+	user.limiter = refusingLimiter{user.limiter}
+
+	for i := 0; i < 20; i++ {
+		if h.AllowRequest(req).Allowed {
+			t.Fatal("request allowed by a refusing tier")
+		}
+	}
+	for _, level := range []TierLevel{TierGlobal, TierTenant} {
+		if remaining := h.entries[tierKey(level, req)].limiter.Quota().Remaining; remaining != 9 {
+			t.Errorf("%s tier has %d remaining, want 9", level, remaining)
+		}
+	}
+}
+
+func TestHierarchyBoundsState(t *testing.T) {
+	h, err := NewHierarchicalLimiter(DefaultHierarchyConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < MaxTierEntries; i++ {
+		h.AllowRequest(HierarchicalRequest{Tenant: "acme", User: fmt.Sprintf("user-%d", i), Endpoint: "/search"})
+	}
+
+	if len(h.entries) > MaxTierEntries || h.recency.Len() != len(h.entries) {
+		t.Errorf("%d tier keys tracked (%d in recency order), at most %d allowed", len(h.entries), h.recency.Len(), MaxTierEntries)
+	}
+	if len(h.decisions) > maxTierDecisions {
+		t.Errorf("%d decisions kept, at most %d allowed", len(h.decisions), maxTierDecisions)
+	}
+	if _, exists := h.entries["global"]; !exists {
+		t.Error("the global tier, used by every request, was evicted")
+	}
+}
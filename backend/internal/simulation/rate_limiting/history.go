@@ -0,0 +1,71 @@
+package rate_limiting
+
+// keyedRequestHistory is how many recent decisions each key of a keyed
+// limiter keeps; standalone limiters keep their whole history
+const keyedRequestHistory = 100
+
+// historyCapper is implemented by limiters whose request history can be
+// capped, so a keyed limiter's per-key state stays bounded
+type historyCapper interface {
+	capHistory(keep int)
+}
+
+// capHistory limits the limiter's request history if it supports a cap
+func capHistory(limiter RateLimiter, keep int) {
+	if h, ok := limiter.(historyCapper); ok {
+		h.capHistory(keep)
+	}
+}
+
+func (f *FixedWindowCounter) capHistory(keep int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.historyCap = keep
+}
+
+func (s *SlidingLog) capHistory(keep int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.historyCap = keep
+}
+
+func (sw *SlidingWindowCounter) capHistory(keep int) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	sw.historyCap = keep
+}
+
+func (tb *TokenBucket) capHistory(keep int) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.historyCap = keep
+}
+
+func (lb *LeakyBucket) capHistory(keep int) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	lb.historyCap = keep
+}
+
+func (g *GCRA) capHistory(keep int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.historyCap = keep
+}
+
+func (a *AdaptiveConcurrencyLimiter) capHistory(keep int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.historyCap = keep
+}
+
+// Compile-time checks that every algorithm NewLimiter builds can be capped
+var (
+	_ historyCapper = (*FixedWindowCounter)(nil)
+	_ historyCapper = (*SlidingLog)(nil)
+	_ historyCapper = (*SlidingWindowCounter)(nil)
+	_ historyCapper = (*TokenBucket)(nil)
+	_ historyCapper = (*LeakyBucket)(nil)
+	_ historyCapper = (*GCRA)(nil)
+	_ historyCapper = (*AdaptiveConcurrencyLimiter)(nil)
+)
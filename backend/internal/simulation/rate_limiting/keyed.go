@@ -0,0 +1,294 @@
+package rate_limiting
+
+import (
+	"container/list"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Approximate in-memory sizes used for StateBytes accounting (64-bit platform)
+const (
+	wordBytes        = 8  // int, float64, time.Duration
+	timeBytes        = 24 // time.Time
+	sliceHeaderBytes = 24 // pointer, len, cap
+	keyOverheadBytes = 64 // map bucket slot, list element and bookkeeping per key
+)
+
+// KeyedLimiter applies an independent limiter per key (user, IP, API key)
+// Keys idle for longer than idleTTL are evicted, and once maxKeys is reached
+// the least recently seen key is evicted to make room, bounding memory
+// Each key's visualization history is capped, so a key's memory no longer
+// grows with its traffic; memory figures count only the algorithm state
+type KeyedLimiter struct {
+	mu        sync.Mutex
+	algorithm string
+	name      string
+	spec      LimiterSpec
+	maxKeys   int
+	idleTTL   time.Duration
+	clock     Clock
+	entries   map[string]*keyedEntry
+	recency   *list.List // front = most recently seen key
+
+	memoryBytes       int // current StateBytes of all keys plus per-key overhead
+	peakMemoryBytes   int
+	peakKeys          int
+	idleEvictions     int
+	capacityEvictions int
+	accepted          int
+	rejected          int
+}
+
+// keyedEntry is the limiter and usage counters for one key
+type keyedEntry struct {
+	key      string
+	limiter  RateLimiter
+	element  *list.Element
+	lastSeen time.Time
+	accepted int
+	rejected int
+}
+
+// KeyState is the visualization state of a single key
+type KeyState struct {
+	Key        string    `json:"key"`
+	StateBytes int       `json:"stateBytes"`
+	LastSeen   time.Time `json:"lastSeen"`
+	Accepted   int       `json:"accepted"`
+	Rejected   int       `json:"rejected"`
+}
+
+// KeyedState is the visualization state of a keyed limiter
+type KeyedState struct {
+	Algorithm         string      `json:"algorithm"`
+	Spec              LimiterSpec `json:"spec"`
+	MaxKeys           int         `json:"maxKeys"`
+	IdleTTLSeconds    float64     `json:"idleTTLSeconds"`
+	ActiveKeys        int         `json:"activeKeys"`
+	PeakKeys          int         `json:"peakKeys"`
+	MemoryBytes       int         `json:"memoryBytes"`
+	PeakMemoryBytes   int         `json:"peakMemoryBytes"`
+	BytesPerKey       float64     `json:"bytesPerKey"`
+	IdleEvictions     int         `json:"idleEvictions"`
+	CapacityEvictions int         `json:"capacityEvictions"` // Evicted while still active (their limit resets)
+	Accepted          int         `json:"accepted"`
+	Rejected          int         `json:"rejected"`
+	TopKeys           []KeyState  `json:"topKeys"` // Largest keys by memory
+}
+
+// NewKeyedLimiter creates a keyed limiter for one algorithm
+// maxKeys <= 0 means unbounded, idleTTL <= 0 disables idle eviction
+func NewKeyedLimiter(algorithm string, spec LimiterSpec, maxKeys int, idleTTL time.Duration) (*KeyedLimiter, error) {
+	if spec.Limit <= 0 || spec.WindowSeconds <= 0 {
+		return nil, fmt.Errorf("limit and windowSeconds must be positive")
+	}
+	probe, err := NewLimiter(algorithm, spec, RealClock())
+	if err != nil {
+		return nil, err
+	}
+	return &KeyedLimiter{
+		algorithm: algorithm,
+		name:      probe.GetName(),
+		spec:      spec,
+		maxKeys:   maxKeys,
+		idleTTL:   idleTTL,
+		clock:     RealClock(),
+		entries:   make(map[string]*keyedEntry),
+		recency:   list.New(),
+	}, nil
+}
+
+// NewKeyedLimiterSet creates a keyed limiter for every algorithm
+func NewKeyedLimiterSet(spec LimiterSpec, maxKeys int, idleTTL time.Duration) ([]*KeyedLimiter, error) {
+	limiters := make([]*KeyedLimiter, 0, len(LimiterAlgorithms))
+	for _, algorithm := range LimiterAlgorithms {
+		limiter, err := NewKeyedLimiter(algorithm, spec, maxKeys, idleTTL)
+		if err != nil {
+			return nil, err
+		}
+		limiters = append(limiters, limiter)
+	}
+	return limiters, nil
+}
+
+// AllowRequest checks the request against the limiter for its key
+func (k *KeyedLimiter) AllowRequest(key string) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := k.clock.Now()
+	k.evictIdle(now)
+
+	entry, exists := k.entries[key]
+	if !exists {
+		if k.maxKeys > 0 && len(k.entries) >= k.maxKeys {
+			k.evict(k.recency.Back().Value.(*keyedEntry))
+			k.capacityEvictions++
+		}
+		limiter, _ := NewLimiter(k.algorithm, k.spec, k.clock)
+		capHistory(limiter, keyedRequestHistory)
+		if shaper, ok := limiter.(Shaper); ok {
+			// Per-key queueing delays are never shown
+			shaper.KeepReleases(1)
+		}
+		entry = &keyedEntry{key: key, limiter: limiter}
+		entry.element = k.recency.PushFront(entry)
+		k.entries[key] = entry
+		k.memoryBytes += keyOverheadBytes + len(key) + limiter.StateBytes()
+	} else {
+		k.recency.MoveToFront(entry.element)
+	}
+
+	before := entry.limiter.StateBytes()
+	allowed := entry.limiter.AllowRequest()
+	k.memoryBytes += entry.limiter.StateBytes() - before
+	entry.lastSeen = now
+
+	if allowed {
+		entry.accepted++
+		k.accepted++
+	} else {
+		entry.rejected++
+		k.rejected++
+	}
+
+	if k.memoryBytes > k.peakMemoryBytes {
+		k.peakMemoryBytes = k.memoryBytes
+	}
+	if len(k.entries) > k.peakKeys {
+		k.peakKeys = len(k.entries)
+	}
+	return allowed
+}
+
+// evictIdle drops keys not seen within idleTTL (must be called with lock held)
+func (k *KeyedLimiter) evictIdle(now time.Time) {
+	if k.idleTTL <= 0 {
+		return
+	}
+	for back := k.recency.Back(); back != nil; back = k.recency.Back() {
+		entry := back.Value.(*keyedEntry)
+		if now.Sub(entry.lastSeen) < k.idleTTL {
+			return
+		}
+		k.evict(entry)
+		k.idleEvictions++
+	}
+}
+
+// evict removes a key and its memory (must be called with lock held)
+func (k *KeyedLimiter) evict(entry *keyedEntry) {
+	k.memoryBytes -= keyOverheadBytes + len(entry.key) + entry.limiter.StateBytes()
+	k.recency.Remove(entry.element)
+	delete(k.entries, entry.key)
+}
+
+// GetState returns the keyed limiter state, including the 10 largest keys
+func (k *KeyedLimiter) GetState() KeyedState {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	keys := make([]KeyState, 0, len(k.entries))
+	for _, entry := range k.entries {
+		keys = append(keys, KeyState{
+			Key:        entry.key,
+			StateBytes: entry.limiter.StateBytes(),
+			LastSeen:   entry.lastSeen,
+			Accepted:   entry.accepted,
+			Rejected:   entry.rejected,
+		})
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].StateBytes != keys[j].StateBytes {
+			return keys[i].StateBytes > keys[j].StateBytes
+		}
+		return keys[i].Key < keys[j].Key
+	})
+	if len(keys) > 10 {
+		keys = keys[:10]
+	}
+
+	bytesPerKey := 0.0
+	if len(k.entries) > 0 {
+		bytesPerKey = float64(k.memoryBytes) / float64(len(k.entries))
+	}
+
+	return KeyedState{
+		Algorithm:         k.name,
+		Spec:              k.spec,
+		MaxKeys:           k.maxKeys,
+		IdleTTLSeconds:    k.idleTTL.Seconds(),
+		ActiveKeys:        len(k.entries),
+		PeakKeys:          k.peakKeys,
+		MemoryBytes:       k.memoryBytes,
+		PeakMemoryBytes:   k.peakMemoryBytes,
+		BytesPerKey:       bytesPerKey,
+		IdleEvictions:     k.idleEvictions,
+		CapacityEvictions: k.capacityEvictions,
+		Accepted:          k.accepted,
+		Rejected:          k.rejected,
+		TopKeys:           keys,
+	}
+}
+
+// Reset drops every key and counter
+func (k *KeyedLimiter) Reset() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.entries = make(map[string]*keyedEntry)
+	k.recency = list.New()
+	k.memoryBytes = 0
+	k.peakMemoryBytes = 0
+	k.peakKeys = 0
+	k.idleEvictions = 0
+	k.capacityEvictions = 0
+	k.accepted = 0
+	k.rejected = 0
+}
+
+// SetClock swaps the time source and resets the limiter onto it
+func (k *KeyedLimiter) SetClock(clock Clock) {
+	k.mu.Lock()
+	k.clock = clock
+	k.mu.Unlock()
+	k.Reset()
+}
+
+// Algorithm returns the algorithm key this limiter applies per client
+func (k *KeyedLimiter) Algorithm() string {
+	return k.algorithm
+}
+
+// SimulateKeyed replays a timeline against keyed limiters of every algorithm
+// on a virtual clock and returns their final states for memory comparison
+func SimulateKeyed(spec LimiterSpec, maxKeys int, idleTTL time.Duration, requests []ScheduledRequest) ([]KeyedState, error) {
+	limiters, err := NewKeyedLimiterSet(spec, maxKeys, idleTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	timeline := append([]ScheduledRequest(nil), requests...)
+	sort.SliceStable(timeline, func(i, j int) bool { return timeline[i].OffsetMs < timeline[j].OffsetMs })
+
+	start := time.Unix(0, 0).UTC()
+	clock := NewVirtualClock(start)
+	for _, limiter := range limiters {
+		limiter.SetClock(clock)
+	}
+
+	for _, req := range timeline {
+		clock.Set(start.Add(time.Duration(req.OffsetMs) * time.Millisecond))
+		for _, limiter := range limiters {
+			limiter.AllowRequest(req.ClientID)
+		}
+	}
+
+	states := make([]KeyedState, 0, len(limiters))
+	for _, limiter := range limiters {
+		states = append(states, limiter.GetState())
+	}
+	return states, nil
+}
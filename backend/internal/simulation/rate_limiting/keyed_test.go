@@ -0,0 +1,52 @@
+package rate_limiting
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyedLimiterBoundsPerKeyHistory(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, algorithm := range LimiterAlgorithms {
+		t.Run(algorithm, func(t *testing.T) {
+			clock := NewVirtualClock(start)
+			keyed, err := NewKeyedLimiter(algorithm, LimiterSpec{Limit: 10, WindowSeconds: 1}, 10, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			keyed.SetClock(clock)
+
+			for i := 0; i < 20*keyedRequestHistory; i++ {
+				clock.Set(start.Add(time.Duration(i) * 50 * time.Millisecond))
+				keyed.AllowRequest("client")
+			}
+
+			state := keyed.entries["client"].limiter.GetState().(map[string]interface{})
+			if history := state["requestHistory"].([]RequestLog); len(history) > 2*keyedRequestHistory {
+				t.Errorf("key keeps %d requests of history, at most %d allowed", len(history), 2*keyedRequestHistory)
+			}
+			if shaper, ok := keyed.entries["client"].limiter.(Shaper); ok && len(shaper.Releases()) > 1 {
+				t.Errorf("key keeps %d releases", len(shaper.Releases()))
+			}
+		})
+	}
+}
+
+func TestStandaloneLimiterKeepsFullHistory(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewVirtualClock(start)
+	limiter, err := NewLimiter("tokenBucket", LimiterSpec{Limit: 10, WindowSeconds: 1}, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := 5 * keyedRequestHistory
+	for i := 0; i < requests; i++ {
+		clock.Set(start.Add(time.Duration(i) * 50 * time.Millisecond))
+		limiter.AllowRequest()
+	}
+
+	state := limiter.GetState().(map[string]interface{})
+	if history := state["requestHistory"].([]RequestLog); len(history) != requests {
+		t.Errorf("limiter shows %d requests of history, want all %d", len(history), requests)
+	}
+}
@@ -0,0 +1,180 @@
+package rate_limiting
+
+import (
+	"math"
+	"time"
+)
+
+// Quota is a limiter's remaining allowance, in the shape of the IETF
+// RateLimit-Limit / RateLimit-Remaining / RateLimit-Reset headers
+type Quota struct {
+	Limit      int           `json:"limit"`
+	Remaining  int           `json:"remaining"`
+	Reset      time.Duration `json:"reset"`      // Until the quota is fully restored
+	RetryAfter time.Duration `json:"retryAfter"` // Until the next request would be allowed (0 = now)
+}
+
+// secondsToDuration converts fractional seconds to a duration
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+func (f *FixedWindowCounter) Quota() Quota {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	now := f.clock.Now()
+	reset := f.windowStart.Add(f.windowSize).Sub(now)
+	if reset <= 0 {
+		return Quota{Limit: f.limit, Remaining: f.limit}
+	}
+
+	quota := Quota{Limit: f.limit, Remaining: max(0, f.limit-f.counter), Reset: reset}
+	if quota.Remaining == 0 {
+		quota.RetryAfter = reset
+	}
+	return quota
+}
+
+func (s *SlidingLog) Quota() Quota {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.clock.Now()
+	windowStart := now.Add(-s.windowSize)
+
+	inWindow := []time.Time{}
+	for _, timestamp := range s.requestLog {
+		if timestamp.After(windowStart) {
+			inWindow = append(inWindow, timestamp)
+		}
+	}
+
+	quota := Quota{Limit: s.limit, Remaining: max(0, s.limit-len(inWindow))}
+	if len(inWindow) > 0 {
+		// Fully restored once the newest request leaves the window
+		quota.Reset = inWindow[len(inWindow)-1].Add(s.windowSize).Sub(now)
+		if quota.Remaining == 0 {
+			// A slot frees up when the oldest request in the window expires
+			quota.RetryAfter = inWindow[len(inWindow)-s.limit].Add(s.windowSize).Sub(now)
+		}
+	}
+	return quota
+}
+
+func (sw *SlidingWindowCounter) Quota() Quota {
+	estimated := sw.estimatedCount()
+
+	sw.mu.RLock()
+	defer sw.mu.RUnlock()
+
+	quota := Quota{
+		Limit:     sw.limit,
+		Remaining: max(0, int(math.Ceil(float64(sw.limit)-estimated))),
+	}
+	if estimated > 0 {
+		// Every counted request has aged out one window after the current one ends
+		quota.Reset = sw.currWindowStart.Add(2 * sw.windowSize).Sub(sw.clock.Now())
+	}
+	if quota.Remaining == 0 {
+		quota.RetryAfter = sw.retryAfter(estimated)
+	}
+	return quota
+}
+
+// retryAfter estimates when the weighted count drops below the limit again
+// (must be called with lock held)
+func (sw *SlidingWindowCounter) retryAfter(estimated float64) time.Duration {
+	target := float64(sw.limit - 1)
+	untilRollover := sw.currWindowStart.Add(sw.windowSize).Sub(sw.clock.Now())
+	if untilRollover < 0 {
+		untilRollover = 0
+	}
+
+	// The previous window's weight decays linearly until the current window ends
+	if sw.prevCounter > 0 {
+		wait := time.Duration((estimated - target) / float64(sw.prevCounter) * float64(sw.windowSize))
+		if wait <= untilRollover {
+			return wait
+		}
+	}
+
+	// Otherwise wait for the current window to roll over and decay in turn
+	if sw.currCounter == 0 {
+		return untilRollover
+	}
+	decay := 1 - target/float64(sw.currCounter)
+	return untilRollover + time.Duration(maxFloat(0, decay)*float64(sw.windowSize))
+}
+
+func (tb *TokenBucket) Quota() Quota {
+	tb.mu.RLock()
+	defer tb.mu.RUnlock()
+
+	elapsed := tb.clock.Now().Sub(tb.lastRefill).Seconds()
+	tokens := min(float64(tb.capacity), tb.tokens+(elapsed*tb.refillRate))
+
+	quota := Quota{
+		Limit:     tb.capacity,
+		Remaining: int(tokens),
+		Reset:     secondsToDuration((float64(tb.capacity) - tokens) / tb.refillRate),
+	}
+	if tokens < 1 {
+		quota.RetryAfter = secondsToDuration((1 - tokens) / tb.refillRate)
+	}
+	return quota
+}
+
+func (lb *LeakyBucket) Quota() Quota {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	now := lb.clock.Now()
+	waiting := lb.queue[lb.processedCount(now):]
+
+	quota := Quota{Limit: lb.capacity, Remaining: max(0, lb.capacity-len(waiting))}
+	if len(waiting) > 0 {
+		quota.Reset = lb.drainedAt(waiting[len(waiting)-1]).Sub(now)
+		if quota.Remaining == 0 {
+			quota.RetryAfter = lb.drainedAt(waiting[0]).Sub(now)
+		}
+	}
+	return quota
+}
+
+func (g *GCRA) Quota() Quota {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	now := g.clock.Now()
+	reset := g.tat.Sub(now)
+	if reset < 0 {
+		reset = 0
+	}
+	return Quota{
+		Limit:      g.burst,
+		Remaining:  g.remaining(now),
+		Reset:      reset,
+		RetryAfter: g.retryAfter(now),
+	}
+}
+
+// Quota first completes the requests done by now, as AllowRequest would, so
+// Remaining is the current limit minus the requests still in flight
+func (a *AdaptiveConcurrencyLimiter) Quota() Quota {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.clock.Now()
+	a.completeUntil(now)
+
+	limit := int(a.limit)
+	quota := Quota{Limit: limit, Remaining: max(0, limit-len(a.inflight))}
+	if len(a.inflight) > 0 {
+		quota.Reset = a.inflight[len(a.inflight)-1].done.Sub(now)
+		if quota.Remaining == 0 {
+			quota.RetryAfter = a.inflight[0].done.Sub(now)
+		}
+	}
+	return quota
+}
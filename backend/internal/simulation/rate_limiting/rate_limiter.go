@@ -19,6 +19,16 @@ type RateLimiter interface {
 	
 	// GetName returns the name of the algorithm
 	GetName() string
+
+	// SetClock swaps the time source and resets the limiter onto it
+	SetClock(clock Clock)
+
+	// StateBytes estimates the memory the algorithm needs to track one client
+	// (visualization history is excluded)
+	StateBytes() int
+
+	// Quota reports the remaining allowance for RateLimit response headers
+	Quota() Quota
 }
 
 // RequestLog represents a single request for logging-based algorithms
@@ -27,6 +37,16 @@ type RequestLog struct {
 	Allowed   bool      `json:"allowed"`
 }
 
+// recordRequest appends a decision to a limiter's history; when keep is set,
+// only about the most recent keep decisions are retained
+func recordRequest(history []RequestLog, keep int, now time.Time, allowed bool) []RequestLog {
+	history = append(history, RequestLog{Timestamp: now, Allowed: allowed})
+	if keep > 0 && len(history) > 2*keep {
+		history = append([]RequestLog{}, history[len(history)-keep:]...)
+	}
+	return history
+}
+
 // FixedWindowCounter implements rate limiting with fixed time windows
 // Simple but can allow 2x limit at window boundaries
 type FixedWindowCounter struct {
@@ -35,7 +55,9 @@ type FixedWindowCounter struct {
 	windowSize    time.Duration
 	counter       int       // Current count in window
 	windowStart   time.Time // Start of current window
-	requestHistory []RequestLog `json:"requestHistory"`
+	requestHistory []RequestLog
+	historyCap    int // Decisions kept in requestHistory; 0 keeps them all
+	clock         Clock
 }
 
 // NewFixedWindowCounter creates a new fixed window counter rate limiter
@@ -46,6 +68,7 @@ func NewFixedWindowCounter(limit int, windowSize time.Duration) *FixedWindowCoun
 		counter:       0,
 		windowStart:   time.Now(),
 		requestHistory: []RequestLog{},
+		clock:         RealClock(),
 	}
 }
 
@@ -53,7 +76,7 @@ func (f *FixedWindowCounter) AllowRequest() bool {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 	
-	now := time.Now()
+	now := f.clock.Now()
 	
 	// Check if we need to reset the window
 	if now.Sub(f.windowStart) >= f.windowSize {
@@ -68,10 +91,7 @@ func (f *FixedWindowCounter) AllowRequest() bool {
 	}
 	
 	// Record request
-	f.requestHistory = append(f.requestHistory, RequestLog{
-		Timestamp: now,
-		Allowed:   allowed,
-	})
+	f.requestHistory = recordRequest(f.requestHistory, f.historyCap, now, allowed)
 	
 	return allowed
 }
@@ -87,6 +107,7 @@ func (f *FixedWindowCounter) GetState() interface{} {
 		"currentCount":   f.counter,
 		"windowStart":    f.windowStart,
 		"windowEnd":      f.windowStart.Add(f.windowSize),
+		"stateBytes":     f.StateBytes(),
 		"requestHistory": f.requestHistory,
 	}
 }
@@ -96,7 +117,7 @@ func (f *FixedWindowCounter) Reset() {
 	defer f.mu.Unlock()
 	
 	f.counter = 0
-	f.windowStart = time.Now()
+	f.windowStart = f.clock.Now()
 	f.requestHistory = []RequestLog{}
 }
 
@@ -104,6 +125,19 @@ func (f *FixedWindowCounter) GetName() string {
 	return "Fixed Window Counter"
 }
 
+// SetClock swaps the time source and resets the limiter onto it
+func (f *FixedWindowCounter) SetClock(clock Clock) {
+	f.mu.Lock()
+	f.clock = clock
+	f.mu.Unlock()
+	f.Reset()
+}
+
+// StateBytes counts limit, window size, counter and window start
+func (f *FixedWindowCounter) StateBytes() int {
+	return 3*wordBytes + timeBytes
+}
+
 // SlidingLog implements rate limiting by keeping a log of all requests
 // Accurate but memory-intensive
 type SlidingLog struct {
@@ -112,6 +146,8 @@ type SlidingLog struct {
 	windowSize    time.Duration
 	requestLog    []time.Time // Timestamps of allowed requests
 	requestHistory []RequestLog
+	historyCap    int // Decisions kept in requestHistory; 0 keeps them all
+	clock         Clock
 }
 
 func NewSlidingLog(limit int, windowSize time.Duration) *SlidingLog {
@@ -120,6 +156,7 @@ func NewSlidingLog(limit int, windowSize time.Duration) *SlidingLog {
 		windowSize:    windowSize,
 		requestLog:    []time.Time{},
 		requestHistory: []RequestLog{},
+		clock:         RealClock(),
 	}
 }
 
@@ -127,7 +164,7 @@ func (s *SlidingLog) AllowRequest() bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	
-	now := time.Now()
+	now := s.clock.Now()
 	windowStart := now.Add(-s.windowSize)
 	
 	// Remove old requests outside the window
@@ -146,10 +183,7 @@ func (s *SlidingLog) AllowRequest() bool {
 	}
 	
 	// Record request
-	s.requestHistory = append(s.requestHistory, RequestLog{
-		Timestamp: now,
-		Allowed:   allowed,
-	})
+	s.requestHistory = recordRequest(s.requestHistory, s.historyCap, now, allowed)
 	
 	return allowed
 }
@@ -158,7 +192,7 @@ func (s *SlidingLog) GetState() interface{} {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	
-	now := time.Now()
+	now := s.clock.Now()
 	windowStart := now.Add(-s.windowSize)
 	
 	return map[string]interface{}{
@@ -169,6 +203,7 @@ func (s *SlidingLog) GetState() interface{} {
 		"windowStart":    windowStart,
 		"windowEnd":      now,
 		"requestLog":     s.requestLog,
+		"stateBytes":     s.stateBytes(),
 		"requestHistory": s.requestHistory,
 	}
 }
@@ -185,6 +220,26 @@ func (s *SlidingLog) GetName() string {
 	return "Sliding Log"
 }
 
+// SetClock swaps the time source and resets the limiter onto it
+func (s *SlidingLog) SetClock(clock Clock) {
+	s.mu.Lock()
+	s.clock = clock
+	s.mu.Unlock()
+	s.Reset()
+}
+
+// StateBytes grows with every timestamp kept in the log
+func (s *SlidingLog) StateBytes() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.stateBytes()
+}
+
+// stateBytes is StateBytes without locking (must be called with lock held)
+func (s *SlidingLog) stateBytes() int {
+	return 2*wordBytes + sliceHeaderBytes + timeBytes*len(s.requestLog)
+}
+
 // SlidingWindowCounter combines fixed window and sliding log approaches
 // More memory efficient than sliding log, more accurate than fixed window
 type SlidingWindowCounter struct {
@@ -195,6 +250,8 @@ type SlidingWindowCounter struct {
 	currCounter   int       // Count in current window
 	currWindowStart time.Time
 	requestHistory []RequestLog
+	historyCap    int // Decisions kept in requestHistory; 0 keeps them all
+	clock         Clock
 }
 
 func NewSlidingWindowCounter(limit int, windowSize time.Duration) *SlidingWindowCounter {
@@ -205,14 +262,21 @@ func NewSlidingWindowCounter(limit int, windowSize time.Duration) *SlidingWindow
 		currCounter:    0,
 		currWindowStart: time.Now(),
 		requestHistory: []RequestLog{},
+		clock:          RealClock(),
 	}
 }
 
 func (sw *SlidingWindowCounter) AllowRequest() bool {
+	return sw.allowWithRemote(0)
+}
+
+// allowWithRemote is AllowRequest with requests seen elsewhere (other gateway
+// nodes) also counted toward the limit
+func (sw *SlidingWindowCounter) allowWithRemote(remote float64) bool {
 	sw.mu.Lock()
 	defer sw.mu.Unlock()
 	
-	now := time.Now()
+	now := sw.clock.Now()
 	
 	// Check if we need to move to next window
 	if now.Sub(sw.currWindowStart) >= sw.windowSize {
@@ -226,17 +290,14 @@ func (sw *SlidingWindowCounter) AllowRequest() bool {
 	prevWeight := 1.0 - (float64(elapsed) / float64(sw.windowSize))
 	estimatedCount := float64(sw.prevCounter)*prevWeight + float64(sw.currCounter)
 	
-	allowed := estimatedCount < float64(sw.limit)
+	allowed := estimatedCount+remote < float64(sw.limit)
 	
 	if allowed {
 		sw.currCounter++
 	}
 	
 	// Record request
-	sw.requestHistory = append(sw.requestHistory, RequestLog{
-		Timestamp: now,
-		Allowed:   allowed,
-	})
+	sw.requestHistory = recordRequest(sw.requestHistory, sw.historyCap, now, allowed)
 	
 	return allowed
 }
@@ -245,7 +306,7 @@ func (sw *SlidingWindowCounter) GetState() interface{} {
 	sw.mu.RLock()
 	defer sw.mu.RUnlock()
 	
-	now := time.Now()
+	now := sw.clock.Now()
 	elapsed := now.Sub(sw.currWindowStart)
 	prevWeight := 1.0 - (float64(elapsed) / float64(sw.windowSize))
 	estimatedCount := float64(sw.prevCounter)*prevWeight + float64(sw.currCounter)
@@ -258,6 +319,7 @@ func (sw *SlidingWindowCounter) GetState() interface{} {
 		"currentCount":   sw.currCounter,
 		"estimatedCount": estimatedCount,
 		"windowStart":    sw.currWindowStart,
+		"stateBytes":     sw.StateBytes(),
 		"requestHistory": sw.requestHistory,
 	}
 }
@@ -268,7 +330,7 @@ func (sw *SlidingWindowCounter) Reset() {
 	
 	sw.prevCounter = 0
 	sw.currCounter = 0
-	sw.currWindowStart = time.Now()
+	sw.currWindowStart = sw.clock.Now()
 	sw.requestHistory = []RequestLog{}
 }
 
@@ -276,6 +338,34 @@ func (sw *SlidingWindowCounter) GetName() string {
 	return "Sliding Window Counter"
 }
 
+// SetClock swaps the time source and resets the limiter onto it
+func (sw *SlidingWindowCounter) SetClock(clock Clock) {
+	sw.mu.Lock()
+	sw.clock = clock
+	sw.mu.Unlock()
+	sw.Reset()
+}
+
+// StateBytes counts limit, window size, both counters and window start
+func (sw *SlidingWindowCounter) StateBytes() int {
+	return 4*wordBytes + timeBytes
+}
+
+// estimatedCount returns the weighted request count at the current time
+func (sw *SlidingWindowCounter) estimatedCount() float64 {
+	sw.mu.RLock()
+	defer sw.mu.RUnlock()
+
+	elapsed := sw.clock.Now().Sub(sw.currWindowStart)
+	if elapsed >= sw.windowSize {
+		// The current window has ended: it is now the previous one
+		weight := 1.0 - float64(elapsed-sw.windowSize)/float64(sw.windowSize)
+		return float64(sw.currCounter) * maxFloat(0, weight)
+	}
+	prevWeight := 1.0 - (float64(elapsed) / float64(sw.windowSize))
+	return float64(sw.prevCounter)*prevWeight + float64(sw.currCounter)
+}
+
 // TokenBucket allows bursts of traffic up to bucket capacity
 // Tokens are added at a fixed rate
 type TokenBucket struct {
@@ -285,6 +375,8 @@ type TokenBucket struct {
 	tokens        float64   // Current tokens
 	lastRefill    time.Time
 	requestHistory []RequestLog
+	historyCap    int // Decisions kept in requestHistory; 0 keeps them all
+	clock         Clock
 }
 
 func NewTokenBucket(capacity int, refillRate float64) *TokenBucket {
@@ -294,6 +386,7 @@ func NewTokenBucket(capacity int, refillRate float64) *TokenBucket {
 		tokens:        float64(capacity), // Start with full bucket
 		lastRefill:    time.Now(),
 		requestHistory: []RequestLog{},
+		clock:         RealClock(),
 	}
 }
 
@@ -301,7 +394,7 @@ func (tb *TokenBucket) AllowRequest() bool {
 	tb.mu.Lock()
 	defer tb.mu.Unlock()
 	
-	now := time.Now()
+	now := tb.clock.Now()
 	
 	// Refill tokens based on time elapsed
 	elapsed := now.Sub(tb.lastRefill).Seconds()
@@ -315,10 +408,7 @@ func (tb *TokenBucket) AllowRequest() bool {
 	}
 	
 	// Record request
-	tb.requestHistory = append(tb.requestHistory, RequestLog{
-		Timestamp: now,
-		Allowed:   allowed,
-	})
+	tb.requestHistory = recordRequest(tb.requestHistory, tb.historyCap, now, allowed)
 	
 	return allowed
 }
@@ -333,6 +423,7 @@ func (tb *TokenBucket) GetState() interface{} {
 		"refillRate":     tb.refillRate,
 		"currentTokens":  tb.tokens,
 		"lastRefill":     tb.lastRefill,
+		"stateBytes":     tb.StateBytes(),
 		"requestHistory": tb.requestHistory,
 	}
 }
@@ -342,7 +433,7 @@ func (tb *TokenBucket) Reset() {
 	defer tb.mu.Unlock()
 	
 	tb.tokens = float64(tb.capacity)
-	tb.lastRefill = time.Now()
+	tb.lastRefill = tb.clock.Now()
 	tb.requestHistory = []RequestLog{}
 }
 
@@ -350,64 +441,180 @@ func (tb *TokenBucket) GetName() string {
 	return "Token Bucket"
 }
 
+// SetClock swaps the time source and resets the limiter onto it
+func (tb *TokenBucket) SetClock(clock Clock) {
+	tb.mu.Lock()
+	tb.clock = clock
+	tb.mu.Unlock()
+	tb.Reset()
+}
+
+// StateBytes counts capacity, refill rate, tokens and last refill time
+func (tb *TokenBucket) StateBytes() int {
+	return 3*wordBytes + timeBytes
+}
+
+// take refills the bucket and removes up to n whole tokens, returning how
+// many were granted (used to lease tokens to gateway nodes)
+func (tb *TokenBucket) take(n int) int {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	now := tb.clock.Now()
+	elapsed := now.Sub(tb.lastRefill).Seconds()
+	tb.tokens = min(float64(tb.capacity), tb.tokens+(elapsed*tb.refillRate))
+	tb.lastRefill = now
+
+	granted := n
+	if available := int(tb.tokens); available < granted {
+		granted = available
+	}
+	tb.tokens -= float64(granted)
+	return granted
+}
+
+// Release records when an accepted request was handed to the backend
+type Release struct {
+	Arrival   time.Time `json:"arrival"`
+	Processed time.Time `json:"processed"`
+}
+
+// Shaper is implemented by limiters that delay accepted requests instead of
+// forwarding them immediately (traffic shaping rather than policing)
+type Shaper interface {
+	// Releases returns the most recent accepted requests with their processing time, in acceptance order
+	Releases() []Release
+
+	// KeepReleases sets how many accepted requests Releases returns
+	KeepReleases(n int)
+}
+
+// maxLeakyReleases is how many accepted requests a leaky bucket keeps for
+// its queueing delay statistics unless told to keep more
+const maxLeakyReleases = 1000
+
 // LeakyBucket processes requests at a fixed rate
-// Excess requests are queued (or rejected if queue is full)
+// Accepted requests wait in a FIFO queue and are released one every
+// 1/processRate seconds; a request occupies the bucket until its processing
+// slot has passed, and requests arriving to a full bucket are rejected
 type LeakyBucket struct {
-	mu            sync.RWMutex
-	capacity      int       // Max queue size
-	processRate   float64   // Requests processed per second
-	queue         int       // Current queue size
-	lastProcess   time.Time
+	mu             sync.RWMutex
+	capacity       int       // Max queue size
+	processRate    float64   // Requests processed per second
+	queue          []Release // Waiting requests with their scheduled processing time
+	lastScheduled  time.Time // Processing slot of the most recently accepted request
+	releases       []Release // Recent accepted requests, for queueing delay statistics
+	keepReleases   int       // Accepted requests kept in releases
 	requestHistory []RequestLog
+	historyCap     int // Decisions kept in requestHistory; 0 keeps them all
+	clock          Clock
 }
 
 func NewLeakyBucket(capacity int, processRate float64) *LeakyBucket {
 	return &LeakyBucket{
-		capacity:      capacity,
-		processRate:   processRate,
-		queue:         0,
-		lastProcess:   time.Now(),
+		capacity:       capacity,
+		processRate:    processRate,
+		queue:          []Release{},
+		releases:       []Release{},
+		keepReleases:   maxLeakyReleases,
 		requestHistory: []RequestLog{},
+		clock:          RealClock(),
 	}
 }
 
 func (lb *LeakyBucket) AllowRequest() bool {
 	lb.mu.Lock()
 	defer lb.mu.Unlock()
-	
-	now := time.Now()
-	
-	// Process (leak) requests based on time elapsed
-	elapsed := now.Sub(lb.lastProcess).Seconds()
-	processed := int(elapsed * lb.processRate)
-	lb.queue = max(0, lb.queue-processed)
-	lb.lastProcess = now
-	
-	allowed := lb.queue < lb.capacity
-	
+
+	now := lb.clock.Now()
+
+	// Release (leak) requests whose processing time has come
+	lb.queue = lb.queue[lb.processedCount(now):]
+
+	allowed := len(lb.queue) < lb.capacity
+
 	if allowed {
-		lb.queue++
+		// Next free processing slot: right away if the bucket has been idle
+		slot := lb.lastScheduled.Add(secondsToDuration(1 / lb.processRate))
+		if lb.lastScheduled.IsZero() || slot.Before(now) {
+			slot = now
+		}
+		lb.lastScheduled = slot
+
+		release := Release{Arrival: now, Processed: slot}
+		lb.queue = append(lb.queue, release)
+		lb.releases = append(lb.releases, release)
+		if len(lb.releases) > 2*lb.keepReleases {
+			lb.releases = append([]Release{}, lb.releases[len(lb.releases)-lb.keepReleases:]...)
+		}
 	}
-	
+
 	// Record request
-	lb.requestHistory = append(lb.requestHistory, RequestLog{
-		Timestamp: now,
-		Allowed:   allowed,
-	})
-	
+	lb.requestHistory = recordRequest(lb.requestHistory, lb.historyCap, now, allowed)
+
 	return allowed
 }
 
+// processedCount returns how many queued requests have left the bucket by now:
+// a request is in it until one processing interval after its slot
+// (must be called with lock held)
+func (lb *LeakyBucket) processedCount(now time.Time) int {
+	n := 0
+	for n < len(lb.queue) && !lb.drainedAt(lb.queue[n]).After(now) {
+		n++
+	}
+	return n
+}
+
+// drainedAt returns when a request stops occupying the bucket
+func (lb *LeakyBucket) drainedAt(release Release) time.Time {
+	return release.Processed.Add(secondsToDuration(1 / lb.processRate))
+}
+
+// recentReleases returns the kept accepted requests (must be called with lock held)
+func (lb *LeakyBucket) recentReleases() []Release {
+	if len(lb.releases) > lb.keepReleases {
+		return lb.releases[len(lb.releases)-lb.keepReleases:]
+	}
+	return lb.releases
+}
+
+// Releases returns the most recent accepted requests with their processing time
+func (lb *LeakyBucket) Releases() []Release {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+	return append([]Release(nil), lb.recentReleases()...)
+}
+
+// KeepReleases sets how many accepted requests Releases returns
+func (lb *LeakyBucket) KeepReleases(n int) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	lb.keepReleases = max(1, n)
+}
+
 func (lb *LeakyBucket) GetState() interface{} {
 	lb.mu.RLock()
 	defer lb.mu.RUnlock()
-	
+
+	now := lb.clock.Now()
+	waiting := lb.queue[lb.processedCount(now):]
+
+	releases := lb.recentReleases()
+	delays := make([]time.Duration, len(releases))
+	for i, release := range releases {
+		delays[i] = release.Processed.Sub(release.Arrival)
+	}
+
 	return map[string]interface{}{
 		"algorithm":      "Leaky Bucket",
 		"capacity":       lb.capacity,
 		"processRate":    lb.processRate,
-		"currentQueue":   lb.queue,
-		"lastProcess":    lb.lastProcess,
+		"currentQueue":   len(waiting),
+		"queue":          waiting,
+		"lastProcess":    now,
+		"queueDelay":     ComputeDelayStats(delays),
+		"stateBytes":     lb.stateBytes(),
 		"requestHistory": lb.requestHistory,
 	}
 }
@@ -415,9 +622,10 @@ func (lb *LeakyBucket) GetState() interface{} {
 func (lb *LeakyBucket) Reset() {
 	lb.mu.Lock()
 	defer lb.mu.Unlock()
-	
-	lb.queue = 0
-	lb.lastProcess = time.Now()
+
+	lb.queue = []Release{}
+	lb.lastScheduled = time.Time{}
+	lb.releases = []Release{}
 	lb.requestHistory = []RequestLog{}
 }
 
@@ -425,6 +633,26 @@ func (lb *LeakyBucket) GetName() string {
 	return "Leaky Bucket"
 }
 
+// SetClock swaps the time source and resets the limiter onto it
+func (lb *LeakyBucket) SetClock(clock Clock) {
+	lb.mu.Lock()
+	lb.clock = clock
+	lb.mu.Unlock()
+	lb.Reset()
+}
+
+// StateBytes counts capacity, process rate, the last slot and every queued request
+func (lb *LeakyBucket) StateBytes() int {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+	return lb.stateBytes()
+}
+
+// stateBytes is StateBytes without locking (must be called with lock held)
+func (lb *LeakyBucket) stateBytes() int {
+	return 2*wordBytes + timeBytes + sliceHeaderBytes + 2*timeBytes*len(lb.queue)
+}
+
 // Helper functions
 func min(a, b float64) float64 {
 	if a < b {
@@ -433,6 +661,13 @@ func min(a, b float64) float64 {
 	return b
 }
 
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
 func max(a, b int) int {
 	if a > b {
 		return a
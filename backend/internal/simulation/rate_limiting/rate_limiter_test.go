@@ -0,0 +1,74 @@
+package rate_limiting
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLeakyBucketAdmission(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		capacity int
+		rate     float64
+		offsets  []time.Duration
+		want     int
+	}{
+		{name: "burst", capacity: 5, rate: 1, offsets: make([]time.Duration, 20), want: 5},
+		{name: "burst of one", capacity: 1, rate: 1, offsets: make([]time.Duration, 3), want: 1},
+		{name: "at the process rate", capacity: 1, rate: 10, offsets: []time.Duration{0, 100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond}, want: 4},
+		{name: "faster than the process rate", capacity: 1, rate: 10, offsets: []time.Duration{0, 50 * time.Millisecond, 100 * time.Millisecond, 150 * time.Millisecond}, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := NewVirtualClock(start)
+			lb := NewLeakyBucket(tt.capacity, tt.rate)
+			lb.SetClock(clock)
+
+			allowed := 0
+			for _, offset := range tt.offsets {
+				clock.Set(start.Add(offset))
+				if lb.AllowRequest() {
+					allowed++
+				}
+			}
+			if allowed != tt.want {
+				t.Errorf("allowed %d requests, want %d", allowed, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeakyBucketBoundsReleases(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewVirtualClock(start)
+	lb := NewLeakyBucket(10, 100)
+	lb.SetClock(clock)
+
+	requests := 10 * maxLeakyReleases
+	for i := 0; i < requests; i++ {
+		clock.Set(start.Add(time.Duration(i) * 10 * time.Millisecond))
+		lb.AllowRequest()
+	}
+	if len(lb.releases) > 2*maxLeakyReleases {
+		t.Errorf("%d releases kept, at most %d allowed", len(lb.releases), 2*maxLeakyReleases)
+	}
+	if got := len(lb.Releases()); got != maxLeakyReleases {
+		t.Errorf("Releases() returned %d, want %d", got, maxLeakyReleases)
+	}
+
+	// A replay still sees every accepted request
+	timeline := make([]ScheduledRequest, requests)
+	for i := range timeline {
+		timeline[i] = ScheduledRequest{OffsetMs: int64(i) * 10}
+	}
+	report, err := CompareAlgorithms(LimiterSpec{Limit: 10, WindowSeconds: 0.1}, timeline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, algorithm := range report.Algorithms {
+		if algorithm.Key == "leakyBucket" && algorithm.QueueDelay.Count != algorithm.Accepted {
+			t.Errorf("queue delay covers %d requests, %d were accepted", algorithm.QueueDelay.Count, algorithm.Accepted)
+		}
+	}
+}
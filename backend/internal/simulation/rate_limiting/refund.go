@@ -0,0 +1,116 @@
+package rate_limiting
+
+// refunder is implemented by limiters that can hand back the allowance of
+// the request they admitted last, so a caller that charges several limiters
+// for one request can undo the charges when a later limiter refuses it
+type refunder interface {
+	refund()
+}
+
+// refund undoes the limiter's last admission if it supports refunds
+func refund(limiter RateLimiter) {
+	if r, ok := limiter.(refunder); ok {
+		r.refund()
+	}
+}
+
+// refundHistory records the last request as rejected after all
+func refundHistory(history []RequestLog) {
+	if len(history) > 0 {
+		history[len(history)-1].Allowed = false
+	}
+}
+
+func (f *FixedWindowCounter) refund() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.counter > 0 {
+		f.counter--
+	}
+	refundHistory(f.requestHistory)
+}
+
+func (s *SlidingLog) refund() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.requestLog) > 0 {
+		s.requestLog = s.requestLog[:len(s.requestLog)-1]
+	}
+	refundHistory(s.requestHistory)
+}
+
+func (sw *SlidingWindowCounter) refund() {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	if sw.currCounter > 0 {
+		sw.currCounter--
+	}
+	refundHistory(sw.requestHistory)
+}
+
+func (tb *TokenBucket) refund() {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	tb.tokens = min(float64(tb.capacity), tb.tokens+1)
+	refundHistory(tb.requestHistory)
+}
+
+// refund frees the last processing slot; moving lastScheduled back one
+// interval is exact whether that slot was queued or the bucket was idle
+func (lb *LeakyBucket) refund() {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	if len(lb.releases) == 0 {
+		return
+	}
+	last := lb.releases[len(lb.releases)-1]
+	lb.releases = lb.releases[:len(lb.releases)-1]
+	if n := len(lb.queue); n > 0 && lb.queue[n-1] == last {
+		lb.queue = lb.queue[:n-1]
+	}
+	lb.lastScheduled = last.Processed.Add(-secondsToDuration(1 / lb.processRate))
+	refundHistory(lb.requestHistory)
+}
+
+func (g *GCRA) refund() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.tat = g.tat.Add(-g.emissionInterval)
+	refundHistory(g.requestHistory)
+}
+
+// refund drops the request admitted last: of those started now, the one
+// that completes last, since each admission raised the simulated latency
+func (a *AdaptiveConcurrencyLimiter) refund() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.clock.Now()
+	last := -1
+	for i, req := range a.inflight {
+		if req.start.Equal(now) && (last < 0 || !req.done.Before(a.inflight[last].done)) {
+			last = i
+		}
+	}
+	if last >= 0 {
+		a.inflight = append(a.inflight[:last], a.inflight[last+1:]...)
+	}
+	refundHistory(a.requestHistory)
+}
+
+// Compile-time checks that every algorithm NewLimiter builds can be refunded
+var (
+	_ refunder = (*FixedWindowCounter)(nil)
+	_ refunder = (*SlidingLog)(nil)
+	_ refunder = (*SlidingWindowCounter)(nil)
+	_ refunder = (*TokenBucket)(nil)
+	_ refunder = (*LeakyBucket)(nil)
+	_ refunder = (*GCRA)(nil)
+	_ refunder = (*AdaptiveConcurrencyLimiter)(nil)
+)
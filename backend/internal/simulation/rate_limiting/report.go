@@ -0,0 +1,319 @@
+package rate_limiting
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// LimiterSpec is the shared limit every algorithm is configured with
+// so a comparison measures the algorithms rather than their settings
+type LimiterSpec struct {
+	Limit         int     `json:"limit"`         // Requests allowed per window
+	WindowSeconds float64 `json:"windowSeconds"` // Window length (refill period for buckets)
+}
+
+// NamedLimiter pairs a limiter with the key it is reported under
+type NamedLimiter struct {
+	Key     string
+	Limiter RateLimiter
+}
+
+// LimiterAlgorithms lists the algorithm keys NewLimiter understands, in report order
+var LimiterAlgorithms = []string{
+	"fixedWindow", "slidingLog", "slidingWindow", "tokenBucket", "leakyBucket",
+	"gcra", "concurrencyAIMD", "concurrencyVegas",
+}
+
+// DefaultBackend is the simulated service used by concurrency limiters built
+// from a LimiterSpec: it serves spec.Limit requests at once in 100ms each
+func DefaultBackend(spec LimiterSpec) BackendModel {
+	return BackendModel{
+		BaseLatency: 100 * time.Millisecond,
+		Capacity:    spec.Limit,
+		Timeout:     time.Second,
+	}
+}
+
+// NewLimiter creates a single algorithm instance from the spec on the given clock
+func NewLimiter(algorithm string, spec LimiterSpec, clock Clock) (RateLimiter, error) {
+	window := time.Duration(spec.WindowSeconds * float64(time.Second))
+	rate := float64(spec.Limit) / spec.WindowSeconds
+
+	var limiter RateLimiter
+	switch algorithm {
+	case "fixedWindow":
+		limiter = NewFixedWindowCounter(spec.Limit, window)
+	case "slidingLog":
+		limiter = NewSlidingLog(spec.Limit, window)
+	case "slidingWindow":
+		limiter = NewSlidingWindowCounter(spec.Limit, window)
+	case "tokenBucket":
+		limiter = NewTokenBucket(spec.Limit, rate)
+	case "leakyBucket":
+		limiter = NewLeakyBucket(spec.Limit, rate)
+	case "gcra":
+		gcra, err := NewGCRA(spec.Limit, window, spec.Limit)
+		if err != nil {
+			return nil, err
+		}
+		limiter = gcra
+	case "concurrencyAIMD":
+		limiter = NewAdaptiveConcurrencyLimiter(StrategyAIMD, spec.Limit, DefaultBackend(spec))
+	case "concurrencyVegas":
+		limiter = NewAdaptiveConcurrencyLimiter(StrategyVegas, spec.Limit, DefaultBackend(spec))
+	default:
+		return nil, fmt.Errorf("unknown rate limiting algorithm %q", algorithm)
+	}
+	limiter.SetClock(clock)
+	return limiter, nil
+}
+
+// NewLimiterSet creates one instance of every algorithm from the same spec
+func NewLimiterSet(spec LimiterSpec, clock Clock) ([]NamedLimiter, error) {
+	limiters := make([]NamedLimiter, 0, len(LimiterAlgorithms))
+	for _, key := range LimiterAlgorithms {
+		limiter, err := NewLimiter(key, spec, clock)
+		if err != nil {
+			return nil, err
+		}
+		limiters = append(limiters, NamedLimiter{Key: key, Limiter: limiter})
+	}
+	return limiters, nil
+}
+
+// SeriesPoint is the traffic seen by one algorithm during one second
+type SeriesPoint struct {
+	Second   int `json:"second"`
+	Sent     int `json:"sent"`
+	Accepted int `json:"accepted"`
+	Rejected int `json:"rejected"`
+	Released int `json:"released"` // Requests forwarded to the backend (later than accepted when shaping)
+}
+
+// ClientStats summarizes how one client was treated by an algorithm
+type ClientStats struct {
+	Sent       int     `json:"sent"`
+	Accepted   int     `json:"accepted"`
+	AcceptRate float64 `json:"acceptRate"`
+}
+
+// AlgorithmReport holds the outcome of a workload for a single algorithm
+type AlgorithmReport struct {
+	Key              string                 `json:"key"`
+	Algorithm        string                 `json:"algorithm"`
+	Accepted         int                    `json:"accepted"`
+	Rejected         int                    `json:"rejected"`
+	AcceptRate       float64                `json:"acceptRate"`
+	MaxBurstAdmitted int                    `json:"maxBurstAdmitted"` // Most requests admitted within any one-second span
+	Fairness         float64                `json:"fairness"`         // Jain's index over per-client accept rates (1 = perfectly fair)
+	Shaping          bool                   `json:"shaping"`          // Accepted requests are delayed rather than forwarded immediately
+	QueueDelay       DelayStats             `json:"queueDelay"`       // Time accepted requests waited before being forwarded
+	Clients          map[string]ClientStats `json:"clients"`
+	Series           []SeriesPoint          `json:"series"`
+}
+
+// DelayStats summarizes how long accepted requests were held back
+type DelayStats struct {
+	Count  int     `json:"count"`
+	MeanMs float64 `json:"meanMs"`
+	P50Ms  float64 `json:"p50Ms"`
+	P90Ms  float64 `json:"p90Ms"`
+	P99Ms  float64 `json:"p99Ms"`
+	MaxMs  float64 `json:"maxMs"`
+}
+
+// ComputeDelayStats returns the mean, percentiles and maximum of the delays
+func ComputeDelayStats(delays []time.Duration) DelayStats {
+	stats := DelayStats{Count: len(delays)}
+	if len(delays) == 0 {
+		return stats
+	}
+
+	ms := make([]float64, len(delays))
+	total := 0.0
+	for i, d := range delays {
+		ms[i] = float64(d) / float64(time.Millisecond)
+		total += ms[i]
+	}
+	sort.Float64s(ms)
+
+	stats.MeanMs = total / float64(len(ms))
+	stats.P50Ms = percentile(ms, 0.50)
+	stats.P90Ms = percentile(ms, 0.90)
+	stats.P99Ms = percentile(ms, 0.99)
+	stats.MaxMs = ms[len(ms)-1]
+	return stats
+}
+
+// percentile returns the nearest-rank percentile p (0-1) of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(0, rank)]
+}
+
+// ComparisonReport is the side-by-side result of driving every algorithm
+// with the same request timeline
+type ComparisonReport struct {
+	Spec            LimiterSpec       `json:"spec"`
+	TotalRequests   int               `json:"totalRequests"`
+	DurationSeconds float64           `json:"durationSeconds"`
+	Algorithms      []AlgorithmReport `json:"algorithms"`
+}
+
+// CompareAlgorithms replays the timeline against fresh limiters sharing a
+// virtual clock and reports how each algorithm handled it
+func CompareAlgorithms(spec LimiterSpec, requests []ScheduledRequest) (ComparisonReport, error) {
+	if spec.Limit <= 0 || spec.WindowSeconds <= 0 {
+		return ComparisonReport{}, fmt.Errorf("limit and windowSeconds must be positive")
+	}
+	if err := validateTimeline(requests); err != nil {
+		return ComparisonReport{}, err
+	}
+
+	timeline := append([]ScheduledRequest(nil), requests...)
+	sort.SliceStable(timeline, func(i, j int) bool { return timeline[i].OffsetMs < timeline[j].OffsetMs })
+
+	start := time.Unix(0, 0).UTC()
+	clock := NewVirtualClock(start)
+	limiters, err := NewLimiterSet(spec, clock)
+	if err != nil {
+		return ComparisonReport{}, err
+	}
+	for _, named := range limiters {
+		if shaper, ok := named.Limiter.(Shaper); ok {
+			shaper.KeepReleases(len(timeline))
+		}
+	}
+
+	decisions := make([][]bool, len(limiters))
+	for i := range decisions {
+		decisions[i] = make([]bool, len(timeline))
+	}
+
+	for r, req := range timeline {
+		clock.Set(start.Add(time.Duration(req.OffsetMs) * time.Millisecond))
+		for i, named := range limiters {
+			decisions[i][r] = named.Limiter.AllowRequest()
+		}
+	}
+
+	report := ComparisonReport{
+		Spec:          spec,
+		TotalRequests: len(timeline),
+		Algorithms:    make([]AlgorithmReport, 0, len(limiters)),
+	}
+	if len(timeline) > 0 {
+		report.DurationSeconds = float64(timeline[len(timeline)-1].OffsetMs) / 1000
+	}
+
+	for i, named := range limiters {
+		report.Algorithms = append(report.Algorithms, summarize(named, start, timeline, decisions[i]))
+	}
+	return report, nil
+}
+
+// summarize builds the per-algorithm report from its accept/reject decisions
+func summarize(named NamedLimiter, start time.Time, timeline []ScheduledRequest, allowed []bool) AlgorithmReport {
+	result := AlgorithmReport{
+		Key:       named.Key,
+		Algorithm: named.Limiter.GetName(),
+		Clients:   make(map[string]ClientStats),
+		Series:    []SeriesPoint{},
+	}
+
+	// seriesAt returns the point for the given second, growing the series as needed
+	seriesAt := func(second int) *SeriesPoint {
+		for len(result.Series) <= second {
+			result.Series = append(result.Series, SeriesPoint{Second: len(result.Series)})
+		}
+		return &result.Series[second]
+	}
+
+	for r, req := range timeline {
+		point := seriesAt(int(req.OffsetMs / 1000))
+		point.Sent++
+
+		client := result.Clients[req.ClientID]
+		client.Sent++
+
+		if allowed[r] {
+			result.Accepted++
+			point.Accepted++
+			client.Accepted++
+			if _, shaping := named.Limiter.(Shaper); !shaping {
+				// Policing: admitted requests go straight to the backend
+				point.Released++
+			}
+		} else {
+			result.Rejected++
+			point.Rejected++
+		}
+		result.Clients[req.ClientID] = client
+	}
+
+	// Shaping: admitted requests reach the backend at their scheduled time
+	if shaper, ok := named.Limiter.(Shaper); ok {
+		result.Shaping = true
+		releases := shaper.Releases()
+		delays := make([]time.Duration, len(releases))
+		for i, release := range releases {
+			delays[i] = release.Processed.Sub(release.Arrival)
+			seriesAt(int(release.Processed.Sub(start)/time.Second)).Released++
+		}
+		result.QueueDelay = ComputeDelayStats(delays)
+	}
+
+	if len(timeline) > 0 {
+		result.AcceptRate = float64(result.Accepted) / float64(len(timeline))
+	}
+
+	rates := make([]float64, 0, len(result.Clients))
+	for id, client := range result.Clients {
+		client.AcceptRate = float64(client.Accepted) / float64(client.Sent)
+		result.Clients[id] = client
+		rates = append(rates, client.AcceptRate)
+	}
+	result.Fairness = jainIndex(rates)
+	result.MaxBurstAdmitted = maxAdmittedWithin(timeline, allowed, 1000)
+
+	return result
+}
+
+// maxAdmittedWithin returns the most accepted requests inside any span of windowMs
+func maxAdmittedWithin(timeline []ScheduledRequest, allowed []bool, windowMs int64) int {
+	admitted := []int64{}
+	for r, req := range timeline {
+		if allowed[r] {
+			admitted = append(admitted, req.OffsetMs)
+		}
+	}
+
+	best, left := 0, 0
+	for right := range admitted {
+		for admitted[right]-admitted[left] >= windowMs {
+			left++
+		}
+		if count := right - left + 1; count > best {
+			best = count
+		}
+	}
+	return best
+}
+
+// jainIndex computes Jain's fairness index: (Σx)² / (n·Σx²)
+func jainIndex(values []float64) float64 {
+	if len(values) == 0 {
+		return 1
+	}
+	sum, sumSquares := 0.0, 0.0
+	for _, v := range values {
+		sum += v
+		sumSquares += v * v
+	}
+	if sumSquares == 0 {
+		return 1
+	}
+	return (sum * sum) / (float64(len(values)) * sumSquares)
+}
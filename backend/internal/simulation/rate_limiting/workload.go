@@ -0,0 +1,210 @@
+package rate_limiting
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WorkloadPattern names a traffic shape the generator can produce
+type WorkloadPattern string
+
+const (
+	PatternConstant WorkloadPattern = "constant" // evenly spaced requests
+	PatternPoisson  WorkloadPattern = "poisson"  // exponential inter-arrival times
+	PatternBursty   WorkloadPattern = "bursty"   // on/off periods of Poisson traffic
+	PatternDiurnal  WorkloadPattern = "diurnal"  // rate ramps from trough to peak and back
+	PatternReplay   WorkloadPattern = "replay"   // timeline supplied as CSV
+)
+
+// Bounds on workload timelines so a single comparison stays cheap
+const (
+	MaxWorkloadRequests = 100000
+	MaxWorkloadSpanMs   = 24 * 60 * 60 * 1000 // Latest offset a timeline may reach
+)
+
+// ScheduledRequest is one request on a workload timeline
+type ScheduledRequest struct {
+	OffsetMs int64  `json:"offsetMs"` // Milliseconds since the start of the workload
+	ClientID string `json:"clientId"`
+}
+
+// WorkloadConfig describes the traffic to generate
+type WorkloadConfig struct {
+	Pattern         WorkloadPattern `json:"pattern"`
+	DurationSeconds float64         `json:"durationSeconds"`
+	Rate            float64         `json:"rate"`           // Mean requests/sec (trough rate for diurnal, on-rate for bursty)
+	PeakRate        float64         `json:"peakRate"`       // Diurnal peak requests/sec
+	OnSeconds       float64         `json:"onSeconds"`      // Bursty: length of each on period
+	OffSeconds      float64         `json:"offSeconds"`     // Bursty: length of each off period
+	Clients         int             `json:"clients"`        // Number of distinct clients sending traffic
+	HotClientShare  float64         `json:"hotClientShare"` // Fraction of traffic sent by client-1 (0 = uniform)
+	Seed            int64           `json:"seed"`
+}
+
+// GenerateWorkload builds a request timeline for the configured pattern
+// Timelines are deterministic for a given seed so runs can be reproduced
+func GenerateWorkload(config WorkloadConfig) ([]ScheduledRequest, error) {
+	if config.DurationSeconds <= 0 {
+		return nil, fmt.Errorf("durationSeconds must be positive")
+	}
+	if config.DurationSeconds*1000 > MaxWorkloadSpanMs {
+		return nil, fmt.Errorf("durationSeconds must be at most %d", MaxWorkloadSpanMs/1000)
+	}
+	if config.Rate <= 0 {
+		return nil, fmt.Errorf("rate must be positive")
+	}
+
+	// Reject before generating: the loops below draw about this many candidates
+	candidateRate := config.Rate
+	switch config.Pattern {
+	case PatternBursty:
+		if period := config.OnSeconds + config.OffSeconds; period > 0 {
+			candidateRate = config.Rate * config.OnSeconds / period
+		}
+	case PatternDiurnal:
+		candidateRate = math.Max(config.Rate, config.PeakRate)
+	}
+	if expected := config.DurationSeconds * candidateRate; expected > MaxWorkloadRequests {
+		return nil, fmt.Errorf("workload would generate about %.0f requests (max %d)", expected, MaxWorkloadRequests)
+	}
+
+	rng := rand.New(rand.NewSource(config.Seed))
+	var offsets []float64 // seconds
+	// room reports whether another offset fits; random arrivals can overshoot the expected count
+	room := func() bool { return len(offsets) <= MaxWorkloadRequests }
+
+	switch config.Pattern {
+	case PatternConstant, "":
+		interval := 1.0 / config.Rate
+		for t := 0.0; t < config.DurationSeconds && room(); t += interval {
+			offsets = append(offsets, t)
+		}
+
+	case PatternPoisson:
+		for t := rng.ExpFloat64() / config.Rate; t < config.DurationSeconds && room(); t += rng.ExpFloat64() / config.Rate {
+			offsets = append(offsets, t)
+		}
+
+	case PatternBursty:
+		if config.OnSeconds <= 0 || config.OffSeconds < 0 {
+			return nil, fmt.Errorf("bursty pattern needs onSeconds > 0 and offSeconds >= 0")
+		}
+		period := config.OnSeconds + config.OffSeconds
+		for t := rng.ExpFloat64() / config.Rate; t < config.DurationSeconds && room(); t += rng.ExpFloat64() / config.Rate {
+			// Skip ahead past off periods
+			if phase := math.Mod(t, period); phase >= config.OnSeconds {
+				t += period - phase
+				if t >= config.DurationSeconds {
+					break
+				}
+			}
+			offsets = append(offsets, t)
+		}
+
+	case PatternDiurnal:
+		if config.PeakRate < config.Rate {
+			return nil, fmt.Errorf("diurnal pattern needs peakRate >= rate")
+		}
+		// Thinning: draw candidates at the peak rate and keep each with
+		// probability rate(t)/peakRate, where rate(t) follows one cosine "day"
+		for t := rng.ExpFloat64() / config.PeakRate; t < config.DurationSeconds && room(); t += rng.ExpFloat64() / config.PeakRate {
+			dayPhase := (1 - math.Cos(2*math.Pi*t/config.DurationSeconds)) / 2
+			rate := config.Rate + (config.PeakRate-config.Rate)*dayPhase
+			if rng.Float64() < rate/config.PeakRate {
+				offsets = append(offsets, t)
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown workload pattern %q", config.Pattern)
+	}
+
+	if len(offsets) > MaxWorkloadRequests {
+		return nil, fmt.Errorf("workload would generate more than %d requests", MaxWorkloadRequests)
+	}
+
+	requests := make([]ScheduledRequest, len(offsets))
+	for i, offset := range offsets {
+		requests[i] = ScheduledRequest{
+			OffsetMs: int64(offset * 1000),
+			ClientID: pickClient(rng, config.Clients, config.HotClientShare),
+		}
+	}
+	return requests, nil
+}
+
+// pickClient assigns a request to a client, optionally skewed toward client-1
+func pickClient(rng *rand.Rand, clients int, hotShare float64) string {
+	if clients <= 1 {
+		return "client-1"
+	}
+	if hotShare > 0 && rng.Float64() < hotShare {
+		return "client-1"
+	}
+	return fmt.Sprintf("client-%d", rng.Intn(clients)+1)
+}
+
+// ParseTraceCSV reads a replay timeline with rows of "offsetMs[,clientId]"
+// A non-numeric first row is treated as a header and skipped
+func ParseTraceCSV(r io.Reader) ([]ScheduledRequest, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	requests := []ScheduledRequest{}
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", row, err)
+		}
+		if len(record) == 0 || strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		offset, err := strconv.ParseFloat(strings.TrimSpace(record[0]), 64)
+		if err != nil {
+			if row == 1 {
+				continue // header
+			}
+			return nil, fmt.Errorf("row %d: invalid offset %q", row, record[0])
+		}
+		if math.IsNaN(offset) || offset < 0 || offset > MaxWorkloadSpanMs {
+			return nil, fmt.Errorf("row %d: offset must be between 0 and %d", row, MaxWorkloadSpanMs)
+		}
+
+		clientID := "client-1"
+		if len(record) > 1 && strings.TrimSpace(record[1]) != "" {
+			clientID = strings.TrimSpace(record[1])
+		}
+
+		requests = append(requests, ScheduledRequest{OffsetMs: int64(offset), ClientID: clientID})
+		if len(requests) > MaxWorkloadRequests {
+			return nil, fmt.Errorf("trace has more than %d requests", MaxWorkloadRequests)
+		}
+	}
+
+	sort.SliceStable(requests, func(i, j int) bool { return requests[i].OffsetMs < requests[j].OffsetMs })
+	return requests, nil
+}
+
+// validateTimeline checks a timeline's size and that every offset lies within the workload span
+func validateTimeline(requests []ScheduledRequest) error {
+	if len(requests) > MaxWorkloadRequests {
+		return fmt.Errorf("timeline has more than %d requests", MaxWorkloadRequests)
+	}
+	for _, req := range requests {
+		if req.OffsetMs < 0 || req.OffsetMs > MaxWorkloadSpanMs {
+			return fmt.Errorf("offsetMs must be between 0 and %d", MaxWorkloadSpanMs)
+		}
+	}
+	return nil
+}
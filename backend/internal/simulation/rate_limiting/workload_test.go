@@ -0,0 +1,83 @@
+package rate_limiting
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateWorkloadLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  WorkloadConfig
+		wantErr bool
+	}{
+		{name: "constant within limit", config: WorkloadConfig{Pattern: PatternConstant, DurationSeconds: 10, Rate: 100}},
+		{name: "constant over limit", config: WorkloadConfig{Pattern: PatternConstant, DurationSeconds: 1e4, Rate: 1e4}, wantErr: true},
+		{name: "poisson over limit", config: WorkloadConfig{Pattern: PatternPoisson, DurationSeconds: 1e9, Rate: 1e9}, wantErr: true},
+		{name: "diurnal peak over limit", config: WorkloadConfig{Pattern: PatternDiurnal, DurationSeconds: 1000, Rate: 1, PeakRate: 1e6}, wantErr: true},
+		{name: "sparse traffic over a year", config: WorkloadConfig{Pattern: PatternConstant, DurationSeconds: 365 * 86400, Rate: 0.001}, wantErr: true},
+		{name: "bursty mostly off", config: WorkloadConfig{Pattern: PatternBursty, DurationSeconds: 1000, Rate: 1000, OnSeconds: 1, OffSeconds: 99}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			requests, err := GenerateWorkload(tt.config)
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("took %v", elapsed)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("generated %d requests, want error", len(requests))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(requests) == 0 || len(requests) > MaxWorkloadRequests {
+				t.Errorf("generated %d requests", len(requests))
+			}
+		})
+	}
+}
+
+func TestParseTraceCSVOffsets(t *testing.T) {
+	tests := []struct {
+		name    string
+		trace   string
+		want    int
+		wantErr bool
+	}{
+		{name: "header and clients", trace: "offsetMs,client\n0,a\n250.5,b\n", want: 2},
+		{name: "full span", trace: "0\n86400000\n", want: 2},
+		{name: "negative", trace: "0\n-1\n", wantErr: true},
+		{name: "infinite", trace: "0\ninf\n", wantErr: true},
+		{name: "not a number", trace: "0\nnan\n", wantErr: true},
+		{name: "past the span", trace: "0\n1e12\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests, err := ParseTraceCSV(strings.NewReader(tt.trace))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parsed %v, want error", requests)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(requests) != tt.want {
+				t.Errorf("parsed %d requests, want %d", len(requests), tt.want)
+			}
+			if _, err := CompareAlgorithms(LimiterSpec{Limit: 5, WindowSeconds: 1}, requests); err != nil {
+				t.Errorf("CompareAlgorithms() error = %v", err)
+			}
+		})
+	}
+
+	if _, err := CompareAlgorithms(LimiterSpec{Limit: 5, WindowSeconds: 1}, []ScheduledRequest{{OffsetMs: -1}}); err == nil {
+		t.Error("CompareAlgorithms accepted a negative offset")
+	}
+}